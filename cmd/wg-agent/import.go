@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/quibex/wg-agent/internal/config"
+	"github.com/quibex/wg-agent/internal/wireguard"
+)
+
+// runImport реализует подкоманду `wg-agent import`:
+// регистрирует пиры из конфига wg-quick в хранилище агента, не трогая устройство.
+// Агент должен быть остановлен, иначе он перезапишет файл состояния.
+// На работающем агенте используйте RPC ImportClients.
+func runImport(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	configPath := fs.String("config", cfg.WGConfigPath, "конфиг wg-quick для импорта (\"-\" - stdin, например `wg showconf wg0 | wg-agent import -config -`)")
+	mapPath := fs.String("map", "", "файл соответствий \"public_key user_id\" (по одному на строку)")
+	dryRun := fs.Bool("dry-run", false, "только показать результат, ничего не сохранять")
+	fs.Parse(args)
+
+	var in io.Reader = os.Stdin
+	if *configPath != "-" {
+		f, err := os.Open(*configPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	parsed, err := wireguard.ParseWGQuickConfig(in)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", *configPath, err)
+	}
+
+	userIDs := map[string]string{}
+	if *mapPath != "" {
+		f, err := os.Open(*mapPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if userIDs, err = wireguard.ParseUserIDMap(f); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", *mapPath, err)
+		}
+	}
+
+	sealer, err := newKeySealer(cfg)
+	if err != nil {
+		return err
+	}
+	if !*dryRun {
+		if err := checkPlaintextKeys(cfg, sealer); err != nil {
+			return err
+		}
+	}
+
+	clients, err := newClientStore(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open client store: %w", err)
+	}
+
+	results := wireguard.ImportPeers(clients, parsed, wireguard.ImportOptions{
+		UserIDs: userIDs,
+		DryRun:  *dryRun,
+		Sealer:  sealer,
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tUSER_ID\tDEVICE_ID\tCLIENT_IP\tPUBLIC_KEY\tMESSAGE")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Status, r.UserID, r.DeviceID, strings.Join(wireguard.JoinAllowedIPs(r.AllowedIP, r.AllowedIP6), ", "), r.PublicKey, r.Message)
+	}
+	return w.Flush()
+}
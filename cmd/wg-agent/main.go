@@ -1,9 +1,12 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/quibex/wg-agent/internal/config"
@@ -14,6 +17,23 @@ import (
 func main() {
 	cfg := config.Load()
 
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "import":
+			err = runImport(cfg, os.Args[2:])
+		case "rekey":
+			err = runRekey(cfg)
+		default:
+			err = fmt.Errorf("unknown command %q (expected import or rekey)", os.Args[1])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s failed: %v\n", os.Args[1], err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	log := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	log.Info("Starting wg-agent")
 
@@ -24,7 +44,28 @@ func main() {
 	}
 	defer wgClient.Close()
 
-	srv := server.New(cfg, log, wgClient)
+	clients, err := newClientStore(cfg)
+	if err != nil {
+		log.Error("Failed to open client store", "error", err)
+		os.Exit(1)
+	}
+	log.Info("Client store opened", "backend", cfg.StoreBackend, "clients", len(clients.List()))
+
+	sealer, err := newKeySealer(cfg)
+	if err == nil {
+		err = checkPlaintextKeys(cfg, sealer)
+	}
+	if err != nil {
+		log.Error("Failed to load KEK", "error", err)
+		os.Exit(1)
+	}
+	if sealer == nil {
+		log.Warn("KEK is not configured, client private keys are stored in plaintext")
+	} else if n := countUnsealed(clients, sealer); n > 0 {
+		log.Warn("Some private keys are not sealed with the current KEK, run `wg-agent rekey`", "clients", n)
+	}
+
+	srv := server.New(cfg, log, wgClient, clients, sealer)
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -49,3 +90,77 @@ func main() {
 
 	log.Info("wg-agent stopped")
 }
+
+// newClientStore создает хранилище клиентов согласно конфигурации
+func newClientStore(cfg *config.Config) (*wireguard.ClientStore, error) {
+	switch cfg.StoreBackend {
+	case "memory":
+		return wireguard.NewClientStore(), nil
+	case "file":
+		storage, err := wireguard.NewFileStorage(cfg.StatePath)
+		if err != nil {
+			return nil, err
+		}
+		return wireguard.NewPersistentClientStore(storage)
+	default:
+		return nil, fmt.Errorf("unknown store backend %q (expected file or memory)", cfg.StoreBackend)
+	}
+}
+
+// checkPlaintextKeys запрещает хранить ключи клиентов в файле состояния
+// открытым текстом, если это явно не разрешено WG_AGENT_ALLOW_PLAINTEXT_KEYS
+func checkPlaintextKeys(cfg *config.Config, sealer *wireguard.KeySealer) error {
+	if sealer != nil || cfg.StoreBackend != "file" || cfg.AllowPlaintextKeys {
+		return nil
+	}
+	return errors.New("KEK is not configured: set WG_AGENT_KEK or WG_AGENT_KEK_FILE " +
+		"to encrypt client keys in the state file, or WG_AGENT_ALLOW_PLAINTEXT_KEYS=true to store them in plaintext")
+}
+
+// newKeySealer загружает KEK из окружения и файла, nil - шифрование не настроено
+func newKeySealer(cfg *config.Config) (*wireguard.KeySealer, error) {
+	var encoded []string
+	if cfg.KEK != "" {
+		encoded = append(encoded, cfg.KEK)
+	}
+	if cfg.KEKFile != "" {
+		data, err := os.ReadFile(cfg.KEKFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read KEK file: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				encoded = append(encoded, line)
+			}
+		}
+	}
+	for _, key := range strings.Split(cfg.KEKPrevious, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			encoded = append(encoded, key)
+		}
+	}
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+
+	keys := make([][]byte, 0, len(encoded))
+	for i, e := range encoded {
+		key, err := wireguard.ParseKEK(e)
+		if err != nil {
+			return nil, fmt.Errorf("KEK #%d: %w", i+1, err)
+		}
+		keys = append(keys, key)
+	}
+	return wireguard.NewKeySealer(keys[0], keys[1:]...)
+}
+
+// countUnsealed считает клиентов, чьи ключи не зашифрованы текущим KEK
+func countUnsealed(clients *wireguard.ClientStore, sealer *wireguard.KeySealer) int {
+	n := 0
+	for _, c := range clients.List() {
+		if sealer.NeedsReseal(c.PrivateKey) || sealer.NeedsReseal(c.PresharedKey) {
+			n++
+		}
+	}
+	return n
+}
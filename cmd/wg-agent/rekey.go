@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/quibex/wg-agent/internal/config"
+)
+
+// runRekey реализует подкоманду `wg-agent rekey`: перешифровывает приватные и
+// preshared ключи клиентов текущим KEK. Используется после ротации KEK (старый ключ
+// переносится в предыдущие) и для шифрования ключей, сохранённых до включения KEK.
+// Агент должен быть остановлен, иначе он перезапишет файл состояния.
+func runRekey(cfg *config.Config) error {
+	sealer, err := newKeySealer(cfg)
+	if err != nil {
+		return err
+	}
+	if sealer == nil {
+		return errors.New("KEK is not configured (set WG_AGENT_KEK or WG_AGENT_KEK_FILE)")
+	}
+
+	clients, err := newClientStore(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open client store: %w", err)
+	}
+
+	n, err := clients.ResealKeys(sealer)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("resealed keys of %d clients\n", n)
+	return nil
+}
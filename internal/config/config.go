@@ -2,17 +2,55 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// DefaultPoolName имя пула адресов подсети WG_SUBNET
+const DefaultPoolName = "default"
+
 // Config содержит конфигурацию wg-agent
 type Config struct {
 	// WireGuard настройки
-	Interface      string // WireGuard интерфейс по умолчанию (wg0)
-	Subnet         string // Подсеть для выделения IP клиентам (10.8.0.0/24)
-	ServerPublicIP string // Публичный IP/домен сервера для endpoint
-	ServerPort     int    // Порт WireGuard сервера (51820)
+	Interface      string        // WireGuard интерфейс по умолчанию (wg0)
+	Subnet         string        // Подсеть для выделения IP клиентам (10.8.0.0/24)
+	Subnet6        string        // IPv6 (ULA) подсеть для клиентов (fd42:42:42::/64), пусто - только IPv4
+	Pools          string        // Дополнительные именованные IPv4 пулы через запятую: "paid=10.9.0.0/22"
+	DefaultPool    string        // Пул для CreateClient без pool (default - подсеть Subnet)
+	ReservedIPs    string        // Адреса и подсети через запятую, которые не выдаются клиентам
+	IPQuarantine   time.Duration // Сколько адрес удалённого клиента не выдаётся другим (0 - сразу)
+	ServerPublicIP string        // Публичный IP/домен сервера для endpoint
+	ServerPort     int           // Порт WireGuard сервера (51820)
+	WGConfigPath   string        // Путь к конфигу wg-quick (/etc/wireguard/wg0.conf)
+	WGConfigSync   bool          // Переписывать конфиг wg-quick при изменении клиентов
+	PSKPolicy      string        // Preshared key для клиентов: off, always, per-request
+	IdempotencyTTL time.Duration // Сколько помнить idempotency_key CreateClient (0 - не помнить)
+	MaxDevices     int           // Лимит устройств клиента, если он не передан в CreateDevice (0 - без лимита)
+	ExpiryGrace    time.Duration // Сколько клиент работает после expires_at до автоматического отключения
+
+	// Конфиг клиента по умолчанию (переопределяется в CreateClient)
+	ClientDNS        string // DNS серверы через запятую, пусто - без DNS
+	ClientAllowedIPs string // Маршруты через туннель через запятую, пусто - весь трафик
+	ClientMTU        int    // MTU туннеля, 0 - по умолчанию клиента
+	ClientKeepalive  int    // PersistentKeepalive в секундах, 0 - выключен
+	SplitTunnels     string // Пресеты split-tunnel: "lan=192.168.0.0/16,fc00::/7;ru=@/etc/wg-agent/ru.txt"
+
+	// Хранилище состояния
+	StoreBackend string // Бэкенд хранилища клиентов: file или memory
+	StatePath    string // Путь к файлу состояния для бэкенда file
+
+	// Шифрование приватных ключей клиентов (KEK, base64 от 32 байт)
+	KEK                string // Текущий KEK
+	KEKFile            string // Файл с KEK: первая строка - текущий, следующие - предыдущие
+	KEKPrevious        string // Предыдущие KEK через запятую (для расшифровки при ротации)
+	AllowPlaintextKeys bool   // Разрешить бэкенд file без KEK: ключи клиентов в файле открытым текстом
+
+	// Сверка с устройством WireGuard
+	UnknownPeerPolicy string        // Что делать с неизвестными пирами: adopt, remove, ignore
+	ReconcileInterval time.Duration // Интервал периодической сверки (0 - только при старте)
 
 	// TLS настройки
 	TLSCert  string // Путь к TLS сертификату
@@ -43,12 +81,109 @@ func Load() *Config {
 		}
 	}
 
+	reconcileInterval := 5 * time.Minute
+	if ri := os.Getenv("WG_AGENT_RECONCILE_INTERVAL"); ri != "" {
+		if parsed, err := time.ParseDuration(ri); err == nil {
+			reconcileInterval = parsed
+		}
+	}
+
+	clientMTU := 0
+	if mtu := os.Getenv("WG_AGENT_CLIENT_MTU"); mtu != "" {
+		if parsed, err := strconv.Atoi(mtu); err == nil {
+			clientMTU = parsed
+		}
+	}
+
+	clientKeepalive := 25
+	if ka := os.Getenv("WG_AGENT_CLIENT_KEEPALIVE"); ka != "" {
+		if parsed, err := strconv.Atoi(ka); err == nil {
+			clientKeepalive = parsed
+		}
+	}
+
+	ipQuarantine := 24 * time.Hour
+	if q := os.Getenv("WG_AGENT_IP_QUARANTINE"); q != "" {
+		if parsed, err := time.ParseDuration(q); err == nil {
+			ipQuarantine = parsed
+		}
+	}
+
+	idempotencyTTL := 24 * time.Hour
+	if ttl := os.Getenv("WG_AGENT_IDEMPOTENCY_TTL"); ttl != "" {
+		if parsed, err := time.ParseDuration(ttl); err == nil {
+			idempotencyTTL = parsed
+		}
+	}
+
+	expiryGrace := time.Duration(0)
+	if g := os.Getenv("WG_AGENT_EXPIRY_GRACE"); g != "" {
+		if parsed, err := time.ParseDuration(g); err == nil {
+			expiryGrace = parsed
+		}
+	}
+
+	maxDevices := 5
+	if md := os.Getenv("WG_AGENT_MAX_DEVICES"); md != "" {
+		if parsed, err := strconv.Atoi(md); err == nil {
+			maxDevices = parsed
+		}
+	}
+
+	wgConfigSync := false
+	if cs := os.Getenv("WG_AGENT_WG_CONFIG_SYNC"); cs != "" {
+		if parsed, err := strconv.ParseBool(cs); err == nil {
+			wgConfigSync = parsed
+		}
+	}
+
+	allowPlaintextKeys := false
+	if pk := os.Getenv("WG_AGENT_ALLOW_PLAINTEXT_KEYS"); pk != "" {
+		if parsed, err := strconv.ParseBool(pk); err == nil {
+			allowPlaintextKeys = parsed
+		}
+	}
+
+	iface := getEnv("WG_AGENT_INTERFACE", "wg0")
+
 	return &Config{
 		// WireGuard
-		Interface:      getEnv("WG_AGENT_INTERFACE", "wg0"),
+		Interface:      iface,
 		Subnet:         getEnv("WG_SUBNET", "10.8.0.0/24"),
+		Subnet6:        os.Getenv("WG_SUBNET6"),
+		Pools:          os.Getenv("WG_AGENT_POOLS"),
+		DefaultPool:    getEnv("WG_AGENT_DEFAULT_POOL", DefaultPoolName),
+		ReservedIPs:    os.Getenv("WG_AGENT_RESERVED_IPS"),
+		IPQuarantine:   ipQuarantine,
 		ServerPublicIP: getEnv("SERVER_PUBLIC_IP", ""),
 		ServerPort:     serverPort,
+		WGConfigPath:   getEnv("WG_AGENT_WG_CONFIG", "/etc/wireguard/"+iface+".conf"),
+		WGConfigSync:   wgConfigSync,
+		PSKPolicy:      getEnv("WG_AGENT_PSK_POLICY", "off"),
+		IdempotencyTTL: idempotencyTTL,
+		MaxDevices:     maxDevices,
+		ExpiryGrace:    expiryGrace,
+
+		// Client config
+		ClientDNS:        getEnv("WG_AGENT_CLIENT_DNS", "1.1.1.1, 1.0.0.1"),
+		ClientAllowedIPs: os.Getenv("WG_AGENT_CLIENT_ALLOWED_IPS"),
+		ClientMTU:        clientMTU,
+		ClientKeepalive:  clientKeepalive,
+		SplitTunnels:     os.Getenv("WG_AGENT_SPLIT_TUNNELS"),
+
+		// Store
+		StoreBackend: getEnv("WG_AGENT_STORE", "file"),
+		StatePath:    getEnv("WG_AGENT_STATE_PATH", "/var/lib/wg-agent/state.json"),
+
+		// KEK
+		KEK:                os.Getenv("WG_AGENT_KEK"),
+		KEKFile:            os.Getenv("WG_AGENT_KEK_FILE"),
+		KEKPrevious:        os.Getenv("WG_AGENT_KEK_PREVIOUS"),
+		AllowPlaintextKeys: allowPlaintextKeys,
+
+		// Reconcile
+		UnknownPeerPolicy: getEnv("WG_AGENT_UNKNOWN_PEERS", "ignore"),
+		ReconcileInterval: reconcileInterval,
 
 		// TLS
 		TLSCert:  getEnv("WG_AGENT_TLS_CERT", "/etc/wg-agent/cert.pem"),
@@ -77,7 +212,111 @@ func (c *Config) Validate() error {
 	if c.ServerPublicIP == "" {
 		return fmt.Errorf("SERVER_PUBLIC_IP is required")
 	}
-	return nil
+	pools, err := c.AddressPools()
+	if err != nil {
+		return err
+	}
+	for _, p := range pools {
+		if p.Name == c.DefaultPool || c.DefaultPool == "" && p.Name == DefaultPoolName {
+			return nil
+		}
+	}
+	return fmt.Errorf("default pool %q is not configured", c.DefaultPool)
+}
+
+// AddressPool именованная IPv4 подсеть для клиентов
+type AddressPool struct {
+	Name   string
+	Subnet string
+}
+
+// AddressPools возвращает пулы адресов: Subnet под именем default и пулы из Pools.
+// Имена должны быть уникальны, подсети не должны пересекаться.
+func (c *Config) AddressPools() ([]AddressPool, error) {
+	pools := []AddressPool{{Name: DefaultPoolName, Subnet: c.Subnet}}
+	for _, entry := range strings.Split(c.Pools, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, subnet, ok := strings.Cut(entry, "=")
+		name, subnet = strings.TrimSpace(name), strings.TrimSpace(subnet)
+		if !ok || name == "" || subnet == "" {
+			return nil, fmt.Errorf("invalid pool %q: want name=subnet", entry)
+		}
+		pools = append(pools, AddressPool{Name: name, Subnet: subnet})
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(pools))
+	for i, p := range pools {
+		prefix, err := netip.ParsePrefix(p.Subnet)
+		if err != nil || !prefix.Addr().Is4() {
+			return nil, fmt.Errorf("pool %s: invalid IPv4 subnet %q", p.Name, p.Subnet)
+		}
+		for j, other := range prefixes {
+			if pools[j].Name == p.Name {
+				return nil, fmt.Errorf("duplicate pool %s", p.Name)
+			}
+			if other.Overlaps(prefix) {
+				return nil, fmt.Errorf("pool %s (%s) overlaps pool %s (%s)", p.Name, p.Subnet, pools[j].Name, pools[j].Subnet)
+			}
+		}
+		prefixes = append(prefixes, prefix)
+		pools[i].Subnet = prefix.Masked().String()
+	}
+	return pools, nil
+}
+
+// SplitTunnel пресет split-tunnel: через туннель идёт весь трафик, кроме Exclude
+type SplitTunnel struct {
+	Name    string
+	Exclude []string // исключаемые подсети IPv4 и IPv6
+}
+
+// SplitTunnelPresets разбирает пресеты split-tunnel. Пресеты разделяются ";",
+// подсети внутри пресета - ",". Вместо списка можно указать файл "@/path":
+// по подсети на строку, строки с "#" - комментарии.
+func (c *Config) SplitTunnelPresets() ([]SplitTunnel, error) {
+	var presets []SplitTunnel
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(c.SplitTunnels, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, list, ok := strings.Cut(entry, "=")
+		name, list = strings.TrimSpace(name), strings.TrimSpace(list)
+		if !ok || name == "" || list == "" {
+			return nil, fmt.Errorf("invalid split tunnel %q: want name=cidr,cidr", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate split tunnel %s", name)
+		}
+		seen[name] = true
+
+		var items []string
+		if path, isFile := strings.CutPrefix(list, "@"); isFile {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("split tunnel %s: %w", name, err)
+			}
+			for _, line := range strings.Split(string(data), "\n") {
+				line, _, _ = strings.Cut(line, "#")
+				items = append(items, line)
+			}
+		} else {
+			items = strings.Split(list, ",")
+		}
+
+		preset := SplitTunnel{Name: name}
+		for _, item := range items {
+			if item = strings.TrimSpace(item); item != "" {
+				preset.Exclude = append(preset.Exclude, item)
+			}
+		}
+		presets = append(presets, preset)
+	}
+	return presets, nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/quibex/wg-agent/internal/wireguard"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// primaryDeviceID device_id основного устройства клиента в API.
+// В хранилище у основного устройства device_id пустой.
+const primaryDeviceID = "primary"
+
+func deviceNotFound(userID, deviceID string) error {
+	return statusError(codes.NotFound, proto.ErrorReason_DEVICE_NOT_FOUND, "device not found",
+		"user_id", userID, "device_id", deviceID)
+}
+
+// apiDeviceID device_id устройства в ответах API
+func apiDeviceID(client *wireguard.ClientData) string {
+	if client.DeviceID == "" {
+		return primaryDeviceID
+	}
+	return client.DeviceID
+}
+
+// getDevice возвращает устройство клиента по device_id из запроса,
+// пусто или "primary" - основное устройство
+func (s *agentService) getDevice(userID, deviceID string) (*wireguard.ClientData, error) {
+	if deviceID == primaryDeviceID {
+		deviceID = ""
+	}
+	client, exists := s.clients.GetDevice(userID, deviceID)
+	if !exists {
+		if deviceID == "" {
+			return nil, clientNotFound(userID)
+		}
+		return nil, deviceNotFound(userID, deviceID)
+	}
+	return client, nil
+}
+
+// newDeviceID генерирует device_id для устройства без ID в запросе
+func newDeviceID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", internalError(fmt.Errorf("failed to generate device_id: %w", err))
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateDevice добавляет клиенту устройство со своими ключами и адресом.
+// Пул, шаблон конфига и состояние (включен/отключен) берутся у основного устройства.
+func (s *agentService) CreateDevice(ctx context.Context, req *proto.CreateDeviceRequest) (*proto.CreateDeviceResponse, error) {
+	userID := req.UserId
+	if userID == "" {
+		return nil, userIDRequired()
+	}
+	if req.DeviceId == primaryDeviceID {
+		return nil, invalidArgument("device_id", `device_id "primary" is reserved for the client's own device`)
+	}
+	if req.DeviceId != "" {
+		if err := wireguard.ValidateDeviceID(req.DeviceId); err != nil {
+			return nil, invalidField("device_id", err)
+		}
+	}
+	if req.MaxDevices < 0 {
+		return nil, invalidArgument("max_devices", "max_devices must not be negative")
+	}
+	if req.TunnelName != "" {
+		if err := wireguard.ValidateTunnelName(req.TunnelName); err != nil {
+			return nil, invalidField("tunnel_name", err)
+		}
+	}
+	render, err := parseRenderOptions(req.QrOptions, req.OutputFormats, req.DeepLinkOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+	defer s.users.lock(userID)()
+
+	devices := s.clients.ListDevices(userID)
+	if len(devices) == 0 {
+		return nil, clientNotFound(userID)
+	}
+	limit := int(req.MaxDevices)
+	if limit == 0 {
+		limit = s.maxDevices
+	}
+	if limit > 0 && len(devices) >= limit {
+		return nil, statusError(codes.ResourceExhausted, proto.ErrorReason_DEVICE_LIMIT_EXCEEDED,
+			fmt.Sprintf("client %s already has %d devices, limit is %d", userID, len(devices), limit),
+			"user_id", userID, "max_devices", strconv.Itoa(limit))
+	}
+
+	deviceID := req.DeviceId
+	if deviceID == "" {
+		if deviceID, err = newDeviceID(); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range devices {
+		if d.DeviceID == deviceID {
+			return nil, statusError(codes.AlreadyExists, proto.ErrorReason_DEVICE_ALREADY_EXISTS,
+				fmt.Sprintf("device %s already exists", d.ID()), "user_id", userID, "device_id", deviceID)
+		}
+	}
+
+	// Основное устройство первое; если его нет (импорт), берём любое
+	base := devices[0]
+	client := &wireguard.ClientData{
+		UserID:     userID,
+		DeviceID:   deviceID,
+		Enabled:    base.Enabled,
+		Template:   base.Template,
+		TunnelName: base.TunnelName,
+	}
+	if req.TunnelName != "" {
+		client.TunnelName = req.TunnelName
+	}
+	serverPublicKey, err := s.provision(client, provisionRequest{
+		publicKey:    req.PublicKey,
+		presharedKey: req.PresharedKey,
+		pool:         s.clientPool(base),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts, err := s.renderClient(client, serverPublicKey, render)
+	if err != nil {
+		return nil, err
+	}
+
+	s.syncConfig()
+	s.log.Info("device created", "user_id", userID, "device_id", deviceID, "client_ip", client.AllowedIP, "key_mode", client.KeyMode())
+
+	return &proto.CreateDeviceResponse{
+		DeviceId: deviceID,
+		Config:   clientConfigResponse(client, artifacts),
+	}, nil
+}
+
+// ListDevices возвращает устройства клиента со статистикой.
+func (s *agentService) ListDevices(ctx context.Context, req *proto.ListDevicesRequest) (*proto.ListDevicesResponse, error) {
+	userID := req.UserId
+	if userID == "" {
+		return nil, userIDRequired()
+	}
+
+	devices := s.clients.ListDevices(userID)
+	if len(devices) == 0 {
+		return nil, clientNotFound(userID)
+	}
+
+	peerStats := make(map[string]wgtypes.Peer)
+	if device, err := s.wgClient.Device(s.iface); err == nil {
+		for _, peer := range device.Peers {
+			peerStats[peer.PublicKey.String()] = peer
+		}
+	}
+
+	result := make([]*proto.DeviceInfo, 0, len(devices))
+	for _, d := range devices {
+		info := &proto.DeviceInfo{
+			DeviceId:  apiDeviceID(d),
+			ClientIp:  d.AllowedIP,
+			ClientIps: d.AllowedIPs(),
+			Enabled:   d.Enabled,
+			KeyMode:   d.KeyMode(),
+
+			HasPresharedKey: d.PresharedKey != "",
+			TunnelName:      s.tunnelName(d),
+		}
+		if peer, ok := peerStats[d.PublicKey]; ok && d.Enabled {
+			info.LastHandshake = peer.LastHandshakeTime.Unix()
+			info.RxBytes = peer.ReceiveBytes
+			info.TxBytes = peer.TransmitBytes
+		}
+		result = append(result, info)
+	}
+
+	return &proto.ListDevicesResponse{Devices: result}, nil
+}
+
+// DeleteDevice удаляет дополнительное устройство клиента.
+func (s *agentService) DeleteDevice(ctx context.Context, req *proto.DeleteDeviceRequest) (*emptypb.Empty, error) {
+	userID := req.UserId
+	if userID == "" {
+		return nil, userIDRequired()
+	}
+	switch req.DeviceId {
+	case "":
+		return nil, invalidArgument("device_id", "device_id is required")
+	case primaryDeviceID:
+		return nil, invalidArgument("device_id", "primary device can only be deleted with DeleteClient")
+	}
+
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+	defer s.users.lock(userID)()
+
+	device, err := s.getDevice(userID, req.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if device.Enabled {
+		if err := s.removePeer(device.PublicKey); err != nil {
+			s.log.Warn("failed to remove peer from WireGuard", "device", device.ID(), "error", err)
+		}
+	}
+
+	if err := s.clients.DeleteDevice(userID, device.DeviceID); err != nil {
+		return nil, storeError("failed to delete device", err)
+	}
+	s.syncConfig()
+	s.log.Info("device deleted", "user_id", userID, "device_id", device.DeviceID)
+
+	return &emptypb.Empty{}, nil
+}
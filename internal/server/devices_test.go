@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quibex/wg-agent/internal/config"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateDevice(t *testing.T) {
+	s, wg := newTestService(t, func(cfg *config.Config) { cfg.MaxDevices = 3 })
+	ctx := context.Background()
+
+	primary, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", TunnelName: "home"})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	phone, err := s.CreateDevice(ctx, &proto.CreateDeviceRequest{UserId: "alice", DeviceId: "phone"})
+	if err != nil {
+		t.Fatalf("CreateDevice() error = %v", err)
+	}
+	if phone.DeviceId != "phone" || phone.Config.ClientIp == primary.ClientIp || phone.Config.ConfigFilename != "home.conf" {
+		t.Errorf("CreateDevice() = %v", phone)
+	}
+	generated, err := s.CreateDevice(ctx, &proto.CreateDeviceRequest{UserId: "alice"})
+	if err != nil || len(generated.DeviceId) != 8 {
+		t.Fatalf("CreateDevice() without device_id = %v, %v", generated, err)
+	}
+	if peers := devicePeers(t, wg); len(peers) != 3 {
+		t.Errorf("device has %d peers, want 3", len(peers))
+	}
+
+	tests := []struct {
+		name   string
+		req    *proto.CreateDeviceRequest
+		code   codes.Code
+		reason proto.ErrorReason
+	}{
+		{"server limit", &proto.CreateDeviceRequest{UserId: "alice"}, codes.ResourceExhausted, proto.ErrorReason_DEVICE_LIMIT_EXCEEDED},
+		{"request limit", &proto.CreateDeviceRequest{UserId: "alice", MaxDevices: 2}, codes.ResourceExhausted, proto.ErrorReason_DEVICE_LIMIT_EXCEEDED},
+		{"duplicate", &proto.CreateDeviceRequest{UserId: "alice", DeviceId: "phone", MaxDevices: 10}, codes.AlreadyExists, proto.ErrorReason_DEVICE_ALREADY_EXISTS},
+		{"primary", &proto.CreateDeviceRequest{UserId: "alice", DeviceId: "primary"}, codes.InvalidArgument, proto.ErrorReason_INVALID_ARGUMENT},
+		{"invalid id", &proto.CreateDeviceRequest{UserId: "alice", DeviceId: "my phone"}, codes.InvalidArgument, proto.ErrorReason_INVALID_ARGUMENT},
+		{"unknown user", &proto.CreateDeviceRequest{UserId: "bob"}, codes.NotFound, proto.ErrorReason_CLIENT_NOT_FOUND},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.CreateDevice(ctx, tt.req)
+			if status.Code(err) != tt.code || errorReason(err) != tt.reason.String() {
+				t.Errorf("CreateDevice() error = %v, want %s %s", err, tt.code, tt.reason)
+			}
+		})
+	}
+
+	// Лимит из запроса выше лимита сервера
+	if _, err := s.CreateDevice(ctx, &proto.CreateDeviceRequest{UserId: "alice", DeviceId: "tv", MaxDevices: 4}); err != nil {
+		t.Errorf("CreateDevice() with max_devices=4 error = %v", err)
+	}
+
+	list, err := s.ListDevices(ctx, &proto.ListDevicesRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("ListDevices() error = %v", err)
+	}
+	if len(list.Devices) != 4 || list.Devices[0].DeviceId != "primary" || list.Devices[0].ClientIp != primary.ClientIp {
+		t.Errorf("ListDevices() = %v", list.Devices)
+	}
+	if got, _ := s.GetClient(ctx, &proto.GetClientRequest{UserId: "alice"}); got.GetDeviceCount() != 4 {
+		t.Errorf("GetClient() device_count = %d, want 4", got.GetDeviceCount())
+	}
+	clients, _ := s.ListClients(ctx, &proto.ListClientsRequest{})
+	if len(clients.Clients) != 1 || clients.Clients[0].DeviceCount != 4 {
+		t.Errorf("ListClients() = %v, want one client with 4 devices", clients.Clients)
+	}
+
+	config, err := s.GetClientConfig(ctx, &proto.GetClientConfigRequest{UserId: "alice", DeviceId: "phone"})
+	if err != nil || config.ConfigFile != phone.Config.ConfigFile {
+		t.Errorf("GetClientConfig(phone) = %v, %v", config, err)
+	}
+	if _, err := s.GetClientConfig(ctx, &proto.GetClientConfigRequest{UserId: "alice", DeviceId: "laptop"}); errorReason(err) != proto.ErrorReason_DEVICE_NOT_FOUND.String() {
+		t.Errorf("GetClientConfig(laptop) error = %v, want DEVICE_NOT_FOUND", err)
+	}
+	assertConsistent(t, s, wg)
+}
+
+func TestDevices_ClientOperations(t *testing.T) {
+	s, wg := newTestService(t)
+	ctx := context.Background()
+
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"}); err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	if _, err := s.CreateDevice(ctx, &proto.CreateDeviceRequest{UserId: "alice", DeviceId: "phone"}); err != nil {
+		t.Fatalf("CreateDevice() error = %v", err)
+	}
+
+	// DisableClient отключает все устройства разом
+	if resp, err := s.DisableClient(ctx, &proto.DisableClientRequest{UserId: "alice"}); err != nil || !resp.Success {
+		t.Fatalf("DisableClient() = %v, %v", resp, err)
+	}
+	if peers := devicePeers(t, wg); len(peers) != 0 {
+		t.Errorf("device has %d peers after DisableClient", len(peers))
+	}
+
+	// Новое устройство отключенного клиента тоже отключено
+	if _, err := s.CreateDevice(ctx, &proto.CreateDeviceRequest{UserId: "alice", DeviceId: "tv"}); err != nil {
+		t.Fatalf("CreateDevice() error = %v", err)
+	}
+	assertConsistent(t, s, wg)
+
+	if resp, err := s.EnableClient(ctx, &proto.EnableClientRequest{UserId: "alice"}); err != nil || !resp.Success {
+		t.Fatalf("EnableClient() = %v, %v", resp, err)
+	}
+	if peers := devicePeers(t, wg); len(peers) != 3 {
+		t.Errorf("device has %d peers after EnableClient, want 3", len(peers))
+	}
+
+	for _, id := range []string{"", "primary"} {
+		if _, err := s.DeleteDevice(ctx, &proto.DeleteDeviceRequest{UserId: "alice", DeviceId: id}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("DeleteDevice(%q) error = %v, want InvalidArgument", id, err)
+		}
+	}
+	if _, err := s.DeleteDevice(ctx, &proto.DeleteDeviceRequest{UserId: "alice", DeviceId: "tv"}); err != nil {
+		t.Fatalf("DeleteDevice() error = %v", err)
+	}
+	if _, err := s.DeleteDevice(ctx, &proto.DeleteDeviceRequest{UserId: "alice", DeviceId: "tv"}); status.Code(err) != codes.NotFound {
+		t.Errorf("DeleteDevice() twice error = %v, want NotFound", err)
+	}
+	assertConsistent(t, s, wg)
+
+	// DeleteClient удаляет клиента вместе с устройствами
+	if _, err := s.DeleteClient(ctx, &proto.DeleteClientRequest{UserId: "alice"}); err != nil {
+		t.Fatalf("DeleteClient() error = %v", err)
+	}
+	if n := len(s.clients.List()); n != 0 {
+		t.Errorf("store has %d clients after DeleteClient", n)
+	}
+	if peers := devicePeers(t, wg); len(peers) != 0 {
+		t.Errorf("device has %d peers after DeleteClient", len(peers))
+	}
+}
+
+func TestDevices_RotateAndPresharedKey(t *testing.T) {
+	s, wg := newTestService(t, func(cfg *config.Config) { cfg.PSKPolicy = string(PSKPerRequest) })
+	ctx := context.Background()
+
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"}); err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	if _, err := s.CreateDevice(ctx, &proto.CreateDeviceRequest{UserId: "alice", DeviceId: "phone"}); err != nil {
+		t.Fatalf("CreateDevice() error = %v", err)
+	}
+	primary, _ := s.clients.Get("alice")
+	phone, _ := s.clients.GetDevice("alice", "phone")
+
+	// Потерянный телефон: новые ключи, тот же адрес, основное устройство не трогается
+	rotated, err := s.RotateClientKeys(ctx, &proto.RotateClientKeysRequest{UserId: "alice", DeviceId: "phone"})
+	if err != nil {
+		t.Fatalf("RotateClientKeys(phone) error = %v", err)
+	}
+	updated, _ := s.clients.GetDevice("alice", "phone")
+	if rotated.ClientIp != phone.AllowedIP || updated.PublicKey == phone.PublicKey {
+		t.Errorf("rotated phone = %s %s, want new key on %s", rotated.ClientIp, updated.PublicKey, phone.AllowedIP)
+	}
+	if got, _ := s.clients.Get("alice"); got.PublicKey != primary.PublicKey {
+		t.Error("rotating a device changed the primary device key")
+	}
+
+	psk, err := s.AddPresharedKey(ctx, &proto.AddPresharedKeyRequest{UserId: "alice", DeviceId: "phone"})
+	if err != nil || !psk.HasPresharedKey {
+		t.Fatalf("AddPresharedKey(phone) = %v, %v", psk, err)
+	}
+	if got, _ := s.clients.Get("alice"); got.PresharedKey != "" {
+		t.Error("preshared key added to the primary device")
+	}
+	assertConsistent(t, s, wg)
+
+	for _, call := range []func() error{
+		func() error {
+			_, err := s.RotateClientKeys(ctx, &proto.RotateClientKeysRequest{UserId: "alice", DeviceId: "tv"})
+			return err
+		},
+		func() error {
+			_, err := s.AddPresharedKey(ctx, &proto.AddPresharedKeyRequest{UserId: "alice", DeviceId: "tv"})
+			return err
+		},
+	} {
+		if err := call(); errorReason(err) != proto.ErrorReason_DEVICE_NOT_FOUND.String() {
+			t.Errorf("unknown device error = %v, want DEVICE_NOT_FOUND", err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/quibex/wg-agent/internal/wireguard"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain домен причин в google.rpc.ErrorInfo
+const errorDomain = "wg-agent"
+
+// errorModeHeader заголовок запроса: "status" - DisableClient/EnableClient
+// возвращают ошибки статусом gRPC вместо success=false
+const errorModeHeader = "x-wg-agent-errors"
+
+// statusError ошибка gRPC с причиной в ErrorInfo. kv - пары ключ/значение metadata.
+func statusError(code codes.Code, reason proto.ErrorReason, msg string, kv ...string) error {
+	info := &errdetails.ErrorInfo{Reason: reason.String(), Domain: errorDomain}
+	if len(kv) > 0 {
+		info.Metadata = make(map[string]string, len(kv)/2)
+		for i := 0; i+1 < len(kv); i += 2 {
+			info.Metadata[kv[i]] = kv[i+1]
+		}
+	}
+	st, err := status.New(code, msg).WithDetails(info)
+	if err != nil {
+		return status.Error(code, msg)
+	}
+	return st.Err()
+}
+
+// invalidArgument ошибка в поле запроса: ErrorInfo и BadRequest с именем поля
+func invalidArgument(field, msg string) error {
+	st, err := status.New(codes.InvalidArgument, msg).WithDetails(
+		&errdetails.ErrorInfo{
+			Reason:   proto.ErrorReason_INVALID_ARGUMENT.String(),
+			Domain:   errorDomain,
+			Metadata: map[string]string{"field": field},
+		},
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: field, Description: msg},
+		}},
+	)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, msg)
+	}
+	return st.Err()
+}
+
+// invalidField ошибка проверки поля запроса с текстом "invalid <field>: <err>"
+func invalidField(field string, err error) error {
+	return invalidArgument(field, fmt.Sprintf("invalid %s: %v", field, err))
+}
+
+func userIDRequired() error {
+	return invalidArgument("user_id", "user_id is required")
+}
+
+func clientNotFound(userID string) error {
+	return statusError(codes.NotFound, proto.ErrorReason_CLIENT_NOT_FOUND, "client not found", "user_id", userID)
+}
+
+// deviceError ошибка обращения к интерфейсу WireGuard. Ошибки, уже
+// переведённые в статус (например, при сборке пира), возвращаются как есть.
+func (s *agentService) deviceError(msg string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return statusError(codes.Unavailable, proto.ErrorReason_DEVICE_UNAVAILABLE,
+		fmt.Sprintf("%s: %v", msg, err), "interface", s.iface)
+}
+
+// storeError ошибка хранилища клиентов
+func storeError(msg string, err error) error {
+	switch {
+	case errors.Is(err, wireguard.ErrClientNotFound):
+		return statusError(codes.NotFound, proto.ErrorReason_CLIENT_NOT_FOUND, "client not found")
+	case errors.Is(err, wireguard.ErrClientExists):
+		return statusError(codes.AlreadyExists, proto.ErrorReason_CLIENT_ALREADY_EXISTS, err.Error())
+	case errors.Is(err, wireguard.ErrPublicKeyInUse):
+		return statusError(codes.AlreadyExists, proto.ErrorReason_PUBLIC_KEY_IN_USE, err.Error())
+	case errors.Is(err, wireguard.ErrIPInUse):
+		return statusError(codes.AlreadyExists, proto.ErrorReason_IP_IN_USE, err.Error())
+	}
+	return statusError(codes.Internal, proto.ErrorReason_STORAGE_FAILED, fmt.Sprintf("%s: %v", msg, err))
+}
+
+// internalError ошибка, не зависящая от запроса (генерация ключей, шифрование)
+func internalError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return statusError(codes.Internal, proto.ErrorReason_INTERNAL, err.Error())
+}
+
+// statusErrorMode сообщает, что клиент просит ошибки статусом gRPC
+// вместо устаревших полей success/message
+func statusErrorMode(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get(errorModeHeader) {
+		if v == "status" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quibex/wg-agent/internal/config"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// errorReason причина из ErrorInfo в деталях статуса
+func errorReason(err error) string {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+func TestErrorCodes(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"}); err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		call   func() error
+		code   codes.Code
+		reason proto.ErrorReason
+	}{
+		{"create without user_id", func() error {
+			_, err := s.CreateClient(ctx, &proto.CreateClientRequest{})
+			return err
+		}, codes.InvalidArgument, proto.ErrorReason_INVALID_ARGUMENT},
+		{"create existing", func() error {
+			_, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"})
+			return err
+		}, codes.AlreadyExists, proto.ErrorReason_CLIENT_ALREADY_EXISTS},
+		{"unknown pool", func() error {
+			_, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob", Pool: "nope"})
+			return err
+		}, codes.InvalidArgument, proto.ErrorReason_UNKNOWN_POOL},
+		{"get missing", func() error {
+			_, err := s.GetClient(ctx, &proto.GetClientRequest{UserId: "bob"})
+			return err
+		}, codes.NotFound, proto.ErrorReason_CLIENT_NOT_FOUND},
+		{"delete missing", func() error {
+			_, err := s.DeleteClient(ctx, &proto.DeleteClientRequest{UserId: "bob"})
+			return err
+		}, codes.NotFound, proto.ErrorReason_CLIENT_NOT_FOUND},
+		{"psk disabled", func() error {
+			_, err := s.AddPresharedKey(ctx, &proto.AddPresharedKeyRequest{UserId: "alice"})
+			return err
+		}, codes.FailedPrecondition, proto.ErrorReason_PRESHARED_KEY_DISABLED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if status.Code(err) != tt.code || errorReason(err) != tt.reason.String() {
+				t.Errorf("error = %v (reason %q), want %v %v", err, errorReason(err), tt.code, tt.reason)
+			}
+		})
+	}
+}
+
+func TestErrorCodes_BadRequestField(t *testing.T) {
+	s, _ := newTestService(t)
+
+	_, err := s.CreateClient(context.Background(), &proto.CreateClientRequest{UserId: "alice", TunnelName: "bad name"})
+	for _, d := range status.Convert(err).Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			if got := br.FieldViolations[0].Field; got != "tunnel_name" {
+				t.Errorf("field = %q, want tunnel_name", got)
+			}
+			return
+		}
+	}
+	t.Fatalf("error %v has no BadRequest details", err)
+}
+
+func TestErrorCodes_DeviceUnavailable(t *testing.T) {
+	s, _ := newTestService(t, func(cfg *config.Config) { cfg.Interface = "wg9" })
+
+	_, err := s.CreateClient(context.Background(), &proto.CreateClientRequest{UserId: "alice"})
+	if status.Code(err) != codes.Unavailable || errorReason(err) != proto.ErrorReason_DEVICE_UNAVAILABLE.String() {
+		t.Errorf("error = %v, want Unavailable DEVICE_UNAVAILABLE", err)
+	}
+}
+
+func TestDisableClient_ErrorMode(t *testing.T) {
+	s, _ := newTestService(t)
+
+	// Без заголовка - прежний ответ success=false
+	resp, err := s.DisableClient(context.Background(), &proto.DisableClientRequest{UserId: "bob"})
+	if err != nil || resp.Success || resp.Message != "client not found" {
+		t.Errorf("legacy DisableClient() = %v, %v", resp, err)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(errorModeHeader, "status"))
+	if _, err := s.DisableClient(ctx, &proto.DisableClientRequest{UserId: "bob"}); status.Code(err) != codes.NotFound {
+		t.Errorf("DisableClient() error = %v, want NotFound", err)
+	}
+	if _, err := s.EnableClient(ctx, &proto.EnableClientRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("EnableClient() error = %v, want InvalidArgument", err)
+	}
+}
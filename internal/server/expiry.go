@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/quibex/wg-agent/internal/wireguard"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+)
+
+// parseExpiresAt разбирает expires_at из запроса: unix timestamp в будущем,
+// 0 - бессрочно
+func parseExpiresAt(unix int64) (*time.Time, error) {
+	if unix == 0 {
+		return nil, nil
+	}
+	if unix < 0 {
+		return nil, invalidArgument("expires_at", "expires_at must be a unix timestamp")
+	}
+	t := time.Unix(unix, 0).UTC()
+	if !t.After(time.Now()) {
+		return nil, invalidArgument("expires_at", "expires_at is in the past")
+	}
+	return &t, nil
+}
+
+// expiresAtUnix срок подписки клиента для ответа, 0 - бессрочно
+func expiresAtUnix(client *wireguard.ClientData) int64 {
+	if client.ExpiresAt == nil {
+		return 0
+	}
+	return client.ExpiresAt.Unix()
+}
+
+// ExtendClient меняет срок подписки клиента и при enable включает его.
+// Без expires_at, duration_seconds и clear_expiry срок остаётся прежним.
+func (s *agentService) ExtendClient(ctx context.Context, req *proto.ExtendClientRequest) (*proto.ExtendClientResponse, error) {
+	userID := req.UserId
+	if userID == "" {
+		return nil, userIDRequired()
+	}
+	if req.ExpiresAt != 0 && req.DurationSeconds != 0 {
+		return nil, invalidArgument("duration_seconds", "expires_at and duration_seconds are mutually exclusive")
+	}
+	if req.ClearExpiry && (req.ExpiresAt != 0 || req.DurationSeconds != 0) {
+		return nil, invalidArgument("clear_expiry", "clear_expiry is mutually exclusive with expires_at and duration_seconds")
+	}
+	if req.DurationSeconds < 0 {
+		return nil, invalidArgument("duration_seconds", "duration_seconds must not be negative")
+	}
+	expiresAt, err := parseExpiresAt(req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+	defer s.users.lock(userID)()
+
+	client, exists := s.clients.Get(userID)
+	if !exists {
+		return nil, clientNotFound(userID)
+	}
+
+	// Продление считается от текущего срока, а если он прошёл - от сейчас
+	if req.DurationSeconds > 0 {
+		from := time.Now()
+		if client.ExpiresAt != nil && client.ExpiresAt.After(from) {
+			from = *client.ExpiresAt
+		}
+		t := from.Add(time.Duration(req.DurationSeconds) * time.Second).Truncate(time.Second)
+		expiresAt = &t
+	}
+
+	if expiresAt != nil || req.ClearExpiry {
+		if err := s.clients.SetExpiresAt(userID, expiresAt); err != nil {
+			return nil, storeError("failed to save client", err)
+		}
+		client.ExpiresAt = expiresAt
+		s.log.Info("client extended", "user_id", userID, "expires_at", expiresAt)
+	}
+
+	if req.Enable && !client.Enabled {
+		if _, err := s.enableLocked(userID); err != nil {
+			return nil, err
+		}
+		client.Enabled = true
+	}
+
+	return &proto.ExtendClientResponse{
+		ExpiresAt: expiresAtUnix(client),
+		Enabled:   client.Enabled,
+	}, nil
+}
+
+// expireClients отключает клиентов, срок подписки которых (с отсрочкой
+// WG_AGENT_EXPIRY_GRACE) прошёл. Тем же путём, что и DisableClient.
+func (s *agentService) expireClients() {
+	for _, client := range s.clients.ListExpired(time.Now().Add(-s.expiryGrace)) {
+		s.expireClient(client.UserID)
+	}
+}
+
+// expireClient отключает клиента с истёкшим сроком
+func (s *agentService) expireClient(userID string) {
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+	defer s.users.lock(userID)()
+
+	// Срок могли продлить, пока ждали блокировку
+	client, exists := s.clients.Get(userID)
+	if !exists || !client.Enabled || !client.Expired(time.Now().Add(-s.expiryGrace)) {
+		return
+	}
+	if _, err := s.disableLocked(userID); err != nil {
+		s.log.Error("Failed to disable expired client", "user_id", userID, "error", err)
+		return
+	}
+	s.log.Info("client expired", "user_id", userID, "expires_at", client.ExpiresAt)
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/quibex/wg-agent/internal/config"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestExpireClients(t *testing.T) {
+	s, wg := newTestService(t, func(cfg *config.Config) { cfg.ExpiryGrace = time.Hour })
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(24 * time.Hour).Unix()
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", ExpiresAt: expiresAt}); err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	if _, err := s.CreateDevice(ctx, &proto.CreateDeviceRequest{UserId: "alice", DeviceId: "phone"}); err != nil {
+		t.Fatalf("CreateDevice() error = %v", err)
+	}
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob"}); err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	if got, _ := s.GetClient(ctx, &proto.GetClientRequest{UserId: "alice"}); got.GetExpiresAt() != expiresAt {
+		t.Errorf("GetClient() expires_at = %d, want %d", got.GetExpiresAt(), expiresAt)
+	}
+
+	// Срок прошёл, но отсрочка ещё нет
+	past := time.Now().Add(-time.Minute)
+	if err := s.clients.SetExpiresAt("alice", &past); err != nil {
+		t.Fatalf("SetExpiresAt() error = %v", err)
+	}
+	s.expireClients()
+	if client, _ := s.clients.Get("alice"); !client.Enabled {
+		t.Fatal("client disabled during grace period")
+	}
+
+	past = time.Now().Add(-2 * time.Hour)
+	if err := s.clients.SetExpiresAt("alice", &past); err != nil {
+		t.Fatalf("SetExpiresAt() error = %v", err)
+	}
+	s.expireClients()
+	for _, d := range s.clients.ListDevices("alice") {
+		if d.Enabled {
+			t.Errorf("device %s enabled after expiry", d.ID())
+		}
+	}
+	if client, _ := s.clients.Get("bob"); !client.Enabled {
+		t.Error("client without expires_at was disabled")
+	}
+	assertConsistent(t, s, wg)
+
+	// Продление от текущего момента, так как срок уже прошёл
+	resp, err := s.ExtendClient(ctx, &proto.ExtendClientRequest{UserId: "alice", DurationSeconds: 3600, Enable: true})
+	if err != nil {
+		t.Fatalf("ExtendClient() error = %v", err)
+	}
+	if want := time.Now().Add(time.Hour).Unix(); !resp.Enabled || resp.ExpiresAt < want-5 || resp.ExpiresAt > want {
+		t.Errorf("ExtendClient() = %v, want enabled until ~%d", resp, want)
+	}
+	s.expireClients()
+	if peers := devicePeers(t, wg); len(peers) != 3 {
+		t.Errorf("device has %d peers after ExtendClient, want 3", len(peers))
+	}
+
+	// Повторное продление считается от нового срока
+	again, err := s.ExtendClient(ctx, &proto.ExtendClientRequest{UserId: "alice", DurationSeconds: 3600})
+	if err != nil || again.ExpiresAt != resp.ExpiresAt+3600 {
+		t.Errorf("ExtendClient() = %v, %v, want expires_at %d", again, err, resp.ExpiresAt+3600)
+	}
+	// Без нового срока срок не меняется
+	if resp, err := s.ExtendClient(ctx, &proto.ExtendClientRequest{UserId: "alice", Enable: true}); err != nil || resp.ExpiresAt != again.ExpiresAt {
+		t.Errorf("ExtendClient(enable) = %v, %v, want expires_at %d", resp, err, again.ExpiresAt)
+	}
+	if resp, err := s.ExtendClient(ctx, &proto.ExtendClientRequest{UserId: "alice", ClearExpiry: true}); err != nil || resp.ExpiresAt != 0 {
+		t.Errorf("ExtendClient(clear_expiry) = %v, %v, want no expiry", resp, err)
+	}
+}
+
+func TestEnableClient_Expired(t *testing.T) {
+	s, wg := newTestService(t, func(cfg *config.Config) { cfg.ExpiryGrace = time.Hour })
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(errorModeHeader, "status"))
+
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", ExpiresAt: time.Now().Add(time.Hour).Unix()}); err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	if _, err := s.DisableClient(ctx, &proto.DisableClientRequest{UserId: "alice"}); err != nil {
+		t.Fatalf("DisableClient() error = %v", err)
+	}
+
+	// В пределах отсрочки клиента ещё можно включить
+	past := time.Now().Add(-time.Minute)
+	if err := s.clients.SetExpiresAt("alice", &past); err != nil {
+		t.Fatalf("SetExpiresAt() error = %v", err)
+	}
+	if _, err := s.EnableClient(ctx, &proto.EnableClientRequest{UserId: "alice"}); err != nil {
+		t.Fatalf("EnableClient() during grace error = %v", err)
+	}
+	if _, err := s.DisableClient(ctx, &proto.DisableClientRequest{UserId: "alice"}); err != nil {
+		t.Fatalf("DisableClient() error = %v", err)
+	}
+
+	past = time.Now().Add(-2 * time.Hour)
+	if err := s.clients.SetExpiresAt("alice", &past); err != nil {
+		t.Fatalf("SetExpiresAt() error = %v", err)
+	}
+	_, err := s.EnableClient(ctx, &proto.EnableClientRequest{UserId: "alice"})
+	if status.Code(err) != codes.FailedPrecondition || errorReason(err) != proto.ErrorReason_SUBSCRIPTION_EXPIRED.String() {
+		t.Errorf("EnableClient() expired error = %v, want FailedPrecondition SUBSCRIPTION_EXPIRED", err)
+	}
+	if legacy, err := s.EnableClient(context.Background(), &proto.EnableClientRequest{UserId: "alice"}); err != nil || legacy.Success {
+		t.Errorf("legacy EnableClient() expired = %v, %v, want success=false", legacy, err)
+	}
+	if peers := devicePeers(t, wg); len(peers) != 0 {
+		t.Errorf("device has %d peers after refused enable", len(peers))
+	}
+
+	if resp, err := s.ExtendClient(ctx, &proto.ExtendClientRequest{UserId: "alice", DurationSeconds: 3600, Enable: true}); err != nil || !resp.Enabled {
+		t.Errorf("ExtendClient(enable) = %v, %v", resp, err)
+	}
+}
+
+func TestExpiresAt_Invalid(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour).Unix()
+
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", ExpiresAt: past}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateClient() with past expires_at error = %v, want InvalidArgument", err)
+	}
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"}); err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  *proto.ExtendClientRequest
+		code codes.Code
+	}{
+		{"past", &proto.ExtendClientRequest{UserId: "alice", ExpiresAt: past}, codes.InvalidArgument},
+		{"both", &proto.ExtendClientRequest{UserId: "alice", ExpiresAt: time.Now().Add(time.Hour).Unix(), DurationSeconds: 60}, codes.InvalidArgument},
+		{"negative", &proto.ExtendClientRequest{UserId: "alice", DurationSeconds: -60}, codes.InvalidArgument},
+		{"clear with duration", &proto.ExtendClientRequest{UserId: "alice", DurationSeconds: 60, ClearExpiry: true}, codes.InvalidArgument},
+		{"unknown user", &proto.ExtendClientRequest{UserId: "bob", DurationSeconds: 60}, codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.ExtendClient(ctx, tt.req); status.Code(err) != tt.code {
+				t.Errorf("ExtendClient() error = %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
@@ -5,11 +5,18 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/quibex/wg-agent/internal/config"
 	"github.com/quibex/wg-agent/internal/wireguard"
 	proto "github.com/quibex/wg-agent/pkg/api/proto"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -20,218 +27,445 @@ type agentService struct {
 	wgClient       wireguard.Client
 	iface          string // WireGuard интерфейс (wg0)
 	clients        *wireguard.ClientStore
-	subnet         string // подсеть для IP (10.8.0.0/24)
-	serverEndpoint string // endpoint для клиентов (vpn.example.com:51820)
+	sealer         *wireguard.KeySealer // nil - секреты клиентов хранятся открыто
+	pskPolicy      PresharedKeyPolicy
+	pools          map[string]*wireguard.IPAllocator // IPv4 пулы адресов по имени (default - 10.8.0.0/24)
+	defaultPool    string                            // пул для CreateClient без pool
+	pool6          *wireguard.IPAllocator            // IPv6 адреса (fd42:42:42::/64), nil - только IPv4
+	serverEndpoint string                            // endpoint для клиентов (vpn.example.com:51820)
+	wgConfigPath   string                            // конфиг wg-quick (/etc/wireguard/wg0.conf)
+	configSync     *wireguard.ConfigSyncer           // nil - конфиг wg-quick не переписывается
+	template       wireguard.ClientTemplate          // параметры конфига клиента по умолчанию
+	splitTunnels   map[string][]string               // AllowedIPs пресетов split-tunnel по имени
+	maxDevices     int                               // лимит устройств клиента по умолчанию, 0 - без лимита
+	expiryGrace    time.Duration                     // отсрочка отключения после expires_at
+
+	// syncMu: операции RPC над устройством и хранилищем берут RLock,
+	// сверка (reconcile) берёт Lock, чтобы видеть согласованное состояние
+	syncMu        sync.RWMutex
+	users         userLocks // операции над одним user_id выполняются по очереди
+	unknownPolicy UnknownPeerPolicy
+	reconcileMu   sync.Mutex
+	lastReconcile *ReconcileResult // последний результат сверки, под reconcileMu
 }
 
-func newAgentService(log *slog.Logger, wgClient wireguard.Client, iface, subnet, serverEndpoint string) *agentService {
-	return &agentService{
+func newAgentService(cfg *config.Config, log *slog.Logger, wgClient wireguard.Client, clients *wireguard.ClientStore, sealer *wireguard.KeySealer) (*agentService, error) {
+	unknownPolicy, err := ParseUnknownPeerPolicy(cfg.UnknownPeerPolicy)
+	if err != nil {
+		return nil, err
+	}
+	pskPolicy, err := ParsePresharedKeyPolicy(cfg.PSKPolicy)
+	if err != nil {
+		return nil, err
+	}
+	template, err := parseClientTemplate(cfg)
+	if err != nil {
+		return nil, err
+	}
+	addressPools, err := cfg.AddressPools()
+	if err != nil {
+		return nil, err
+	}
+	reserved := strings.Split(cfg.ReservedIPs, ",")
+	pools := make(map[string]*wireguard.IPAllocator, len(addressPools))
+	for _, p := range addressPools {
+		pool, err := wireguard.NewIPAllocator(p.Subnet, reserved)
+		if err != nil {
+			return nil, fmt.Errorf("pool %s: %w", p.Name, err)
+		}
+		clients.AttachAllocator(pool)
+		pools[p.Name] = pool
+	}
+	defaultPool := cfg.DefaultPool
+	if defaultPool == "" {
+		defaultPool = config.DefaultPoolName
+	}
+	if pools[defaultPool] == nil {
+		return nil, fmt.Errorf("default pool %q is not configured", defaultPool)
+	}
+	clients.SetQuarantine(cfg.IPQuarantine)
+	clients.SetIdempotencyTTL(cfg.IdempotencyTTL)
+
+	var pool6 *wireguard.IPAllocator
+	if cfg.Subnet6 != "" {
+		ip, _, err := net.ParseCIDR(cfg.Subnet6)
+		if err != nil || ip.To4() != nil {
+			return nil, fmt.Errorf("invalid IPv6 subnet %q", cfg.Subnet6)
+		}
+		if pool6, err = wireguard.NewIPAllocator(cfg.Subnet6, reserved); err != nil {
+			return nil, err
+		}
+		clients.AttachAllocator(pool6)
+	}
+
+	splitTunnels, err := parseSplitTunnels(cfg, pool6 != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &agentService{
 		log:            log,
 		wgClient:       wgClient,
-		iface:          iface,
-		clients:        wireguard.NewClientStore(),
-		subnet:         subnet,
-		serverEndpoint: serverEndpoint,
+		iface:          cfg.Interface,
+		clients:        clients,
+		sealer:         sealer,
+		pskPolicy:      pskPolicy,
+		pools:          pools,
+		defaultPool:    defaultPool,
+		pool6:          pool6,
+		serverEndpoint: cfg.ServerEndpoint(),
+		wgConfigPath:   cfg.WGConfigPath,
+		unknownPolicy:  unknownPolicy,
+		template:       template,
+		splitTunnels:   splitTunnels,
+		maxDevices:     cfg.MaxDevices,
+		expiryGrace:    cfg.ExpiryGrace,
+	}
+	if cfg.WGConfigSync {
+		s.configSync = wireguard.NewConfigSyncer(cfg.WGConfigPath, clients, sealer)
 	}
+	return s, nil
 }
 
 // CreateClient создаёт нового VPN клиента.
 func (s *agentService) CreateClient(ctx context.Context, req *proto.CreateClientRequest) (*proto.CreateClientResponse, error) {
 	userID := req.UserId
 	if userID == "" {
-		return nil, fmt.Errorf("user_id is required")
+		return nil, userIDRequired()
 	}
 
-	// Проверяем, что клиент ещё не существует
-	if s.clients.Exists(userID) {
-		return nil, fmt.Errorf("client %s already exists", userID)
+	render, err := parseRenderOptions(req.QrOptions, req.OutputFormats, req.DeepLinkOptions)
+	if err != nil {
+		return nil, err
+	}
+	template, err := s.newClientTemplate(req.ConfigOptions)
+	if err != nil {
+		return nil, err
+	}
+	if req.TunnelName != "" {
+		if err := wireguard.ValidateTunnelName(req.TunnelName); err != nil {
+			return nil, invalidField("tunnel_name", err)
+		}
+	}
+	if err := wireguard.ValidateLabels(req.Labels); err != nil {
+		return nil, invalidField("labels", err)
+	}
+	ifExists, err := parseIfExistsPolicy(req.IfExists)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateIdempotencyKey(req.IdempotencyKey); err != nil {
+		return nil, err
+	}
+	expiresAt, err := parseExpiresAt(req.ExpiresAt)
+	if err != nil {
+		return nil, err
 	}
 
-	// Проверяем, что сервер настроен
-	if s.serverEndpoint == "" {
-		return nil, fmt.Errorf("server not configured: SERVER_PUBLIC_IP is required")
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+	defer s.users.lock(userID)()
+
+	// Повтор запроса с тем же idempotency_key отдаёт уже созданного клиента
+	if client, err := s.replayedClient(req.IdempotencyKey, userID); err != nil {
+		return nil, err
+	} else if client != nil {
+		client, artifacts, _, err := s.existingClient(client, IfExistsReturnExisting, "", render)
+		if err != nil {
+			return nil, err
+		}
+		return createClientResponse(client, artifacts, resultReplayed), nil
 	}
 
-	// Генерируем ключи
-	privateKey, publicKey, err := wireguard.GenerateKeyPair()
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate keys: %w", err)
+	// Клиент уже существует: ошибка, его конфиг или новые ключи по if_exists
+	if client, exists := s.clients.Get(userID); exists {
+		client, artifacts, result, err := s.existingClient(client, ifExists, req.PublicKey, render)
+		if err != nil {
+			return nil, err
+		}
+		s.rememberIdempotencyKey(req.IdempotencyKey, userID)
+		return createClientResponse(client, artifacts, result), nil
 	}
 
-	// Выделяем IP адрес
-	usedIPs := s.clients.GetUsedIPs()
-	clientIP, err := wireguard.AllocateIP(s.subnet, usedIPs)
+	client := &wireguard.ClientData{
+		UserID:     userID,
+		Enabled:    true,
+		Template:   &template,
+		TunnelName: req.TunnelName,
+		Labels:     req.Labels,
+		ExpiresAt:  expiresAt,
+	}
+	serverPublicKey, err := s.provision(client, provisionRequest{
+		publicKey:    req.PublicKey,
+		presharedKey: req.PresharedKey,
+		pool:         req.Pool,
+		requestedIP:  req.RequestedIp,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to allocate IP: %w", err)
+		return nil, err
 	}
 
-	// Получаем публичный ключ сервера
-	device, err := s.wgClient.Device(s.iface)
+	// Генерируем конфиг, QR код и deep link
+	artifacts, err := s.renderClient(client, serverPublicKey, render)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get WireGuard device: %w", err)
+		return nil, err
 	}
-	serverPublicKey := device.PublicKey.String()
 
-	// Добавляем пира в WireGuard
-	key, _ := wgtypes.ParseKey(publicKey)
-	_, ipNet, _ := net.ParseCIDR(clientIP)
-	keepalive := 25 * time.Second
+	s.syncConfig()
+	s.rememberIdempotencyKey(req.IdempotencyKey, userID)
+	s.log.Info("client created", "user_id", userID, "client_ip", client.AllowedIP, "pool", client.Pool, "key_mode", client.KeyMode())
 
-	cfg := wgtypes.Config{
-		Peers: []wgtypes.PeerConfig{{
-			PublicKey:                   key,
-			AllowedIPs:                  []net.IPNet{*ipNet},
-			ReplaceAllowedIPs:           true,
-			PersistentKeepaliveInterval: &keepalive,
-		}},
+	return createClientResponse(client, artifacts, resultCreated), nil
+}
+
+// provisionRequest параметры нового пира из запроса
+type provisionRequest struct {
+	publicKey    string // ключ клиента, пусто - сгенерировать пару
+	presharedKey bool   // запрошен preshared key (по политике сервера)
+	pool         string // пул адресов, пусто - по умолчанию
+	requestedIP  string // конкретный адрес, пусто - любой свободный
+}
+
+// provision выдаёт новому клиенту или устройству ключи и адреса, добавляет
+// пира на устройство (если client.Enabled) и создаёт запись в хранилище.
+// В client должны быть заданы UserID, DeviceID и параметры конфига, остальное
+// заполняется здесь. Возвращает публичный ключ сервера для конфига.
+// Вызывается под s.syncMu.RLock и блокировкой user_id.
+func (s *agentService) provision(client *wireguard.ClientData, req provisionRequest) (string, error) {
+	// Проверяем, что сервер настроен
+	if s.serverEndpoint == "" {
+		return "", statusError(codes.FailedPrecondition, proto.ErrorReason_SERVER_NOT_CONFIGURED,
+			"server not configured: SERVER_PUBLIC_IP is required")
 	}
 
-	if err := s.wgClient.ConfigureDevice(s.iface, cfg); err != nil {
-		return nil, fmt.Errorf("failed to add peer to WireGuard: %w", err)
+	// Генерируем ключи или принимаем публичный ключ клиента. Присланный ключ
+	// блокируется, чтобы два пользователя не заняли один ключ одновременно
+	if req.publicKey != "" {
+		defer s.users.lock(publicKeyLock(req.publicKey))()
+	}
+	storedKey, publicKey, err := s.clientKeys(req.publicKey)
+	if err != nil {
+		return "", err
 	}
 
-	// Сохраняем клиента
-	s.clients.Add(&wireguard.ClientData{
-		UserID:     userID,
-		PublicKey:  publicKey,
-		PrivateKey: privateKey,
-		AllowedIP:  clientIP,
-		Enabled:    true,
-	})
+	// Preshared key по политике сервера
+	var presharedKey string
+	if usePSK, err := s.pskPolicy.use(req.presharedKey); err != nil {
+		return "", err
+	} else if usePSK {
+		if presharedKey, err = s.newPresharedKey(); err != nil {
+			return "", err
+		}
+	}
 
-	// Генерируем конфиг
-	configFile := wireguard.GenerateClientConfig(
-		privateKey,
-		serverPublicKey,
-		s.serverEndpoint,
-		"0.0.0.0/0",       // весь трафик через VPN
-		"1.1.1.1, 1.0.0.1", // Cloudflare DNS
-		clientIP,
-	)
-
-	// Генерируем QR код
-	qrCode, err := wireguard.GenerateQRCode(configFile)
+	// Получаем публичный ключ сервера
+	device, err := s.wgClient.Device(s.iface)
 	if err != nil {
-		s.log.Warn("failed to generate QR code", "error", err)
-		qrCode = ""
+		return "", s.deviceError("failed to get WireGuard device", err)
+	}
+	serverPublicKey := device.PublicKey.String()
+	if publicKey == serverPublicKey {
+		return "", invalidArgument("public_key", "public_key must not be the server key")
 	}
 
-	// Генерируем deep link для автоимпорта
-	deepLink := wireguard.GenerateWireGuardLink(configFile)
+	// Выделяем IP адрес (и IPv6, если он включён)
+	poolName, clientIP, clientIP6, err := s.allocateIPs(client.UserID, req.pool, req.requestedIP)
+	if err != nil {
+		return "", err
+	}
 
-	s.log.Info("client created", "user_id", userID, "client_ip", clientIP)
+	client.PublicKey = publicKey
+	client.PrivateKey = storedKey
+	client.PresharedKey = presharedKey
+	client.AllowedIP, client.AllowedIP6 = clientIP, clientIP6
+	client.Pool = poolName
 
-	return &proto.CreateClientResponse{
-		ConfigFile:   configFile,
-		QrCodeBase64: qrCode,
-		DeepLink:     deepLink,
-		ClientIp:     clientIP,
-	}, nil
+	// Добавляем пира в WireGuard
+	if client.Enabled {
+		if err := s.addPeer(client); err != nil {
+			s.clients.ReleaseIPs(client.AllowedIPs()...)
+			return "", s.deviceError("failed to add peer to WireGuard", err)
+		}
+	}
+
+	// Сохраняем клиента, при ошибке убираем пира обратно
+	if err := s.clients.Create(client); err != nil {
+		if client.Enabled {
+			s.rollback(s.removePeer(client.PublicKey))
+			s.restorePeers(client.AllowedIPs())
+		}
+		s.clients.ReleaseIPs(client.AllowedIPs()...)
+		return "", storeError("failed to save client", err)
+	}
+	return serverPublicKey, nil
 }
 
 // DisableClient отключает клиента.
 func (s *agentService) DisableClient(ctx context.Context, req *proto.DisableClientRequest) (*proto.DisableClientResponse, error) {
-	userID := req.UserId
-	if userID == "" {
-		return &proto.DisableClientResponse{Success: false, Message: "user_id is required"}, nil
+	msg, err := s.disableClient(req.UserId)
+	if err != nil {
+		if statusErrorMode(ctx) {
+			return nil, err
+		}
+		return &proto.DisableClientResponse{Success: false, Message: status.Convert(err).Message()}, nil
 	}
+	return &proto.DisableClientResponse{Success: true, Message: msg}, nil
+}
 
-	client, exists := s.clients.Get(userID)
-	if !exists {
-		return &proto.DisableClientResponse{Success: false, Message: "client not found"}, nil
+func (s *agentService) disableClient(userID string) (string, error) {
+	if userID == "" {
+		return "", userIDRequired()
 	}
 
-	if !client.Enabled {
-		return &proto.DisableClientResponse{Success: true, Message: "already disabled"}, nil
-	}
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+	defer s.users.lock(userID)()
 
-	// Удаляем из WireGuard
-	key, _ := wgtypes.ParseKey(client.PublicKey)
-	cfg := wgtypes.Config{
-		Peers: []wgtypes.PeerConfig{{
-			PublicKey: key,
-			Remove:    true,
-		}},
+	return s.disableLocked(userID)
+}
+
+// disableLocked отключает все устройства клиента. Вызывается под
+// s.syncMu.RLock и блокировкой user_id: из DisableClient и по истечении срока
+func (s *agentService) disableLocked(userID string) (string, error) {
+	devices := s.clients.ListDevices(userID)
+	if len(devices) == 0 {
+		return "", clientNotFound(userID)
 	}
 
-	if err := s.wgClient.ConfigureDevice(s.iface, cfg); err != nil {
-		return &proto.DisableClientResponse{Success: false, Message: err.Error()}, nil
+	// Удаляем из WireGuard все включенные устройства клиента. При ошибке
+	// возвращаем уже убранных пиров, чтобы устройство совпадало с хранилищем
+	var removed []*wireguard.ClientData
+	restore := func() {
+		for _, d := range removed {
+			s.rollback(s.addPeer(d))
+		}
+	}
+	for _, d := range devices {
+		if !d.Enabled {
+			continue
+		}
+		if err := s.removePeer(d.PublicKey); err != nil {
+			restore()
+			return "", s.deviceError("failed to remove peer from WireGuard", err)
+		}
+		removed = append(removed, d)
+	}
+	if len(removed) == 0 {
+		return "already disabled", nil
 	}
 
-	s.clients.SetEnabled(userID, false)
-	s.log.Info("client disabled", "user_id", userID)
+	if err := s.clients.SetEnabled(userID, false); err != nil {
+		restore()
+		return "", storeError("failed to save client", err)
+	}
+	s.syncConfig()
+	s.log.Info("client disabled", "user_id", userID, "devices", len(removed))
 
-	return &proto.DisableClientResponse{Success: true, Message: "disabled"}, nil
+	return "disabled", nil
 }
 
 // EnableClient включает клиента.
 func (s *agentService) EnableClient(ctx context.Context, req *proto.EnableClientRequest) (*proto.EnableClientResponse, error) {
-	userID := req.UserId
-	if userID == "" {
-		return &proto.EnableClientResponse{Success: false, Message: "user_id is required"}, nil
+	msg, err := s.enableClient(req.UserId)
+	if err != nil {
+		if statusErrorMode(ctx) {
+			return nil, err
+		}
+		return &proto.EnableClientResponse{Success: false, Message: status.Convert(err).Message()}, nil
 	}
+	return &proto.EnableClientResponse{Success: true, Message: msg}, nil
+}
 
-	client, exists := s.clients.Get(userID)
-	if !exists {
-		return &proto.EnableClientResponse{Success: false, Message: "client not found"}, nil
+func (s *agentService) enableClient(userID string) (string, error) {
+	if userID == "" {
+		return "", userIDRequired()
 	}
 
-	if client.Enabled {
-		return &proto.EnableClientResponse{Success: true, Message: "already enabled"}, nil
-	}
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+	defer s.users.lock(userID)()
 
-	// Добавляем обратно в WireGuard
-	key, _ := wgtypes.ParseKey(client.PublicKey)
-	_, ipNet, _ := net.ParseCIDR(client.AllowedIP)
-	keepalive := 25 * time.Second
+	return s.enableLocked(userID)
+}
 
-	cfg := wgtypes.Config{
-		Peers: []wgtypes.PeerConfig{{
-			PublicKey:                   key,
-			AllowedIPs:                  []net.IPNet{*ipNet},
-			ReplaceAllowedIPs:           true,
-			PersistentKeepaliveInterval: &keepalive,
-		}},
+// enableLocked включает все устройства клиента. Вызывается под
+// s.syncMu.RLock и блокировкой user_id: из EnableClient и ExtendClient
+func (s *agentService) enableLocked(userID string) (string, error) {
+	devices := s.clients.ListDevices(userID)
+	if len(devices) == 0 {
+		return "", clientNotFound(userID)
 	}
 
-	if err := s.wgClient.ConfigureDevice(s.iface, cfg); err != nil {
-		return &proto.EnableClientResponse{Success: false, Message: err.Error()}, nil
+	// Истёкшего клиента следующая проверка сроков сразу отключила бы снова
+	if primary := devices[0]; primary.DeviceID == "" && primary.Expired(time.Now().Add(-s.expiryGrace)) {
+		return "", statusError(codes.FailedPrecondition, proto.ErrorReason_SUBSCRIPTION_EXPIRED,
+			fmt.Sprintf("subscription of client %s expired at %s, extend it with ExtendClient(enable=true)",
+				userID, primary.ExpiresAt.Format(time.RFC3339)),
+			"user_id", userID, "expires_at", strconv.FormatInt(primary.ExpiresAt.Unix(), 10))
 	}
 
-	s.clients.SetEnabled(userID, true)
-	s.log.Info("client enabled", "user_id", userID)
+	// Добавляем обратно в WireGuard все отключенные устройства клиента
+	var added []*wireguard.ClientData
+	restore := func() {
+		for _, d := range added {
+			s.rollback(s.removePeer(d.PublicKey))
+		}
+	}
+	for _, d := range devices {
+		if d.Enabled {
+			continue
+		}
+		if err := s.addPeer(d); err != nil {
+			restore()
+			return "", s.deviceError("failed to add peer to WireGuard", err)
+		}
+		added = append(added, d)
+	}
+	if len(added) == 0 {
+		return "already enabled", nil
+	}
 
-	return &proto.EnableClientResponse{Success: true, Message: "enabled"}, nil
+	if err := s.clients.SetEnabled(userID, true); err != nil {
+		restore()
+		return "", storeError("failed to save client", err)
+	}
+	s.syncConfig()
+	s.log.Info("client enabled", "user_id", userID, "devices", len(added))
+
+	return "enabled", nil
 }
 
-// DeleteClient полностью удаляет клиента.
+// DeleteClient полностью удаляет клиента со всеми устройствами.
 func (s *agentService) DeleteClient(ctx context.Context, req *proto.DeleteClientRequest) (*emptypb.Empty, error) {
 	userID := req.UserId
 	if userID == "" {
-		return nil, fmt.Errorf("user_id is required")
+		return nil, userIDRequired()
 	}
 
-	client, exists := s.clients.Get(userID)
-	if !exists {
-		return nil, fmt.Errorf("client not found")
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+	defer s.users.lock(userID)()
+
+	devices := s.clients.ListDevices(userID)
+	if len(devices) == 0 {
+		return nil, clientNotFound(userID)
 	}
 
-	// Удаляем из WireGuard (если включен)
-	if client.Enabled {
-		key, _ := wgtypes.ParseKey(client.PublicKey)
-		cfg := wgtypes.Config{
-			Peers: []wgtypes.PeerConfig{{
-				PublicKey: key,
-				Remove:    true,
-			}},
+	// Удаляем из WireGuard включенные устройства
+	for _, d := range devices {
+		if !d.Enabled {
+			continue
 		}
-		if err := s.wgClient.ConfigureDevice(s.iface, cfg); err != nil {
-			s.log.Warn("failed to remove peer from WireGuard", "error", err)
+		if err := s.removePeer(d.PublicKey); err != nil {
+			s.log.Warn("failed to remove peer from WireGuard", "device", d.ID(), "error", err)
 		}
 	}
 
-	s.clients.Delete(userID)
-	s.log.Info("client deleted", "user_id", userID)
+	if err := s.clients.Delete(userID); err != nil {
+		return nil, storeError("failed to delete client", err)
+	}
+	s.syncConfig()
+	s.log.Info("client deleted", "user_id", userID, "devices", len(devices))
 
 	return &emptypb.Empty{}, nil
 }
@@ -240,18 +474,27 @@ func (s *agentService) DeleteClient(ctx context.Context, req *proto.DeleteClient
 func (s *agentService) GetClient(ctx context.Context, req *proto.GetClientRequest) (*proto.GetClientResponse, error) {
 	userID := req.UserId
 	if userID == "" {
-		return nil, fmt.Errorf("user_id is required")
+		return nil, userIDRequired()
 	}
 
 	client, exists := s.clients.Get(userID)
 	if !exists {
-		return nil, fmt.Errorf("client not found")
+		return nil, clientNotFound(userID)
 	}
 
 	resp := &proto.GetClientResponse{
 		UserId:   client.UserID,
 		ClientIp: client.AllowedIP,
 		Enabled:  client.Enabled,
+		KeyMode:  client.KeyMode(),
+
+		HasPresharedKey: client.PresharedKey != "",
+		ClientIps:       client.AllowedIPs(),
+		Pool:            s.clientPool(client),
+		TunnelName:      s.tunnelName(client),
+		Labels:          client.Labels,
+		DeviceCount:     int32(len(s.clients.ListDevices(userID))),
+		ExpiresAt:       expiresAtUnix(client),
 	}
 
 	// Получаем статистику из WireGuard если клиент включен
@@ -272,9 +515,47 @@ func (s *agentService) GetClient(ctx context.Context, req *proto.GetClientReques
 	return resp, nil
 }
 
-// ListClients возвращает список всех клиентов.
+// GetClientConfig повторно собирает конфиг клиента из хранилища.
+func (s *agentService) GetClientConfig(ctx context.Context, req *proto.GetClientConfigRequest) (*proto.ClientConfigResponse, error) {
+	userID := req.UserId
+	if userID == "" {
+		return nil, userIDRequired()
+	}
+	render, err := parseRenderOptions(req.QrOptions, req.OutputFormats, req.DeepLinkOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.getDevice(userID, req.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+	// Без приватного ключа на сервере готовый конфиг собрать нельзя
+	if client.KeyMode() != wireguard.KeyModeServer {
+		return nil, statusError(codes.FailedPrecondition, proto.ErrorReason_PRIVATE_KEY_UNAVAILABLE,
+			fmt.Sprintf("client %s uses its own private key, config is not available", client.ID()), "user_id", userID)
+	}
+
+	// Ключ сервера и endpoint берём текущие
+	device, err := s.wgClient.Device(s.iface)
+	if err != nil {
+		return nil, s.deviceError("failed to get WireGuard device", err)
+	}
+
+	artifacts, err := s.renderClient(client, device.PublicKey.String(), render)
+	if err != nil {
+		return nil, err
+	}
+	return clientConfigResponse(client, artifacts), nil
+}
+
+// ListClients возвращает список всех клиентов (их основные устройства).
 func (s *agentService) ListClients(ctx context.Context, req *proto.ListClientsRequest) (*proto.ListClientsResponse, error) {
 	clients := s.clients.List()
+	deviceCount := make(map[string]int32)
+	for _, c := range clients {
+		deviceCount[c.UserID]++
+	}
 
 	// Получаем статистику из WireGuard
 	var device *wgtypes.Device
@@ -289,10 +570,21 @@ func (s *agentService) ListClients(ctx context.Context, req *proto.ListClientsRe
 
 	result := make([]*proto.ClientInfo, 0, len(clients))
 	for _, c := range clients {
+		if c.DeviceID != "" || !c.HasLabels(req.Labels) {
+			continue
+		}
 		info := &proto.ClientInfo{
 			UserId:   c.UserID,
 			ClientIp: c.AllowedIP,
 			Enabled:  c.Enabled,
+			KeyMode:  c.KeyMode(),
+
+			ClientIps:   c.AllowedIPs(),
+			Pool:        s.clientPool(c),
+			TunnelName:  s.tunnelName(c),
+			Labels:      c.Labels,
+			DeviceCount: deviceCount[c.UserID],
+			ExpiresAt:   expiresAtUnix(c),
 		}
 
 		if peer, ok := peerStats[c.PublicKey]; ok {
@@ -304,3 +596,293 @@ func (s *agentService) ListClients(ctx context.Context, req *proto.ListClientsRe
 
 	return &proto.ListClientsResponse{Clients: result}, nil
 }
+
+// clientKeys генерирует пару ключей клиента или проверяет присланный им
+// публичный ключ. Приватный ключ возвращается зашифрованным для хранилища
+// (пустой, если ключ прислал клиент).
+func (s *agentService) clientKeys(requestedPublicKey string) (storedKey, publicKey string, err error) {
+	var privateKey string
+	if requestedPublicKey != "" {
+		if err := wireguard.ValidatePublicKey(requestedPublicKey); err != nil {
+			return "", "", invalidField("public_key", err)
+		}
+		if owner, exists := s.clients.GetByPublicKey(requestedPublicKey); exists {
+			return "", "", statusError(codes.AlreadyExists, proto.ErrorReason_PUBLIC_KEY_IN_USE,
+				fmt.Sprintf("public_key already used by client %s", owner.UserID), "user_id", owner.UserID)
+		}
+		publicKey = requestedPublicKey
+	} else {
+		privateKey, publicKey, err = wireguard.GenerateKeyPair()
+		if err != nil {
+			return "", "", internalError(fmt.Errorf("failed to generate keys: %w", err))
+		}
+	}
+
+	// В хранилище попадает только зашифрованный приватный ключ
+	storedKey, err = s.sealer.Seal(privateKey)
+	if err != nil {
+		return "", "", internalError(fmt.Errorf("failed to seal private key: %w", err))
+	}
+	return storedKey, publicKey, nil
+}
+
+// peerConfig строит конфигурацию пира WireGuard для клиента
+func (s *agentService) peerConfig(client *wireguard.ClientData) (wgtypes.PeerConfig, error) {
+	key, err := wgtypes.ParseKey(client.PublicKey)
+	if err != nil {
+		return wgtypes.PeerConfig{}, internalError(fmt.Errorf("invalid public key: %w", err))
+	}
+	var allowedIPs []net.IPNet
+	for _, cidr := range client.AllowedIPs() {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return wgtypes.PeerConfig{}, internalError(fmt.Errorf("invalid allowed IP: %w", err))
+		}
+		allowedIPs = append(allowedIPs, *ipNet)
+	}
+	keepalive := 25 * time.Second
+
+	peer := wgtypes.PeerConfig{
+		PublicKey:                   key,
+		AllowedIPs:                  allowedIPs,
+		ReplaceAllowedIPs:           true,
+		PersistentKeepaliveInterval: &keepalive,
+	}
+
+	// Нулевой ключ убирает preshared key с пира, если у клиента его нет
+	peer.PresharedKey = &wgtypes.Key{}
+	if client.PresharedKey != "" {
+		psk, err := s.sealer.Open(client.PresharedKey)
+		if err != nil {
+			return wgtypes.PeerConfig{}, internalError(fmt.Errorf("failed to open preshared key: %w", err))
+		}
+		pskKey, err := wgtypes.ParseKey(psk)
+		if err != nil {
+			return wgtypes.PeerConfig{}, internalError(fmt.Errorf("invalid preshared key: %w", err))
+		}
+		peer.PresharedKey = &pskKey
+	}
+	return peer, nil
+}
+
+// addPeer добавляет (или обновляет) пира клиента на устройстве
+func (s *agentService) addPeer(client *wireguard.ClientData) error {
+	peer, err := s.peerConfig(client)
+	if err != nil {
+		return err
+	}
+	return s.wgClient.ConfigureDevice(s.iface, wgtypes.Config{Peers: []wgtypes.PeerConfig{peer}})
+}
+
+// restorePeers заново добавляет на устройство включенных клиентов, которым
+// принадлежат адреса ips. Нужен после отката пира, который перехватил их
+// AllowedIPs: WireGuard переносит адрес на последнего добавленного пира
+// и не возвращает его прежнему при удалении.
+func (s *agentService) restorePeers(ips []string) {
+	for _, ip := range ips {
+		if owner, ok := s.clients.GetByIP(ip); ok && owner.Enabled {
+			s.rollback(s.addPeer(owner))
+		}
+	}
+}
+
+// removePeer убирает пира с устройства по публичному ключу
+func (s *agentService) removePeer(publicKey string) error {
+	key, err := wgtypes.ParseKey(publicKey)
+	if err != nil {
+		return internalError(fmt.Errorf("invalid public key: %w", err))
+	}
+	cfg := wgtypes.Config{
+		Peers: []wgtypes.PeerConfig{{
+			PublicKey: key,
+			Remove:    true,
+		}},
+	}
+	return s.wgClient.ConfigureDevice(s.iface, cfg)
+}
+
+// tunnelName имя туннеля клиента, по умолчанию - имя интерфейса сервера
+func (s *agentService) tunnelName(client *wireguard.ClientData) string {
+	if client.TunnelName != "" {
+		return client.TunnelName
+	}
+	return s.iface
+}
+
+// clientArtifacts конфиг клиента и производные от него QR код и deep link
+type clientArtifacts struct {
+	ConfigFile string
+	Filename   string // предлагаемое имя файла config_file
+	QRCode     string
+	DeepLink   string
+	Formats    []*proto.ConfigArtifact // конфиг в запрошенных форматах
+}
+
+// renderOptions что, помимо конфига wg-quick, вернуть клиенту
+type renderOptions struct {
+	qr      wireguard.QROptions
+	link    wireguard.DeepLinkOptions
+	formats []wireguard.ConfigFormat
+}
+
+// parseRenderOptions собирает параметры QR кода, deep link и список форматов из запроса
+func parseRenderOptions(qr *proto.QrOptions, formats []string, link *proto.DeepLinkOptions) (renderOptions, error) {
+	opts, err := qrOptions(qr)
+	if err != nil {
+		return renderOptions{}, err
+	}
+	render := renderOptions{qr: opts, link: wireguard.DefaultDeepLinkOptions()}
+	if link.GetScheme() != "" {
+		render.link.Scheme = wireguard.DeepLinkScheme(link.GetScheme())
+	}
+	render.link.Compress = link.GetCompress()
+	if err := render.link.Validate(); err != nil {
+		return renderOptions{}, invalidField("deep_link_options", err)
+	}
+	known := wireguard.ConfigFormats()
+	for _, name := range formats {
+		format := wireguard.ConfigFormat(name)
+		if !slices.Contains(known, format) {
+			return renderOptions{}, invalidArgument("output_formats", fmt.Sprintf("unknown output format %q (expected one of %v)", name, known))
+		}
+		if !slices.Contains(render.formats, format) {
+			render.formats = append(render.formats, format)
+		}
+	}
+	return render, nil
+}
+
+// renderClient собирает конфиг клиента. Секреты расшифровываются только здесь.
+// Без приватного ключа на сервере возвращается шаблон, куда клиент
+// подставит свой ключ, а QR код и deep link не формируются.
+func (s *agentService) renderClient(client *wireguard.ClientData, serverPublicKey string, render renderOptions) (*clientArtifacts, error) {
+	privateKey := wireguard.PrivateKeyPlaceholder
+	if client.KeyMode() == wireguard.KeyModeServer {
+		var err error
+		if privateKey, err = s.sealer.Open(client.PrivateKey); err != nil {
+			return nil, internalError(fmt.Errorf("failed to open private key: %w", err))
+		}
+	}
+	presharedKey, err := s.sealer.Open(client.PresharedKey)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("failed to open preshared key: %w", err))
+	}
+
+	template := s.clientTemplate(client)
+	params := wireguard.ClientConfigParams{
+		Name:                s.tunnelName(client),
+		ID:                  client.ID(),
+		PrivateKey:          privateKey,
+		Address:             strings.Join(client.AllowedIPs(), ", "),
+		DNS:                 strings.Join(template.DNS, ", "),
+		MTU:                 template.MTU,
+		PostUp:              template.PostUp,
+		ServerPublicKey:     serverPublicKey,
+		PresharedKey:        presharedKey,
+		AllowedIPs:          strings.Join(template.AllowedIPs, ", "),
+		Endpoint:            s.serverEndpoint,
+		PersistentKeepalive: template.PersistentKeepalive,
+	}
+	artifacts := &clientArtifacts{
+		ConfigFile: wireguard.GenerateClientConfig(params),
+		Filename:   params.Name + ".conf",
+	}
+
+	// Без приватного ключа форматы, как и config_file, остаются шаблонами
+	for _, format := range render.formats {
+		a, err := wireguard.RenderClientConfig(format, params)
+		if err != nil {
+			return nil, internalError(err)
+		}
+		artifacts.Formats = append(artifacts.Formats, &proto.ConfigArtifact{
+			Format:      string(a.Format),
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Content:     a.Content,
+		})
+	}
+
+	// QR код и deep link имеют смысл только для готового конфига
+	if client.KeyMode() == wireguard.KeyModeServer {
+		artifacts.QRCode, err = wireguard.GenerateQRCode(artifacts.ConfigFile, render.qr)
+		if err != nil {
+			s.log.Warn("failed to generate QR code", "error", err)
+			artifacts.QRCode = ""
+		}
+
+		// Генерируем deep link для автоимпорта
+		if artifacts.DeepLink, err = wireguard.BuildDeepLink(params, render.link); err != nil {
+			return nil, internalError(err)
+		}
+	}
+	return artifacts, nil
+}
+
+// qrOptions собирает параметры QR кода из запроса, незаданные поля берутся по умолчанию
+func qrOptions(req *proto.QrOptions) (wireguard.QROptions, error) {
+	opts := wireguard.DefaultQROptions()
+	if req.GetFormat() != "" {
+		opts.Format = wireguard.QRFormat(req.GetFormat())
+	}
+	if req.GetModuleSize() != 0 {
+		opts.ModuleSize = int(req.GetModuleSize())
+	}
+	if req.GetErrorCorrection() != "" {
+		opts.ErrorCorrection = wireguard.QRErrorCorrection(req.GetErrorCorrection())
+	}
+	if req != nil && req.QuietZone != nil {
+		opts.QuietZone = int(req.GetQuietZone())
+	}
+	if err := opts.Validate(); err != nil {
+		return wireguard.QROptions{}, invalidField("qr_options", err)
+	}
+	return opts, nil
+}
+
+// createClientResponse ответ CreateClient, result - что сделал агент
+func createClientResponse(client *wireguard.ClientData, artifacts *clientArtifacts, result string) *proto.CreateClientResponse {
+	return &proto.CreateClientResponse{
+		ConfigFile:      artifacts.ConfigFile,
+		QrCodeBase64:    artifacts.QRCode,
+		DeepLink:        artifacts.DeepLink,
+		ClientIp:        client.AllowedIP,
+		KeyMode:         client.KeyMode(),
+		HasPresharedKey: client.PresharedKey != "",
+		ClientIps:       client.AllowedIPs(),
+		Artifacts:       artifacts.Formats,
+		ConfigFilename:  artifacts.Filename,
+		Result:          result,
+	}
+}
+
+// clientConfigResponse ответ с конфигом клиента
+func clientConfigResponse(client *wireguard.ClientData, artifacts *clientArtifacts) *proto.ClientConfigResponse {
+	return &proto.ClientConfigResponse{
+		ConfigFile:      artifacts.ConfigFile,
+		QrCodeBase64:    artifacts.QRCode,
+		DeepLink:        artifacts.DeepLink,
+		ClientIp:        client.AllowedIP,
+		KeyMode:         client.KeyMode(),
+		HasPresharedKey: client.PresharedKey != "",
+		ClientIps:       client.AllowedIPs(),
+		Artifacts:       artifacts.Formats,
+		ConfigFilename:  artifacts.Filename,
+	}
+}
+
+// syncConfig переписывает конфиг wg-quick, если включена синхронизация
+func (s *agentService) syncConfig() {
+	if s.configSync == nil {
+		return
+	}
+	if err := s.configSync.Sync(); err != nil {
+		s.log.Error("failed to sync wg-quick config", "path", s.wgConfigPath, "error", err)
+	}
+}
+
+// rollback логирует ошибку отката, устройство поправит следующая сверка
+func (s *agentService) rollback(err error) {
+	if err != nil {
+		s.log.Error("failed to roll back peer change", "error", err)
+	}
+}
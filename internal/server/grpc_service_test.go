@@ -0,0 +1,569 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quibex/wg-agent/internal/config"
+	"github.com/quibex/wg-agent/internal/wireguard"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func TestCreateClient_ClientProvidedKey(t *testing.T) {
+	s, wg := newTestService(t)
+	ctx := context.Background()
+	publicKey := mustKey(t)
+
+	resp, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", PublicKey: publicKey})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	if resp.KeyMode != wireguard.KeyModeClient {
+		t.Errorf("KeyMode = %q, want %q", resp.KeyMode, wireguard.KeyModeClient)
+	}
+	if !strings.Contains(resp.ConfigFile, "PrivateKey = "+wireguard.PrivateKeyPlaceholder) {
+		t.Errorf("config should contain private key placeholder:\n%s", resp.ConfigFile)
+	}
+	if resp.QrCodeBase64 != "" || resp.DeepLink != "" {
+		t.Error("QR code and deep link should be empty for client-provided keys")
+	}
+
+	stored, _ := s.clients.Get("alice")
+	if stored.PrivateKey != "" || stored.PublicKey != publicKey {
+		t.Errorf("stored client = %+v, want no private key", stored)
+	}
+	if !devicePeers(t, wg)[publicKey] {
+		t.Error("peer with client-provided key should be added to device")
+	}
+
+	got, err := s.GetClient(ctx, &proto.GetClientRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("GetClient() error = %v", err)
+	}
+	if got.KeyMode != wireguard.KeyModeClient {
+		t.Errorf("GetClient KeyMode = %q", got.KeyMode)
+	}
+
+	// Повторное использование ключа и мусор отклоняются
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob", PublicKey: publicKey}); err == nil {
+		t.Error("CreateClient() with reused public key expected error")
+	}
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob", PublicKey: "garbage"}); err == nil {
+		t.Error("CreateClient() with invalid public key expected error")
+	}
+}
+
+func TestCreateClient_ServerGeneratedKey(t *testing.T) {
+	s, _ := newTestService(t)
+
+	resp, err := s.CreateClient(context.Background(), &proto.CreateClientRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	if resp.KeyMode != wireguard.KeyModeServer {
+		t.Errorf("KeyMode = %q, want %q", resp.KeyMode, wireguard.KeyModeServer)
+	}
+	if strings.Contains(resp.ConfigFile, wireguard.PrivateKeyPlaceholder) || resp.DeepLink == "" {
+		t.Errorf("expected a complete config with deep link, got:\n%s", resp.ConfigFile)
+	}
+	if resp.QrCodeBase64 == "" {
+		t.Error("expected QR code")
+	}
+
+	svg, err := s.CreateClient(context.Background(), &proto.CreateClientRequest{
+		UserId:    "bob",
+		QrOptions: &proto.QrOptions{Format: "svg"},
+	})
+	if err != nil {
+		t.Fatalf("CreateClient(svg) error = %v", err)
+	}
+	if data, _ := base64.StdEncoding.DecodeString(svg.QrCodeBase64); !strings.HasPrefix(string(data), "<svg") {
+		t.Errorf("expected SVG QR code, got %q", svg.QrCodeBase64)
+	}
+
+	if _, err := s.CreateClient(context.Background(), &proto.CreateClientRequest{
+		UserId:    "carol",
+		QrOptions: &proto.QrOptions{Format: "gif"},
+	}); err == nil {
+		t.Error("CreateClient() with invalid qr_options expected error")
+	}
+	if s.clients.Exists("carol") {
+		t.Error("client must not be created with invalid qr_options")
+	}
+}
+
+func TestPresharedKeyPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    PresharedKeyPolicy
+		requested bool
+		wantPSK   bool
+		wantErr   bool
+	}{
+		{name: "off", policy: PSKOff},
+		{name: "off but requested", policy: PSKOff, requested: true, wantErr: true},
+		{name: "always", policy: PSKAlways, wantPSK: true},
+		{name: "per-request without request", policy: PSKPerRequest},
+		{name: "per-request with request", policy: PSKPerRequest, requested: true, wantPSK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, wg := newTestService(t, func(cfg *config.Config) {
+				cfg.PSKPolicy = string(tt.policy)
+			})
+
+			resp, err := s.CreateClient(context.Background(), &proto.CreateClientRequest{UserId: "alice", PresharedKey: tt.requested})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if resp.HasPresharedKey != tt.wantPSK {
+				t.Errorf("HasPresharedKey = %v, want %v", resp.HasPresharedKey, tt.wantPSK)
+			}
+			if got := strings.Contains(resp.ConfigFile, "PresharedKey = "); got != tt.wantPSK {
+				t.Errorf("config has PresharedKey = %v, want %v:\n%s", got, tt.wantPSK, resp.ConfigFile)
+			}
+
+			device, _ := wg.Device("wg0")
+			if got := device.Peers[0].PresharedKey != (wgtypes.Key{}); got != tt.wantPSK {
+				t.Errorf("device peer has preshared key = %v, want %v", got, tt.wantPSK)
+			}
+		})
+	}
+}
+
+func TestAddPresharedKey(t *testing.T) {
+	s, wg := newTestService(t, func(cfg *config.Config) {
+		cfg.PSKPolicy = string(PSKPerRequest)
+	})
+	ctx := context.Background()
+
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"}); err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+
+	resp, err := s.AddPresharedKey(ctx, &proto.AddPresharedKeyRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("AddPresharedKey() error = %v", err)
+	}
+	if !resp.HasPresharedKey || !strings.Contains(resp.ConfigFile, "PresharedKey = ") {
+		t.Errorf("expected config with preshared key:\n%s", resp.ConfigFile)
+	}
+
+	device, _ := wg.Device("wg0")
+	if device.Peers[0].PresharedKey == (wgtypes.Key{}) {
+		t.Error("preshared key should be configured on device")
+	}
+
+	if _, err := s.AddPresharedKey(ctx, &proto.AddPresharedKeyRequest{UserId: "nobody"}); err == nil {
+		t.Error("AddPresharedKey() for unknown client expected error")
+	}
+}
+
+func TestRotateClientKeys(t *testing.T) {
+	s, wg := newTestService(t, func(cfg *config.Config) {
+		cfg.PSKPolicy = string(PSKAlways)
+	})
+	ctx := context.Background()
+
+	created, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	before, _ := s.clients.Get("alice")
+
+	resp, err := s.RotateClientKeys(ctx, &proto.RotateClientKeysRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("RotateClientKeys() error = %v", err)
+	}
+	after, _ := s.clients.Get("alice")
+
+	if resp.ClientIp != created.ClientIp || after.AllowedIP != before.AllowedIP {
+		t.Errorf("client IP changed: %s -> %s", created.ClientIp, resp.ClientIp)
+	}
+	if after.PublicKey == before.PublicKey || after.PrivateKey == before.PrivateKey {
+		t.Error("keys were not rotated")
+	}
+	if after.PresharedKey == before.PresharedKey || !resp.HasPresharedKey {
+		t.Error("preshared key was not rotated")
+	}
+	if resp.ConfigFile == created.ConfigFile {
+		t.Error("expected fresh config")
+	}
+
+	peers := devicePeers(t, wg)
+	if len(peers) != 1 || !peers[after.PublicKey] {
+		t.Errorf("device peers = %v, want only %s", peers, after.PublicKey)
+	}
+
+	// Клиент может перейти на свой ключ
+	clientKey := mustKey(t)
+	resp, err = s.RotateClientKeys(ctx, &proto.RotateClientKeysRequest{UserId: "alice", PublicKey: clientKey})
+	if err != nil {
+		t.Fatalf("RotateClientKeys(public_key) error = %v", err)
+	}
+	if resp.KeyMode != wireguard.KeyModeClient {
+		t.Errorf("KeyMode = %s, want %s", resp.KeyMode, wireguard.KeyModeClient)
+	}
+	if peers := devicePeers(t, wg); len(peers) != 1 || !peers[clientKey] {
+		t.Errorf("device peers = %v, want only %s", peers, clientKey)
+	}
+
+	if _, err := s.RotateClientKeys(ctx, &proto.RotateClientKeysRequest{UserId: "nobody"}); err == nil {
+		t.Error("RotateClientKeys() for unknown client expected error")
+	}
+}
+
+func TestGetClientConfig(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	created, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	resp, err := s.GetClientConfig(ctx, &proto.GetClientConfigRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("GetClientConfig() error = %v", err)
+	}
+	if resp.ConfigFile != created.ConfigFile || resp.DeepLink != created.DeepLink || resp.ClientIp != created.ClientIp {
+		t.Errorf("GetClientConfig() = %+v, want same config as CreateClient", resp)
+	}
+
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob", PublicKey: mustKey(t)}); err != nil {
+		t.Fatalf("CreateClient(public_key) error = %v", err)
+	}
+	if _, err := s.GetClientConfig(ctx, &proto.GetClientConfigRequest{UserId: "bob"}); err == nil {
+		t.Error("GetClientConfig() for client-held key expected error")
+	}
+	if _, err := s.GetClientConfig(ctx, &proto.GetClientConfigRequest{UserId: "nobody"}); err == nil {
+		t.Error("GetClientConfig() for unknown client expected error")
+	}
+}
+
+func TestCreateClient_DualStack(t *testing.T) {
+	s, wg := newTestService(t, func(cfg *config.Config) {
+		cfg.Subnet6 = "fd42:42:42::/64"
+	})
+
+	resp, err := s.CreateClient(context.Background(), &proto.CreateClientRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+
+	wantIPs := []string{"10.8.0.2/32", "fd42:42:42::2/128"}
+	if strings.Join(resp.ClientIps, ",") != strings.Join(wantIPs, ",") {
+		t.Errorf("ClientIps = %v, want %v", resp.ClientIps, wantIPs)
+	}
+	for _, want := range []string{
+		"Address = 10.8.0.2/32, fd42:42:42::2/128\n",
+		"AllowedIPs = 0.0.0.0/0, ::/0\n",
+	} {
+		if !strings.Contains(resp.ConfigFile, want) {
+			t.Errorf("config missing %q:\n%s", want, resp.ConfigFile)
+		}
+	}
+
+	device, _ := wg.Device("wg0")
+	if got := len(device.Peers[0].AllowedIPs); got != 2 {
+		t.Errorf("device peer has %d allowed IPs, want 2", got)
+	}
+}
+
+func TestCreateClient_ConfigOptions(t *testing.T) {
+	s, _ := newTestService(t, func(cfg *config.Config) {
+		cfg.ClientMTU = 1420
+	})
+	ctx := context.Background()
+
+	keepalive := uint32(0)
+	resp, err := s.CreateClient(ctx, &proto.CreateClientRequest{
+		UserId: "alice",
+		ConfigOptions: &proto.ClientConfigOptions{
+			Dns:                 []string{"9.9.9.9"},
+			AllowedIps:          []string{"10.0.0.0/8", "192.168.0.0/16"},
+			PersistentKeepalive: &keepalive,
+			PostUp:              []string{"echo connected"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	for _, want := range []string{
+		"DNS = 9.9.9.9\n",
+		"MTU = 1420\n",
+		"PostUp = echo connected\n",
+		"AllowedIPs = 10.0.0.0/8, 192.168.0.0/16\n",
+	} {
+		if !strings.Contains(resp.ConfigFile, want) {
+			t.Errorf("config missing %q:\n%s", want, resp.ConfigFile)
+		}
+	}
+	if strings.Contains(resp.ConfigFile, "PersistentKeepalive") {
+		t.Errorf("keepalive should be disabled:\n%s", resp.ConfigFile)
+	}
+
+	// Смена настроек сервера не меняет конфиг уже созданного клиента
+	s.template.DNS = []string{"8.8.8.8"}
+	again, err := s.GetClientConfig(ctx, &proto.GetClientConfigRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("GetClientConfig() error = %v", err)
+	}
+	if again.ConfigFile != resp.ConfigFile {
+		t.Errorf("re-rendered config differs:\n%s\nwant\n%s", again.ConfigFile, resp.ConfigFile)
+	}
+
+	for _, opts := range []*proto.ClientConfigOptions{
+		{AllowedIps: []string{"10.0.0.0/33"}},
+		{Dns: []string{"not-an-ip"}},
+		{Mtu: 70000},
+	} {
+		if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob", ConfigOptions: opts}); err == nil {
+			t.Errorf("CreateClient(%v) expected error", opts)
+		}
+	}
+}
+
+func TestCreateClient_SplitTunnel(t *testing.T) {
+	domestic := filepath.Join(t.TempDir(), "domestic.txt")
+	if err := os.WriteFile(domestic, []byte("# domestic ranges\n0.0.0.0/1\n128.0.0.0/2 # comment\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := newTestService(t, func(cfg *config.Config) {
+		cfg.Subnet6 = "fd42:42:42::/64"
+		cfg.SplitTunnels = "lan=192.168.0.0/17,fc00::/7; domestic=@" + domestic
+	})
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		preset string
+		want   string
+	}{
+		{"lan", "AllowedIPs = 0.0.0.0/1, 128.0.0.0/2, 192.0.0.0/9, 192.128.0.0/11, 192.160.0.0/13, " +
+			"192.168.128.0/17, 192.169.0.0/16, 192.170.0.0/15, 192.172.0.0/14, 192.176.0.0/12, 192.192.0.0/10, " +
+			"193.0.0.0/8, 194.0.0.0/7, 196.0.0.0/6, 200.0.0.0/5, 208.0.0.0/4, 224.0.0.0/3, " +
+			"::/1, 8000::/2, c000::/3, e000::/4, f000::/5, f800::/6, fe00::/7\n"},
+		{"domestic", "AllowedIPs = 192.0.0.0/2, ::/0\n"},
+	} {
+		resp, err := s.CreateClient(ctx, &proto.CreateClientRequest{
+			UserId:        tt.preset,
+			ConfigOptions: &proto.ClientConfigOptions{SplitTunnel: tt.preset},
+		})
+		if err != nil {
+			t.Fatalf("CreateClient(%s) error = %v", tt.preset, err)
+		}
+		if !strings.Contains(resp.ConfigFile, tt.want) {
+			t.Errorf("%s config missing %q:\n%s", tt.preset, tt.want, resp.ConfigFile)
+		}
+	}
+
+	for _, opts := range []*proto.ClientConfigOptions{
+		{SplitTunnel: "unknown"},
+		{SplitTunnel: "lan", AllowedIps: []string{"10.0.0.0/8"}},
+	} {
+		if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob", ConfigOptions: opts}); err == nil {
+			t.Errorf("CreateClient(%v) expected error", opts)
+		}
+	}
+}
+
+func TestCreateClient_OutputFormats(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	resp, err := s.CreateClient(ctx, &proto.CreateClientRequest{
+		UserId:        "alice",
+		OutputFormats: []string{"mobileconfig", "json", "mobileconfig"},
+	})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	if len(resp.Artifacts) != 2 || resp.Artifacts[0].Format != "mobileconfig" || resp.Artifacts[1].Format != "json" {
+		t.Fatalf("Artifacts = %v, want mobileconfig and json", resp.Artifacts)
+	}
+	if resp.Artifacts[0].Filename != "wg0.mobileconfig" || len(resp.Artifacts[0].Content) == 0 {
+		t.Errorf("mobileconfig artifact = %s, %d bytes", resp.Artifacts[0].Filename, len(resp.Artifacts[0].Content))
+	}
+
+	again, err := s.GetClientConfig(ctx, &proto.GetClientConfigRequest{UserId: "alice", OutputFormats: []string{"json"}})
+	if err != nil {
+		t.Fatalf("GetClientConfig() error = %v", err)
+	}
+	if len(again.Artifacts) != 1 || string(again.Artifacts[0].Content) != string(resp.Artifacts[1].Content) {
+		t.Errorf("GetClientConfig() artifacts differ from CreateClient")
+	}
+
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob", OutputFormats: []string{"pdf"}}); err == nil {
+		t.Error("CreateClient(unknown format) expected error")
+	}
+}
+
+func TestCreateClient_DeepLinkOptions(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	resp, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	if want := wireguard.GenerateWireGuardLink(resp.ConfigFile); resp.DeepLink != want {
+		t.Errorf("default DeepLink = %s, want v1 link %s", resp.DeepLink, want)
+	}
+
+	again, err := s.GetClientConfig(ctx, &proto.GetClientConfigRequest{
+		UserId:          "alice",
+		DeepLinkOptions: &proto.DeepLinkOptions{Scheme: "amnezia"},
+	})
+	if err != nil {
+		t.Fatalf("GetClientConfig(amnezia) error = %v", err)
+	}
+	if !strings.HasPrefix(again.DeepLink, "vpn://") {
+		t.Errorf("DeepLink = %s, want vpn:// link", again.DeepLink)
+	}
+
+	_, err = s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob", DeepLinkOptions: &proto.DeepLinkOptions{Scheme: "tg"}})
+	if err == nil {
+		t.Error("CreateClient(unknown deep link scheme) expected error")
+	}
+}
+
+func TestCreateClient_TunnelNameAndLabels(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	resp, err := s.CreateClient(ctx, &proto.CreateClientRequest{
+		UserId:          "alice",
+		TunnelName:      "acme-nl",
+		Labels:          map[string]string{"plan": "paid", "region": "eu"},
+		OutputFormats:   []string{"mobileconfig"},
+		DeepLinkOptions: &proto.DeepLinkOptions{Scheme: "wireguard"},
+	})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	if resp.ConfigFilename != "acme-nl.conf" || resp.Artifacts[0].Filename != "acme-nl.mobileconfig" {
+		t.Errorf("filenames = %s, %s", resp.ConfigFilename, resp.Artifacts[0].Filename)
+	}
+	if !strings.Contains(string(resp.Artifacts[0].Content), "<string>acme-nl</string>") {
+		t.Errorf("mobileconfig missing tunnel name:\n%s", resp.Artifacts[0].Content)
+	}
+	if !strings.Contains(resp.DeepLink, "name=acme-nl") {
+		t.Errorf("DeepLink = %s, want name=acme-nl", resp.DeepLink)
+	}
+
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob", Labels: map[string]string{"plan": "free"}}); err != nil {
+		t.Fatalf("CreateClient(bob) error = %v", err)
+	}
+
+	got, _ := s.GetClient(ctx, &proto.GetClientRequest{UserId: "alice"})
+	if got.TunnelName != "acme-nl" || got.Labels["region"] != "eu" {
+		t.Errorf("GetClient() tunnel_name = %q, labels = %v", got.TunnelName, got.Labels)
+	}
+	if got, _ := s.GetClient(ctx, &proto.GetClientRequest{UserId: "bob"}); got.TunnelName != "wg0" {
+		t.Errorf("default tunnel_name = %q, want wg0", got.TunnelName)
+	}
+
+	for _, tt := range []struct {
+		labels map[string]string
+		want   int
+	}{
+		{nil, 2},
+		{map[string]string{"plan": "paid"}, 1},
+		{map[string]string{"plan": "paid", "region": "us"}, 0},
+	} {
+		list, _ := s.ListClients(ctx, &proto.ListClientsRequest{Labels: tt.labels})
+		if len(list.Clients) != tt.want {
+			t.Errorf("ListClients(%v) = %d clients, want %d", tt.labels, len(list.Clients), tt.want)
+		}
+	}
+
+	for _, req := range []*proto.CreateClientRequest{
+		{UserId: "carol", TunnelName: "name with spaces"},
+		{UserId: "carol", TunnelName: "much-too-long-tunnel"},
+		{UserId: "carol", Labels: map[string]string{"bad key": "x"}},
+	} {
+		if _, err := s.CreateClient(ctx, req); err == nil {
+			t.Errorf("CreateClient(%v) expected error", req)
+		}
+	}
+}
+
+// newSyncedService создает agentService с синхронизацией wg0.conf во временный файл
+func newSyncedService(t *testing.T) (*agentService, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "wg0.conf")
+	if err := os.WriteFile(path, []byte("[Interface]\nListenPort = 51820\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	s, _ := newTestService(t, func(cfg *config.Config) {
+		cfg.WGConfigPath = path
+		cfg.WGConfigSync = true
+	})
+	return s, path
+}
+
+func TestConfigSync_DeleteAndRotate(t *testing.T) {
+	ctx := context.Background()
+	readConfig := func(t *testing.T, path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		return string(data)
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		s, path := newSyncedService(t)
+		if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"}); err != nil {
+			t.Fatalf("CreateClient() error = %v", err)
+		}
+		if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob"}); err != nil {
+			t.Fatalf("CreateClient() error = %v", err)
+		}
+		if !strings.Contains(readConfig(t, path), "# user_id = alice") {
+			t.Fatal("created client missing from wg0.conf")
+		}
+
+		if _, err := s.DeleteClient(ctx, &proto.DeleteClientRequest{UserId: "alice"}); err != nil {
+			t.Fatalf("DeleteClient() error = %v", err)
+		}
+		got := readConfig(t, path)
+		if strings.Contains(got, "alice") || !strings.Contains(got, "# user_id = bob") {
+			t.Errorf("wg0.conf after DeleteClient:\n%s", got)
+		}
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		s, path := newSyncedService(t)
+		if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"}); err != nil {
+			t.Fatalf("CreateClient() error = %v", err)
+		}
+		old, _ := s.clients.Get("alice")
+
+		if _, err := s.RotateClientKeys(ctx, &proto.RotateClientKeysRequest{UserId: "alice"}); err != nil {
+			t.Fatalf("RotateClientKeys() error = %v", err)
+		}
+		if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", IfExists: "rotate"}); err != nil {
+			t.Fatalf("CreateClient(if_exists=rotate) error = %v", err)
+		}
+		current, _ := s.clients.Get("alice")
+
+		got := readConfig(t, path)
+		if n := strings.Count(got, "[Peer]"); n != 1 {
+			t.Errorf("wg0.conf has %d peers, want 1:\n%s", n, got)
+		}
+		if strings.Contains(got, old.PublicKey) || !strings.Contains(got, current.PublicKey) {
+			t.Errorf("wg0.conf should have only the current key:\n%s", got)
+		}
+	})
+}
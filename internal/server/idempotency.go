@@ -0,0 +1,122 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/quibex/wg-agent/internal/wireguard"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+)
+
+// maxIdempotencyKeyLen максимальная длина idempotency_key
+const maxIdempotencyKeyLen = 128
+
+// IfExistsPolicy определяет, что делает CreateClient с уже существующим клиентом
+type IfExistsPolicy string
+
+const (
+	IfExistsFail           IfExistsPolicy = "fail"            // ошибка AlreadyExists
+	IfExistsReturnExisting IfExistsPolicy = "return_existing" // конфиг существующего клиента
+	IfExistsRotate         IfExistsPolicy = "rotate"          // перевыпуск ключей
+)
+
+// Значения CreateClientResponse.result
+const (
+	resultCreated  = "created"
+	resultExisting = "existing"
+	resultRotated  = "rotated"
+	resultReplayed = "replayed"
+)
+
+// parseIfExistsPolicy разбирает if_exists из запроса, пусто - fail
+func parseIfExistsPolicy(s string) (IfExistsPolicy, error) {
+	switch p := IfExistsPolicy(s); p {
+	case "":
+		return IfExistsFail, nil
+	case IfExistsFail, IfExistsReturnExisting, IfExistsRotate:
+		return p, nil
+	default:
+		return "", invalidArgument("if_exists",
+			fmt.Sprintf("unknown if_exists %q (expected fail, return_existing or rotate)", s))
+	}
+}
+
+// validateIdempotencyKey проверяет idempotency_key из запроса
+func validateIdempotencyKey(key string) error {
+	if len(key) > maxIdempotencyKeyLen {
+		return invalidArgument("idempotency_key",
+			fmt.Sprintf("idempotency_key is longer than %d characters", maxIdempotencyKeyLen))
+	}
+	return nil
+}
+
+// replayedClient возвращает клиента, уже созданного запросом с тем же
+// idempotency_key. nil - ключ неизвестен, истёк или клиента с тех пор удалили.
+func (s *agentService) replayedClient(key, userID string) (*wireguard.ClientData, error) {
+	if key == "" {
+		return nil, nil
+	}
+	k, ok := s.clients.GetIdempotencyKey(key)
+	if !ok {
+		return nil, nil
+	}
+	if k.UserID != userID {
+		return nil, statusError(codes.InvalidArgument, proto.ErrorReason_IDEMPOTENCY_KEY_REUSED,
+			"idempotency_key is already used for another user_id", "user_id", userID)
+	}
+	client, _ := s.clients.Get(userID)
+	return client, nil
+}
+
+// existingClient отвечает на CreateClient для уже существующего клиента
+// по политике if_exists. Вызывается под s.syncMu.RLock.
+func (s *agentService) existingClient(client *wireguard.ClientData, policy IfExistsPolicy, requestedPublicKey string, render renderOptions) (*wireguard.ClientData, *clientArtifacts, string, error) {
+	if policy == IfExistsFail {
+		return nil, nil, "", statusError(codes.AlreadyExists, proto.ErrorReason_CLIENT_ALREADY_EXISTS,
+			fmt.Sprintf("client %s already exists", client.UserID), "user_id", client.UserID)
+	}
+
+	device, err := s.wgClient.Device(s.iface)
+	if err != nil {
+		return nil, nil, "", s.deviceError("failed to get WireGuard device", err)
+	}
+	serverPublicKey := device.PublicKey.String()
+
+	result := resultExisting
+	if policy == IfExistsRotate {
+		if client, err = s.rotateKeys(client, requestedPublicKey, serverPublicKey); err != nil {
+			return nil, nil, "", err
+		}
+		result = resultRotated
+	}
+
+	artifacts, err := s.renderClient(client, serverPublicKey, render)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return client, artifacts, result, nil
+}
+
+// rememberIdempotencyKey запоминает ключ выполненного запроса. Ошибка только
+// логируется: клиент уже создан, а повтор без ключа вернёт AlreadyExists.
+func (s *agentService) rememberIdempotencyKey(key, userID string) {
+	if key == "" {
+		return
+	}
+	if err := s.clients.AddIdempotencyKey(key, userID); err != nil {
+		s.log.Error("Failed to save idempotency key", "user_id", userID, "error", err)
+	}
+}
+
+// expireIdempotencyKeys забывает ключи идемпотентности с истёкшим сроком
+func (s *agentService) expireIdempotencyKeys() {
+	n, err := s.clients.ExpireIdempotencyKeys(time.Now())
+	if err != nil {
+		s.log.Error("Failed to expire idempotency keys", "error", err)
+		return
+	}
+	if n > 0 {
+		s.log.Debug("Idempotency keys expired", "count", n)
+	}
+}
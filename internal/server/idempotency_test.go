@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/quibex/wg-agent/internal/config"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateClient_IdempotencyKey(t *testing.T) {
+	s, wg := newTestService(t, func(cfg *config.Config) { cfg.IdempotencyTTL = time.Hour })
+	ctx := context.Background()
+	req := &proto.CreateClientRequest{UserId: "alice", IdempotencyKey: "op-1"}
+
+	first, err := s.CreateClient(ctx, req)
+	if err != nil || first.Result != resultCreated {
+		t.Fatalf("CreateClient() = %v, %v", first, err)
+	}
+
+	// Повтор после таймаута отдаёт тот же конфиг и не трогает устройство
+	retry, err := s.CreateClient(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateClient() retry error = %v", err)
+	}
+	if retry.Result != resultReplayed || retry.ConfigFile != first.ConfigFile {
+		t.Errorf("retry result = %q, same config = %v", retry.Result, retry.ConfigFile == first.ConfigFile)
+	}
+	if device, _ := wg.Device("wg0"); len(device.Peers) != 1 {
+		t.Errorf("device has %d peers, want 1", len(device.Peers))
+	}
+
+	_, err = s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob", IdempotencyKey: "op-1"})
+	if status.Code(err) != codes.InvalidArgument || errorReason(err) != proto.ErrorReason_IDEMPOTENCY_KEY_REUSED.String() {
+		t.Errorf("key reused for bob: error = %v", err)
+	}
+}
+
+func TestCreateClient_IfExists(t *testing.T) {
+	s, _ := newTestService(t, func(cfg *config.Config) { cfg.IdempotencyTTL = time.Hour })
+	ctx := context.Background()
+
+	first, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+
+	for _, policy := range []string{"", "fail"} {
+		_, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", IfExists: policy})
+		if status.Code(err) != codes.AlreadyExists {
+			t.Errorf("if_exists=%q: error = %v, want AlreadyExists", policy, err)
+		}
+	}
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", IfExists: "replace"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("unknown if_exists: error = %v, want InvalidArgument", err)
+	}
+
+	existing, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", IfExists: "return_existing"})
+	if err != nil || existing.Result != resultExisting || existing.ConfigFile != first.ConfigFile {
+		t.Errorf("return_existing = %v, %v", existing, err)
+	}
+
+	// rotate с ключом идемпотентности: повтор не перевыпускает ключи ещё раз
+	req := &proto.CreateClientRequest{UserId: "alice", IfExists: "rotate", IdempotencyKey: "rotate-1"}
+	rotated, err := s.CreateClient(ctx, req)
+	if err != nil || rotated.Result != resultRotated {
+		t.Fatalf("rotate = %v, %v", rotated, err)
+	}
+	if rotated.ConfigFile == first.ConfigFile || rotated.ClientIp != first.ClientIp {
+		t.Error("rotate should change keys and keep the IP")
+	}
+	retry, err := s.CreateClient(ctx, req)
+	if err != nil || retry.Result != resultReplayed || retry.ConfigFile != rotated.ConfigFile {
+		t.Errorf("rotate retry = %v, %v", retry, err)
+	}
+}
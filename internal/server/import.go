@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/quibex/wg-agent/internal/wireguard"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+)
+
+// ImportClients регистрирует пиры из конфига wg-quick как клиентов.
+func (s *agentService) ImportClients(ctx context.Context, req *proto.ImportClientsRequest) (*proto.ImportClientsResponse, error) {
+	text := req.Config
+	if text == "" {
+		data, err := os.ReadFile(s.wgConfigPath)
+		if err != nil {
+			return nil, internalError(fmt.Errorf("failed to read %s: %w", s.wgConfigPath, err))
+		}
+		text = string(data)
+	}
+
+	cfg, err := wireguard.ParseWGQuickConfig(strings.NewReader(text))
+	if err != nil {
+		return nil, invalidArgument("config", fmt.Sprintf("failed to parse config: %v", err))
+	}
+
+	// Импорт меняет многих клиентов сразу, поэтому исключает остальные RPC
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	results := wireguard.ImportPeers(s.clients, cfg, wireguard.ImportOptions{
+		UserIDs: req.UserIds,
+		DryRun:  req.DryRun,
+		Sealer:  s.sealer,
+	})
+	if !req.DryRun {
+		s.syncConfig()
+	}
+
+	resp := &proto.ImportClientsResponse{
+		Results: make([]*proto.ImportResult, 0, len(results)),
+	}
+	imported := 0
+	for _, r := range results {
+		if r.Status == wireguard.ImportImported {
+			imported++
+		}
+		resp.Results = append(resp.Results, &proto.ImportResult{
+			PublicKey: r.PublicKey,
+			UserId:    r.UserID,
+			DeviceId:  r.DeviceID,
+			ClientIp:  r.AllowedIP,
+			ClientIps: wireguard.JoinAllowedIPs(r.AllowedIP, r.AllowedIP6),
+			Status:    string(r.Status),
+			Message:   r.Message,
+		})
+	}
+
+	s.log.Info("clients imported", "peers", len(results), "imported", imported, "dry_run", req.DryRun)
+
+	return resp, nil
+}
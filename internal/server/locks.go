@@ -0,0 +1,50 @@
+package server
+
+import "sync"
+
+// userLocks сериализует операции над одним user_id: проверка существования,
+// выделение адреса, изменение устройства и запись в хранилище выполняются
+// без вмешательства параллельных запросов того же пользователя.
+// Запросы разных пользователей не блокируют друг друга.
+type userLocks struct {
+	mu    sync.Mutex
+	locks map[string]*userLock
+}
+
+type userLock struct {
+	mu   sync.Mutex
+	refs int // сколько запросов держат или ждут блокировку
+}
+
+// publicKeyLock ключ блокировки присланного клиентом публичного ключа.
+// Берётся после блокировки user_id.
+func publicKeyLock(publicKey string) string {
+	return "public_key:" + publicKey
+}
+
+// lock блокирует user_id и возвращает функцию разблокировки.
+// Блокировка берётся после s.syncMu.RLock, чтобы не мешать сверке.
+func (l *userLocks) lock(userID string) (unlock func()) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*userLock)
+	}
+	ul, ok := l.locks[userID]
+	if !ok {
+		ul = &userLock{}
+		l.locks[userID] = ul
+	}
+	ul.refs++
+	l.mu.Unlock()
+
+	ul.mu.Lock()
+	return func() {
+		ul.mu.Unlock()
+
+		l.mu.Lock()
+		if ul.refs--; ul.refs == 0 {
+			delete(l.locks, userID)
+		}
+		l.mu.Unlock()
+	}
+}
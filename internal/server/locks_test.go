@@ -0,0 +1,295 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quibex/wg-agent/internal/config"
+	"github.com/quibex/wg-agent/internal/wireguard"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Тесты этого файла имеют смысл под go test -race
+
+// parallel запускает fn n раз одновременно и ждёт завершения
+func parallel(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+}
+
+// assertConsistent проверяет, что на устройстве ровно включённые клиенты
+// хранилища, а адреса клиентов не пересекаются
+func assertConsistent(t *testing.T, s *agentService, wg *wireguard.MockClient) {
+	t.Helper()
+	peers := devicePeers(t, wg)
+	ips := make(map[string]string)
+	enabled := 0
+	for _, c := range s.clients.List() {
+		if owner, ok := ips[c.AllowedIP]; ok {
+			t.Errorf("IP %s is shared by %s and %s", c.AllowedIP, owner, c.UserID)
+		}
+		ips[c.AllowedIP] = c.UserID
+		if c.Enabled {
+			enabled++
+			if !peers[c.PublicKey] {
+				t.Errorf("enabled client %s has no peer on the device", c.UserID)
+			}
+		}
+	}
+	if len(peers) != enabled {
+		t.Errorf("device has %d peers, store has %d enabled clients", len(peers), enabled)
+	}
+}
+
+func TestCreateClient_ConcurrentUsers(t *testing.T) {
+	s, wg := newTestService(t)
+
+	var failed atomic.Int32
+	parallel(50, func(i int) {
+		if _, err := s.CreateClient(context.Background(), &proto.CreateClientRequest{UserId: fmt.Sprintf("user-%d", i)}); err != nil {
+			t.Errorf("CreateClient(user-%d) error = %v", i, err)
+			failed.Add(1)
+		}
+	})
+
+	if got := len(s.clients.List()); got != 50-int(failed.Load()) {
+		t.Errorf("store has %d clients", got)
+	}
+	assertConsistent(t, s, wg)
+}
+
+func TestCreateClient_ConcurrentSameUser(t *testing.T) {
+	s, wg := newTestService(t)
+
+	var created atomic.Int32
+	parallel(20, func(int) {
+		_, err := s.CreateClient(context.Background(), &proto.CreateClientRequest{UserId: "alice"})
+		switch status.Code(err) {
+		case codes.OK:
+			created.Add(1)
+		case codes.AlreadyExists:
+		default:
+			t.Errorf("CreateClient() error = %v", err)
+		}
+	})
+
+	if created.Load() != 1 {
+		t.Errorf("%d creates succeeded, want 1", created.Load())
+	}
+	assertConsistent(t, s, wg)
+
+	// Выделен ровно один адрес: следующий клиент получает второй адрес пула
+	resp, err := s.CreateClient(context.Background(), &proto.CreateClientRequest{UserId: "bob"})
+	if err != nil {
+		t.Fatalf("CreateClient(bob) error = %v", err)
+	}
+	if resp.ClientIp != "10.8.0.3/32" {
+		t.Errorf("bob IP = %s, want 10.8.0.3/32", resp.ClientIp)
+	}
+}
+
+func TestCreateClient_ConcurrentSamePublicKey(t *testing.T) {
+	s, wg := newTestService(t)
+	key := mustKey(t)
+
+	var created atomic.Int32
+	parallel(10, func(i int) {
+		_, err := s.CreateClient(context.Background(), &proto.CreateClientRequest{
+			UserId:    fmt.Sprintf("user-%d", i),
+			PublicKey: key,
+		})
+		switch status.Code(err) {
+		case codes.OK:
+			created.Add(1)
+		case codes.AlreadyExists:
+		default:
+			t.Errorf("CreateClient() error = %v", err)
+		}
+	})
+
+	if created.Load() != 1 {
+		t.Errorf("%d creates succeeded, want 1", created.Load())
+	}
+	assertConsistent(t, s, wg)
+}
+
+func TestClientOperations_Concurrent(t *testing.T) {
+	s, wg := newTestService(t)
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: fmt.Sprintf("user-%d", i)}); err != nil {
+			t.Fatalf("CreateClient() error = %v", err)
+		}
+	}
+
+	// Любые ошибки допустимы (клиента могли удалить), важно итоговое состояние
+	parallel(100, func(i int) {
+		userID := fmt.Sprintf("user-%d", i%5)
+		switch i % 6 {
+		case 0:
+			s.DisableClient(ctx, &proto.DisableClientRequest{UserId: userID})
+		case 1:
+			s.EnableClient(ctx, &proto.EnableClientRequest{UserId: userID})
+		case 2:
+			s.RotateClientKeys(ctx, &proto.RotateClientKeysRequest{UserId: userID})
+		case 3:
+			s.DeleteClient(ctx, &proto.DeleteClientRequest{UserId: userID})
+		case 4:
+			s.CreateClient(ctx, &proto.CreateClientRequest{UserId: userID, IfExists: "return_existing"})
+		case 5:
+			s.GetClient(ctx, &proto.GetClientRequest{UserId: userID})
+			s.ListClients(ctx, &proto.ListClientsRequest{})
+		}
+	})
+
+	assertConsistent(t, s, wg)
+}
+
+// flakyStorage хранилище, запись в которое можно сломать
+type flakyStorage struct {
+	fail atomic.Bool
+}
+
+func (f *flakyStorage) Load() (*wireguard.Snapshot, error) { return nil, nil }
+
+func (f *flakyStorage) Save(*wireguard.Snapshot) error {
+	if f.fail.Load() {
+		return errors.New("disk full")
+	}
+	return nil
+}
+
+func TestCreateClient_StoreFailureRollsBackPeer(t *testing.T) {
+	storage := &flakyStorage{}
+	store, err := wireguard.NewPersistentClientStore(storage)
+	if err != nil {
+		t.Fatalf("NewPersistentClientStore() error = %v", err)
+	}
+	wg := wireguard.NewMockClient()
+	wg.AddMockDevice("wg0", 51820)
+	cfg := &config.Config{
+		Interface:         "wg0",
+		Subnet:            "10.8.0.0/24",
+		ServerPublicIP:    "vpn.example.com",
+		ServerPort:        51820,
+		UnknownPeerPolicy: string(UnknownPeerIgnore),
+		PSKPolicy:         string(PSKOff),
+	}
+	s, err := newAgentService(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), wg, store, nil)
+	if err != nil {
+		t.Fatalf("newAgentService() error = %v", err)
+	}
+
+	pool := s.pools[config.DefaultPoolName]
+	available := pool.Available()
+
+	storage.fail.Store(true)
+	parallel(10, func(i int) {
+		_, err := s.CreateClient(context.Background(), &proto.CreateClientRequest{UserId: fmt.Sprintf("user-%d", i)})
+		if status.Code(err) != codes.Internal {
+			t.Errorf("CreateClient() error = %v, want Internal", err)
+		}
+	})
+	if peers := devicePeers(t, wg); len(peers) != 0 {
+		t.Errorf("device has %d peers after failed creates", len(peers))
+	}
+
+	// Адреса неудачных попыток вернулись в пул
+	if got := pool.Available(); got != available {
+		t.Errorf("pool has %d free addresses, want %d", got, available)
+	}
+}
+
+func TestReleaseReservation_ConcurrentCreate(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		s, wg := newTestService(t)
+		ctx := context.Background()
+		r, err := s.ReserveIP(ctx, &proto.ReserveIPRequest{UserId: "alice", Ip: "10.8.0.2"})
+		if err != nil {
+			t.Fatalf("ReserveIP() error = %v", err)
+		}
+
+		// Снятие резервирования не должно отдать адрес другому, пока
+		// CreateClient владельца его использует
+		parallel(10, func(i int) {
+			switch i {
+			case 0:
+				s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"})
+			case 1:
+				s.ReleaseReservation(ctx, &proto.ReleaseReservationRequest{Ip: r.Ip})
+			default:
+				s.CreateClient(ctx, &proto.CreateClientRequest{UserId: fmt.Sprintf("user-%d", i)})
+			}
+		})
+		assertConsistent(t, s, wg)
+	}
+}
+
+func TestReleaseQuarantine_ConcurrentCreate(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		s, wg := newTestService(t, func(cfg *config.Config) { cfg.IPQuarantine = time.Hour })
+		ctx := context.Background()
+		alice, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"})
+		if err != nil {
+			t.Fatalf("CreateClient() error = %v", err)
+		}
+		if _, err := s.DeleteClient(ctx, &proto.DeleteClientRequest{UserId: "alice"}); err != nil {
+			t.Fatalf("DeleteClient() error = %v", err)
+		}
+
+		// Снятие карантина не должно отдать адрес другому, пока прежний
+		// владелец возвращает его себе через requested_ip
+		parallel(10, func(i int) {
+			switch i {
+			case 0:
+				s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", RequestedIp: alice.ClientIp})
+			case 1:
+				s.ReleaseQuarantine(ctx, &proto.ReleaseQuarantineRequest{All: true})
+			default:
+				s.CreateClient(ctx, &proto.CreateClientRequest{UserId: fmt.Sprintf("user-%d", i)})
+			}
+		})
+		assertConsistent(t, s, wg)
+	}
+}
+
+func TestCreateClient_StoreConflictRestoresOwnerPeer(t *testing.T) {
+	s, wg := newTestService(t)
+	ctx := context.Background()
+	alice, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+
+	// Адрес alice ошибочно вернулся в пул: bob получает его, а хранилище
+	// отказывает. Откат пира bob не должен оставить alice без адреса.
+	s.pools[config.DefaultPoolName].Release(alice.ClientIp)
+	_, err = s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob", RequestedIp: alice.ClientIp})
+	if errorReason(err) != proto.ErrorReason_IP_IN_USE.String() {
+		t.Fatalf("CreateClient(bob) error = %v, want IP_IN_USE", err)
+	}
+
+	device, _ := wg.Device("wg0")
+	if len(device.Peers) != 1 || len(device.Peers[0].AllowedIPs) != 1 || device.Peers[0].AllowedIPs[0].String() != alice.ClientIp {
+		t.Errorf("device peers after rollback = %+v, want alice with %s", device.Peers, alice.ClientIp)
+	}
+}
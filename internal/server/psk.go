@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quibex/wg-agent/internal/wireguard"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+)
+
+// PresharedKeyPolicy определяет, когда клиентам выдаётся preshared key
+type PresharedKeyPolicy string
+
+const (
+	PSKOff        PresharedKeyPolicy = "off"         // preshared key не используются
+	PSKAlways     PresharedKeyPolicy = "always"      // каждому новому клиенту
+	PSKPerRequest PresharedKeyPolicy = "per-request" // если запрошен в CreateClientRequest
+)
+
+// ParsePresharedKeyPolicy разбирает политику из конфигурации
+func ParsePresharedKeyPolicy(s string) (PresharedKeyPolicy, error) {
+	switch p := PresharedKeyPolicy(s); p {
+	case PSKOff, PSKAlways, PSKPerRequest:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown preshared key policy %q (expected off, always or per-request)", s)
+	}
+}
+
+// use решает, нужен ли preshared key новому клиенту
+func (p PresharedKeyPolicy) use(requested bool) (bool, error) {
+	switch p {
+	case PSKAlways:
+		return true, nil
+	case PSKPerRequest:
+		return requested, nil
+	default:
+		if requested {
+			return false, errPSKDisabled()
+		}
+		return false, nil
+	}
+}
+
+// errPSKDisabled preshared key запрошен, а политика сервера - off
+func errPSKDisabled() error {
+	return statusError(codes.FailedPrecondition, proto.ErrorReason_PRESHARED_KEY_DISABLED,
+		"preshared keys are disabled on this server")
+}
+
+// newPresharedKey генерирует preshared key и шифрует его для хранилища
+func (s *agentService) newPresharedKey() (string, error) {
+	psk, err := wireguard.GeneratePresharedKey()
+	if err != nil {
+		return "", internalError(fmt.Errorf("failed to generate preshared key: %w", err))
+	}
+	sealed, err := s.sealer.Seal(psk)
+	if err != nil {
+		return "", internalError(fmt.Errorf("failed to seal preshared key: %w", err))
+	}
+	return sealed, nil
+}
+
+// AddPresharedKey генерирует клиенту (или его устройству) новый preshared key
+// и возвращает обновлённый конфиг.
+func (s *agentService) AddPresharedKey(ctx context.Context, req *proto.AddPresharedKeyRequest) (*proto.ClientConfigResponse, error) {
+	userID := req.UserId
+	if userID == "" {
+		return nil, userIDRequired()
+	}
+	render, err := parseRenderOptions(req.QrOptions, req.OutputFormats, req.DeepLinkOptions)
+	if err != nil {
+		return nil, err
+	}
+	if s.pskPolicy == PSKOff {
+		return nil, errPSKDisabled()
+	}
+
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+	defer s.users.lock(userID)()
+
+	client, err := s.getDevice(userID, req.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	device, err := s.wgClient.Device(s.iface)
+	if err != nil {
+		return nil, s.deviceError("failed to get WireGuard device", err)
+	}
+
+	psk, err := s.newPresharedKey()
+	if err != nil {
+		return nil, err
+	}
+	updated := *client
+	updated.PresharedKey = psk
+
+	// Отключенный клиент получит ключ на устройстве при включении
+	if client.Enabled {
+		if err := s.addPeer(&updated); err != nil {
+			return nil, s.deviceError("failed to update peer in WireGuard", err)
+		}
+	}
+
+	if err := s.clients.Add(&updated); err != nil {
+		if client.Enabled {
+			s.rollback(s.addPeer(client))
+		}
+		return nil, storeError("failed to save client", err)
+	}
+
+	artifacts, err := s.renderClient(&updated, device.PublicKey.String(), render)
+	if err != nil {
+		return nil, err
+	}
+
+	s.syncConfig()
+	s.log.Info("preshared key added", "user_id", userID, "device_id", client.DeviceID)
+
+	return clientConfigResponse(&updated, artifacts), nil
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/quibex/wg-agent/internal/wireguard"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+)
+
+// sweepInterval как часто адреса с истёкшим карантином возвращаются в пул,
+// клиенты с истёкшей подпиской отключаются, а истёкшие ключи
+// идемпотентности забываются
+const sweepInterval = time.Minute
+
+// expireQuarantine возвращает в пул адреса с истёкшим карантином
+func (s *agentService) expireQuarantine() {
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+
+	now := time.Now()
+	for _, q := range s.clients.ListQuarantine() {
+		if q.Until.After(now) {
+			break
+		}
+		released, err := s.releaseQuarantined(q, now)
+		if err != nil {
+			s.log.Error("Failed to expire IP quarantine", "ip", q.IP, "error", err)
+			continue
+		}
+		if released {
+			s.log.Info("IP quarantine expired", "ip", q.IP)
+		}
+	}
+}
+
+// releaseQuarantined снимает карантин с адреса под блокировкой прежнего
+// владельца: тот может как раз возвращать адрес себе через requested_ip.
+// С ненулевым expiredAt адрес освобождается, только если карантин истёк к
+// этому моменту. false - адреса уже нет в карантине за этим владельцем.
+// Вызывается под s.syncMu.
+func (s *agentService) releaseQuarantined(q *wireguard.QuarantinedIP, expiredAt time.Time) (bool, error) {
+	defer s.users.lock(q.UserID)()
+
+	// Пока ждали блокировку, владелец мог забрать адрес себе
+	current, ok := s.clients.GetQuarantine(q.IP)
+	if !ok || current.UserID != q.UserID || (!expiredAt.IsZero() && current.Until.After(expiredAt)) {
+		return false, nil
+	}
+	if _, err := s.clients.ReleaseQuarantine(q.IP); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sweepLoop периодически освобождает адреса с истёкшим карантином,
+// отключает клиентов с истёкшей подпиской и забывает истёкшие ключи
+// идемпотентности до отмены ctx
+func (s *agentService) sweepLoop(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.expireQuarantine()
+			s.expireClients()
+			s.expireIdempotencyKeys()
+		}
+	}
+}
+
+// ListQuarantine возвращает адреса в карантине.
+func (s *agentService) ListQuarantine(ctx context.Context, req *proto.ListQuarantineRequest) (*proto.ListQuarantineResponse, error) {
+	entries := s.clients.ListQuarantine()
+
+	resp := &proto.ListQuarantineResponse{
+		Entries: make([]*proto.QuarantinedIP, 0, len(entries)),
+	}
+	for _, q := range entries {
+		resp.Entries = append(resp.Entries, &proto.QuarantinedIP{
+			Ip:         q.IP,
+			UserId:     q.UserID,
+			ReleasedAt: q.ReleasedAt.Unix(),
+			ExpiresAt:  q.Until.Unix(),
+		})
+	}
+	return resp, nil
+}
+
+// ReleaseQuarantine досрочно возвращает адрес (или все адреса) из карантина в пул.
+func (s *agentService) ReleaseQuarantine(ctx context.Context, req *proto.ReleaseQuarantineRequest) (*proto.ReleaseQuarantineResponse, error) {
+	ip := ""
+	if !req.All {
+		if req.Ip == "" {
+			return nil, invalidArgument("ip", "ip or all is required")
+		}
+		var err error
+		if _, ip, err = s.poolFor(req.Ip); err != nil {
+			return nil, err
+		}
+	}
+
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+
+	var entries []*wireguard.QuarantinedIP
+	if req.All {
+		entries = s.clients.ListQuarantine()
+	} else if q, ok := s.clients.GetQuarantine(ip); ok {
+		entries = append(entries, q)
+	}
+
+	released := make([]string, 0, len(entries))
+	for _, q := range entries {
+		ok, err := s.releaseQuarantined(q, time.Time{})
+		if err != nil {
+			return nil, storeError("failed to save quarantine", err)
+		}
+		if ok {
+			s.log.Info("IP released from quarantine", "ip", q.IP)
+			released = append(released, q.IP)
+		}
+	}
+	if !req.All && len(released) == 0 {
+		return nil, statusError(codes.NotFound, proto.ErrorReason_NOT_QUARANTINED,
+			fmt.Sprintf("IP %s is not in quarantine", req.Ip), "ip", req.Ip)
+	}
+
+	return &proto.ReleaseQuarantineResponse{ReleasedIps: released}, nil
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/quibex/wg-agent/internal/config"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIPQuarantine(t *testing.T) {
+	s, _ := newTestService(t, func(cfg *config.Config) {
+		cfg.Subnet = "10.8.0.0/29" // .2-.6
+		cfg.IPQuarantine = time.Hour
+	})
+	ctx := context.Background()
+
+	alice, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	if _, err := s.DeleteClient(ctx, &proto.DeleteClientRequest{UserId: "alice"}); err != nil {
+		t.Fatalf("DeleteClient() error = %v", err)
+	}
+
+	list, _ := s.ListQuarantine(ctx, &proto.ListQuarantineRequest{})
+	if len(list.Entries) != 1 || list.Entries[0].Ip != alice.ClientIp || list.Entries[0].UserId != "alice" {
+		t.Fatalf("ListQuarantine() = %v", list.Entries)
+	}
+
+	// Адрес в карантине не выдаётся другим пользователям ни автоматически, ни по запросу
+	for _, user := range []string{"bob", "carol", "dave", "erin"} {
+		resp, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: user})
+		if err != nil {
+			t.Fatalf("CreateClient(%s) error = %v", user, err)
+		}
+		if resp.ClientIp == alice.ClientIp {
+			t.Fatalf("%s got quarantined IP %s", user, resp.ClientIp)
+		}
+	}
+	_, err = s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "frank"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("CreateClient() with only quarantined IPs left: error = %v, want ResourceExhausted", err)
+	}
+	_, err = s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "frank", RequestedIp: alice.ClientIp})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("CreateClient(requested quarantined IP) error = %v, want FailedPrecondition", err)
+	}
+
+	// Истёкший карантин не освобождает адрес раньше срока
+	s.expireQuarantine()
+	if list, _ := s.ListQuarantine(ctx, &proto.ListQuarantineRequest{}); len(list.Entries) != 1 {
+		t.Errorf("quarantine expired early: %v", list.Entries)
+	}
+
+	_, err = s.ReleaseQuarantine(ctx, &proto.ReleaseQuarantineRequest{Ip: "10.8.0.6"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("ReleaseQuarantine(not quarantined) error = %v, want NotFound", err)
+	}
+	released, err := s.ReleaseQuarantine(ctx, &proto.ReleaseQuarantineRequest{Ip: alice.ClientIp})
+	if err != nil {
+		t.Fatalf("ReleaseQuarantine() error = %v", err)
+	}
+	if len(released.ReleasedIps) != 1 || released.ReleasedIps[0] != alice.ClientIp {
+		t.Errorf("ReleaseQuarantine() = %v", released.ReleasedIps)
+	}
+
+	frank, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "frank"})
+	if err != nil {
+		t.Fatalf("CreateClient() after release error = %v", err)
+	}
+	if frank.ClientIp != alice.ClientIp {
+		t.Errorf("frank IP = %s, want released %s", frank.ClientIp, alice.ClientIp)
+	}
+}
+
+func TestIPQuarantine_PreviousOwner(t *testing.T) {
+	s, _ := newTestService(t, func(cfg *config.Config) {
+		cfg.IPQuarantine = time.Hour
+	})
+	ctx := context.Background()
+
+	alice, _ := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"})
+	if _, err := s.DeleteClient(ctx, &proto.DeleteClientRequest{UserId: "alice"}); err != nil {
+		t.Fatalf("DeleteClient() error = %v", err)
+	}
+
+	// Прежний владелец может вернуть себе адрес, карантин при этом снимается
+	again, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", RequestedIp: alice.ClientIp})
+	if err != nil {
+		t.Fatalf("CreateClient(previous owner) error = %v", err)
+	}
+	if again.ClientIp != alice.ClientIp {
+		t.Errorf("IP = %s, want %s", again.ClientIp, alice.ClientIp)
+	}
+	if list, _ := s.ListQuarantine(ctx, &proto.ListQuarantineRequest{}); len(list.Entries) != 0 {
+		t.Errorf("quarantine should be lifted, got %v", list.Entries)
+	}
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/quibex/wg-agent/internal/wireguard"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// UnknownPeerPolicy определяет, что делать с пирами на устройстве, которых нет в хранилище
+type UnknownPeerPolicy string
+
+const (
+	UnknownPeerIgnore UnknownPeerPolicy = "ignore" // только сообщать
+	UnknownPeerAdopt  UnknownPeerPolicy = "adopt"  // добавить в хранилище
+	UnknownPeerRemove UnknownPeerPolicy = "remove" // удалить с устройства
+)
+
+// adoptedUserIDPrefix префикс user_id для пиров, взятых под управление при сверке
+const adoptedUserIDPrefix = "adopted:"
+
+// ParseUnknownPeerPolicy разбирает политику из конфигурации
+func ParseUnknownPeerPolicy(s string) (UnknownPeerPolicy, error) {
+	switch p := UnknownPeerPolicy(s); p {
+	case UnknownPeerIgnore, UnknownPeerAdopt, UnknownPeerRemove:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown peer policy %q (expected adopt, remove or ignore)", s)
+	}
+}
+
+// ReconcileResult результат сверки хранилища с устройством WireGuard
+type ReconcileResult struct {
+	RunAt          time.Time
+	Readded        []string // user_id (user_id/device_id) включенных клиентов, добавленных на устройство
+	Removed        []string // user_id (user_id/device_id) отключенных клиентов, убранных с устройства
+	Unknown        []string // публичные ключи пиров, которых нет в хранилище
+	Adopted        []string // user_id пиров, взятых под управление
+	RemovedUnknown []string // публичные ключи неизвестных пиров, удалённых с устройства
+	Errors         []string
+}
+
+// InSync возвращает true, если расхождений и ошибок не найдено
+func (r *ReconcileResult) InSync() bool {
+	return len(r.Readded) == 0 && len(r.Removed) == 0 && len(r.Unknown) == 0 && len(r.Errors) == 0
+}
+
+// reconcile приводит устройство WireGuard в соответствие с хранилищем
+func (s *agentService) reconcile() *ReconcileResult {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	result := &ReconcileResult{RunAt: time.Now()}
+	defer s.setLastReconcile(result)
+
+	device, err := s.wgClient.Device(s.iface)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to get WireGuard device: %v", err))
+		return result
+	}
+
+	peers := make(map[string]wgtypes.Peer, len(device.Peers))
+	for _, peer := range device.Peers {
+		peers[peer.PublicKey.String()] = peer
+	}
+
+	known := make(map[string]bool)
+	for _, client := range s.clients.List() {
+		known[client.PublicKey] = true
+		_, onDevice := peers[client.PublicKey]
+
+		switch {
+		case client.Enabled && !onDevice:
+			if err := s.addPeer(client); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("re-add %s: %v", client.ID(), err))
+				continue
+			}
+			result.Readded = append(result.Readded, client.ID())
+		case !client.Enabled && onDevice:
+			if err := s.removePeer(client.PublicKey); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("remove %s: %v", client.ID(), err))
+				continue
+			}
+			result.Removed = append(result.Removed, client.ID())
+		}
+	}
+
+	for publicKey, peer := range peers {
+		if known[publicKey] {
+			continue
+		}
+		result.Unknown = append(result.Unknown, publicKey)
+
+		switch s.unknownPolicy {
+		case UnknownPeerAdopt:
+			cidrs := make([]string, 0, len(peer.AllowedIPs))
+			for _, ipNet := range peer.AllowedIPs {
+				cidrs = append(cidrs, ipNet.String())
+			}
+			v4, v6, err := wireguard.SplitAllowedIPs(cidrs)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("adopt %s: %v", publicKey, err))
+				continue
+			}
+			client := &wireguard.ClientData{
+				UserID:     adoptedUserIDPrefix + publicKey,
+				PublicKey:  publicKey,
+				AllowedIP:  v4,
+				AllowedIP6: v6,
+				Enabled:    true,
+			}
+			if peer.PresharedKey != (wgtypes.Key{}) {
+				if client.PresharedKey, err = s.sealer.Seal(peer.PresharedKey.String()); err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("adopt %s: %v", publicKey, err))
+					continue
+				}
+			}
+			if err := s.clients.Add(client); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("adopt %s: %v", publicKey, err))
+				continue
+			}
+			result.Adopted = append(result.Adopted, client.UserID)
+		case UnknownPeerRemove:
+			if err := s.removePeer(publicKey); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("remove unknown %s: %v", publicKey, err))
+				continue
+			}
+			result.RemovedUnknown = append(result.RemovedUnknown, publicKey)
+		}
+	}
+
+	// Конфиг wg-quick тоже приводим к хранилищу
+	s.syncConfig()
+
+	for _, list := range [][]string{result.Readded, result.Removed, result.Unknown, result.Adopted, result.RemovedUnknown} {
+		sort.Strings(list)
+	}
+	return result
+}
+
+// setLastReconcile запоминает результат последней сверки
+func (s *agentService) setLastReconcile(result *ReconcileResult) {
+	s.reconcileMu.Lock()
+	defer s.reconcileMu.Unlock()
+	s.lastReconcile = result
+}
+
+// getLastReconcile возвращает результат последней сверки (nil, если сверки не было)
+func (s *agentService) getLastReconcile() *ReconcileResult {
+	s.reconcileMu.Lock()
+	defer s.reconcileMu.Unlock()
+	return s.lastReconcile
+}
+
+// runReconcile выполняет сверку и логирует результат
+func (s *agentService) runReconcile() *ReconcileResult {
+	result := s.reconcile()
+	if result.InSync() {
+		s.log.Debug("reconcile: in sync")
+		return result
+	}
+
+	s.log.Warn("reconcile: drift detected",
+		"readded", result.Readded,
+		"removed", result.Removed,
+		"unknown_peers", len(result.Unknown),
+		"adopted", len(result.Adopted),
+		"removed_unknown", len(result.RemovedUnknown),
+		"policy", s.unknownPolicy,
+		"errors", result.Errors,
+	)
+	return result
+}
+
+// reconcileLoop периодически запускает сверку до отмены ctx
+func (s *agentService) reconcileLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runReconcile()
+		}
+	}
+}
+
+// GetReconcileStatus возвращает результат последней сверки с устройством.
+func (s *agentService) GetReconcileStatus(ctx context.Context, req *proto.GetReconcileStatusRequest) (*proto.GetReconcileStatusResponse, error) {
+	var result *ReconcileResult
+	if req.RunNow {
+		result = s.runReconcile()
+	} else {
+		result = s.getLastReconcile()
+	}
+
+	resp := &proto.GetReconcileStatusResponse{
+		UnknownPeerPolicy: string(s.unknownPolicy),
+	}
+	if result == nil {
+		return resp, nil
+	}
+
+	resp.LastRun = result.RunAt.Unix()
+	resp.InSync = result.InSync()
+	resp.ReaddedUserIds = result.Readded
+	resp.RemovedUserIds = result.Removed
+	resp.UnknownPeers = result.Unknown
+	resp.AdoptedUserIds = result.Adopted
+	resp.RemovedUnknownPeers = result.RemovedUnknown
+	resp.Errors = result.Errors
+	return resp, nil
+}
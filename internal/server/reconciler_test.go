@@ -0,0 +1,139 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/quibex/wg-agent/internal/config"
+	"github.com/quibex/wg-agent/internal/wireguard"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// newTestService создает agentService поверх MockClient с устройством wg0.
+// configure позволяет поменять конфигурацию по умолчанию.
+func newTestService(t *testing.T, configure ...func(*config.Config)) (*agentService, *wireguard.MockClient) {
+	t.Helper()
+	wg := wireguard.NewMockClient()
+	wg.AddMockDevice("wg0", 51820)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{
+		Interface:         "wg0",
+		Subnet:            "10.8.0.0/24",
+		ServerPublicIP:    "vpn.example.com",
+		ServerPort:        51820,
+		UnknownPeerPolicy: string(UnknownPeerIgnore),
+		PSKPolicy:         string(PSKOff),
+		ClientDNS:         "1.1.1.1, 1.0.0.1",
+		ClientKeepalive:   25,
+	}
+	for _, fn := range configure {
+		fn(cfg)
+	}
+	s, err := newAgentService(cfg, log, wg, wireguard.NewClientStore(), nil)
+	if err != nil {
+		t.Fatalf("newAgentService() error = %v", err)
+	}
+	return s, wg
+}
+
+func mustKey(t *testing.T) string {
+	t.Helper()
+	_, pub, err := wireguard.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair() error = %v", err)
+	}
+	return pub
+}
+
+func addDevicePeer(t *testing.T, wg *wireguard.MockClient, publicKey, cidr string) {
+	t.Helper()
+	key, _ := wgtypes.ParseKey(publicKey)
+	_, ipNet, _ := net.ParseCIDR(cidr)
+	err := wg.ConfigureDevice("wg0", wgtypes.Config{Peers: []wgtypes.PeerConfig{{
+		PublicKey:  key,
+		AllowedIPs: []net.IPNet{*ipNet},
+	}}})
+	if err != nil {
+		t.Fatalf("ConfigureDevice() error = %v", err)
+	}
+}
+
+func devicePeers(t *testing.T, wg *wireguard.MockClient) map[string]bool {
+	t.Helper()
+	device, err := wg.Device("wg0")
+	if err != nil {
+		t.Fatalf("Device() error = %v", err)
+	}
+	peers := make(map[string]bool)
+	for _, p := range device.Peers {
+		peers[p.PublicKey.String()] = true
+	}
+	return peers
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      UnknownPeerPolicy
+		wantUnknown bool // остаётся ли неизвестный пир на устройстве
+		wantAdopted bool
+	}{
+		{name: "ignore", policy: UnknownPeerIgnore, wantUnknown: true},
+		{name: "adopt", policy: UnknownPeerAdopt, wantUnknown: true, wantAdopted: true},
+		{name: "remove", policy: UnknownPeerRemove, wantUnknown: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, wg := newTestService(t, func(cfg *config.Config) {
+				cfg.UnknownPeerPolicy = string(tt.policy)
+			})
+
+			missing := mustKey(t)  // включен в хранилище, нет на устройстве
+			disabled := mustKey(t) // отключен в хранилище, есть на устройстве
+			unknown := mustKey(t)  // есть только на устройстве
+
+			s.clients.Add(&wireguard.ClientData{UserID: "missing", PublicKey: missing, AllowedIP: "10.8.0.2/32", Enabled: true})
+			s.clients.Add(&wireguard.ClientData{UserID: "disabled", PublicKey: disabled, AllowedIP: "10.8.0.3/32", Enabled: false})
+			addDevicePeer(t, wg, disabled, "10.8.0.3/32")
+			addDevicePeer(t, wg, unknown, "10.8.0.4/32")
+
+			result := s.reconcile()
+			if result.InSync() {
+				t.Fatal("expected drift")
+			}
+			if len(result.Errors) != 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			if len(result.Readded) != 1 || result.Readded[0] != "missing" {
+				t.Errorf("Readded = %v, want [missing]", result.Readded)
+			}
+			if len(result.Removed) != 1 || result.Removed[0] != "disabled" {
+				t.Errorf("Removed = %v, want [disabled]", result.Removed)
+			}
+
+			peers := devicePeers(t, wg)
+			if !peers[missing] {
+				t.Error("missing client should be re-added to device")
+			}
+			if peers[disabled] {
+				t.Error("disabled client should be removed from device")
+			}
+			if peers[unknown] != tt.wantUnknown {
+				t.Errorf("unknown peer on device = %v, want %v", peers[unknown], tt.wantUnknown)
+			}
+			if got := s.clients.Exists(adoptedUserIDPrefix + unknown); got != tt.wantAdopted {
+				t.Errorf("unknown peer adopted = %v, want %v", got, tt.wantAdopted)
+			}
+
+			// Повторная сверка после adopt/remove не должна находить расхождений
+			if tt.policy != UnknownPeerIgnore {
+				if again := s.reconcile(); !again.InSync() {
+					t.Errorf("second reconcile not in sync: %+v", again)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/quibex/wg-agent/internal/wireguard"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// allocateIPs выбирает клиенту IPv4 адрес из пула poolName и, если включён,
+// IPv6 адрес: запрошенный, зарезервированный за user_id или первый свободный.
+// Возвращает имя пула, из которого выдан IPv4 адрес.
+func (s *agentService) allocateIPs(userID, poolName, requested string) (name, ip, ip6 string, err error) {
+	name, pool, err := s.namedPool(poolName)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	requestedPool, requestedIP := (*wireguard.IPAllocator)(nil), ""
+	if requested != "" {
+		if requestedPool, requestedIP, err = s.poolFor(requested); err != nil {
+			return "", "", "", err
+		}
+		if requestedPool != pool && requestedPool != s.pool6 {
+			return "", "", "", statusError(codes.InvalidArgument, proto.ErrorReason_IP_OUTSIDE_POOL,
+				fmt.Sprintf("IP %s is not in pool %s", requested, name), "ip", requested, "pool", name)
+		}
+	}
+
+	pick := func(pool *wireguard.IPAllocator) (string, error) {
+		if pool == requestedPool {
+			return s.claimIP(pool, userID, requestedIP)
+		}
+		// Резервирование достаётся первому устройству пользователя
+		for _, r := range s.clients.ListReservations(userID) {
+			if _, used := s.clients.GetByIP(r.IP); pool.Contains(r.IP) && !used {
+				return r.IP, nil
+			}
+		}
+		ip, err := pool.Allocate()
+		if err != nil {
+			return "", ipStatusError(err)
+		}
+		return ip, nil
+	}
+
+	if ip, err = pick(pool); err != nil {
+		return "", "", "", err
+	}
+	if s.pool6 != nil {
+		if ip6, err = pick(s.pool6); err != nil {
+			s.clients.ReleaseIPs(ip)
+			return "", "", "", err
+		}
+	}
+	return name, ip, ip6, nil
+}
+
+// namedPool возвращает IPv4 пул по имени, пустое имя - пул по умолчанию
+func (s *agentService) namedPool(name string) (string, *wireguard.IPAllocator, error) {
+	if name == "" {
+		name = s.defaultPool
+	}
+	pool, ok := s.pools[name]
+	if !ok {
+		return "", nil, statusError(codes.InvalidArgument, proto.ErrorReason_UNKNOWN_POOL,
+			fmt.Sprintf("unknown pool %q", name), "pool", name)
+	}
+	return name, pool, nil
+}
+
+// clientPool возвращает имя пула клиента. У клиентов, созданных до пулов,
+// импортированных и принятых при сверке пул определяется по адресу.
+func (s *agentService) clientPool(client *wireguard.ClientData) string {
+	if client.Pool != "" {
+		return client.Pool
+	}
+	for name, pool := range s.pools {
+		if pool.Contains(client.AllowedIP) {
+			return name
+		}
+	}
+	return ""
+}
+
+// poolFor находит пул адреса и приводит адрес к виду с маской хоста
+func (s *agentService) poolFor(ip string) (*wireguard.IPAllocator, string, error) {
+	host, _, _ := strings.Cut(ip, "/")
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return nil, "", statusError(codes.InvalidArgument, proto.ErrorReason_INVALID_ARGUMENT,
+			fmt.Sprintf("invalid IP %q", ip), "ip", ip)
+	}
+
+	if !addr.Is4() {
+		if s.pool6 == nil {
+			return nil, "", statusError(codes.InvalidArgument, proto.ErrorReason_IP_OUTSIDE_POOL,
+				"IPv6 is not enabled on this server", "ip", ip)
+		}
+		cidr, ok := s.pool6.HostCIDR(host)
+		if !ok {
+			return nil, "", statusError(codes.InvalidArgument, proto.ErrorReason_IP_OUTSIDE_POOL,
+				fmt.Sprintf("IP %s is outside the subnet %s", ip, s.pool6.Subnet()), "ip", ip)
+		}
+		return s.pool6, cidr, nil
+	}
+	for _, pool := range s.pools {
+		if cidr, ok := pool.HostCIDR(host); ok {
+			return pool, cidr, nil
+		}
+	}
+	return nil, "", statusError(codes.InvalidArgument, proto.ErrorReason_IP_OUTSIDE_POOL,
+		fmt.Sprintf("IP %s is outside the address pools", ip), "ip", ip)
+}
+
+// claimIP занимает конкретный адрес для user_id. Адрес, зарезервированный
+// за этим же user_id или освобождённый им и находящийся в карантине,
+// уже удерживается хранилищем и отдаётся как есть.
+func (s *agentService) claimIP(pool *wireguard.IPAllocator, userID, cidr string) (string, error) {
+	if r, ok := s.clients.GetReservation(cidr); ok {
+		if r.UserID != userID {
+			return "", statusError(codes.FailedPrecondition, proto.ErrorReason_IP_RESERVED,
+				fmt.Sprintf("IP %s is reserved for another user", cidr), "ip", cidr)
+		}
+		return cidr, nil
+	}
+	if q, ok := s.clients.GetQuarantine(cidr); ok {
+		if q.UserID != userID {
+			until := q.Until.UTC().Format(time.RFC3339)
+			return "", statusError(codes.FailedPrecondition, proto.ErrorReason_IP_QUARANTINED,
+				fmt.Sprintf("IP %s is in quarantine until %s", cidr, until), "ip", cidr, "until", until)
+		}
+		return cidr, nil
+	}
+	ip, err := pool.Claim(cidr)
+	if err != nil {
+		return "", ipStatusError(err)
+	}
+	return ip, nil
+}
+
+// ipStatusError переводит ошибки пула адресов в коды gRPC
+func ipStatusError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, wireguard.ErrIPOutsidePool):
+		return statusError(codes.InvalidArgument, proto.ErrorReason_IP_OUTSIDE_POOL, err.Error())
+	case errors.Is(err, wireguard.ErrIPReserved):
+		return statusError(codes.InvalidArgument, proto.ErrorReason_IP_RESERVED, err.Error())
+	case errors.Is(err, wireguard.ErrIPInUse):
+		return statusError(codes.AlreadyExists, proto.ErrorReason_IP_IN_USE, err.Error())
+	case errors.Is(err, wireguard.ErrPoolExhausted):
+		return statusError(codes.ResourceExhausted, proto.ErrorReason_POOL_EXHAUSTED, err.Error())
+	default:
+		return internalError(err)
+	}
+}
+
+// ReserveIP закрепляет адрес за user_id.
+func (s *agentService) ReserveIP(ctx context.Context, req *proto.ReserveIPRequest) (*proto.Reservation, error) {
+	userID := req.UserId
+	if userID == "" {
+		return nil, userIDRequired()
+	}
+
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+	defer s.users.lock(userID)()
+
+	_, pool, err := s.namedPool(req.Pool)
+	if err != nil {
+		return nil, err
+	}
+	ip := ""
+	if req.Ip != "" {
+		ipPool, cidr, err := s.poolFor(req.Ip)
+		if err != nil {
+			return nil, err
+		}
+		if req.Pool != "" && ipPool != pool && ipPool != s.pool6 {
+			return nil, statusError(codes.InvalidArgument, proto.ErrorReason_IP_OUTSIDE_POOL,
+				fmt.Sprintf("IP %s is not in pool %s", req.Ip, req.Pool), "ip", req.Ip, "pool", req.Pool)
+		}
+		pool, ip = ipPool, cidr
+	}
+
+	// Одно резервирование на user_id в каждом пуле
+	for _, r := range s.clients.ListReservations(userID) {
+		if !pool.Contains(r.IP) {
+			continue
+		}
+		if r.IP == ip {
+			return reservationProto(r), nil
+		}
+		return nil, statusError(codes.FailedPrecondition, proto.ErrorReason_RESERVATION_EXISTS,
+			fmt.Sprintf("user %s already has reservation %s", userID, r.IP), "user_id", userID, "ip", r.IP)
+	}
+
+	// Адрес, уже выданный клиенту этого user_id, закрепляем как есть
+	owned := false
+	if client, ok := s.clients.Get(userID); ok && ip != "" {
+		owned = client.AllowedIP == ip || client.AllowedIP6 == ip
+	}
+	if !owned {
+		var err error
+		if ip == "" {
+			ip, err = pool.Allocate()
+		} else {
+			ip, err = s.claimIP(pool, userID, ip)
+		}
+		if err != nil {
+			return nil, ipStatusError(err)
+		}
+	}
+
+	r := &wireguard.Reservation{IP: ip, UserID: userID, CreatedAt: time.Now()}
+	if err := s.clients.AddReservation(r); err != nil {
+		if !owned {
+			s.clients.ReleaseIPs(ip)
+		}
+		return nil, ipStatusError(err)
+	}
+	s.log.Info("IP reserved", "user_id", userID, "ip", ip)
+
+	return reservationProto(r), nil
+}
+
+// ReleaseReservation снимает резервирование адреса.
+func (s *agentService) ReleaseReservation(ctx context.Context, req *proto.ReleaseReservationRequest) (*emptypb.Empty, error) {
+	if req.Ip == "" {
+		return nil, invalidArgument("ip", "ip is required")
+	}
+	_, ip, err := s.poolFor(req.Ip)
+	if err != nil {
+		return nil, err
+	}
+
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+
+	// Блокируем владельца: его CreateClient мог уже взять этот адрес
+	// из резервирования, но ещё не сохранить клиента
+	r, ok := s.clients.GetReservation(ip)
+	if !ok {
+		return nil, reservationNotFound(ip)
+	}
+	defer s.users.lock(r.UserID)()
+
+	if err := s.clients.DeleteReservation(ip); err != nil {
+		if errors.Is(err, wireguard.ErrReservationNotFound) {
+			return nil, reservationNotFound(ip)
+		}
+		return nil, storeError("failed to save reservations", err)
+	}
+	s.log.Info("IP reservation released", "ip", ip, "user_id", r.UserID)
+
+	return &emptypb.Empty{}, nil
+}
+
+func reservationNotFound(ip string) error {
+	return statusError(codes.NotFound, proto.ErrorReason_RESERVATION_NOT_FOUND,
+		fmt.Sprintf("no reservation for %s", ip), "ip", ip)
+}
+
+// ListReservations возвращает резервирования адресов.
+func (s *agentService) ListReservations(ctx context.Context, req *proto.ListReservationsRequest) (*proto.ListReservationsResponse, error) {
+	reservations := s.clients.ListReservations(req.UserId)
+
+	resp := &proto.ListReservationsResponse{
+		Reservations: make([]*proto.Reservation, 0, len(reservations)),
+	}
+	for _, r := range reservations {
+		resp.Reservations = append(resp.Reservations, reservationProto(r))
+	}
+	return resp, nil
+}
+
+func reservationProto(r *wireguard.Reservation) *proto.Reservation {
+	return &proto.Reservation{
+		Ip:        r.IP,
+		UserId:    r.UserID,
+		CreatedAt: r.CreatedAt.Unix(),
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quibex/wg-agent/internal/config"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReservationsAndRequestedIP(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	wantCode := func(t *testing.T, err error, code codes.Code) {
+		t.Helper()
+		if status.Code(err) != code {
+			t.Errorf("error = %v, want code %s", err, code)
+		}
+	}
+
+	r, err := s.ReserveIP(ctx, &proto.ReserveIPRequest{UserId: "bob", Ip: "10.8.0.50"})
+	if err != nil {
+		t.Fatalf("ReserveIP() error = %v", err)
+	}
+	if r.Ip != "10.8.0.50/32" {
+		t.Errorf("reservation IP = %s, want 10.8.0.50/32", r.Ip)
+	}
+
+	// Чужое резервирование, адрес вне подсети и адрес сервера запросить нельзя
+	_, err = s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", RequestedIp: "10.8.0.50"})
+	wantCode(t, err, codes.FailedPrecondition)
+	_, err = s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", RequestedIp: "10.9.0.5"})
+	wantCode(t, err, codes.InvalidArgument)
+	_, err = s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", RequestedIp: "10.8.0.1"})
+	wantCode(t, err, codes.InvalidArgument)
+
+	alice, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice", RequestedIp: "10.8.0.20"})
+	if err != nil {
+		t.Fatalf("CreateClient(requested_ip) error = %v", err)
+	}
+	if alice.ClientIp != "10.8.0.20/32" {
+		t.Errorf("alice IP = %s, want 10.8.0.20/32", alice.ClientIp)
+	}
+	_, err = s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "carol", RequestedIp: "10.8.0.20"})
+	wantCode(t, err, codes.AlreadyExists)
+
+	// bob получает зарезервированный адрес без requested_ip
+	bob, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob"})
+	if err != nil {
+		t.Fatalf("CreateClient(bob) error = %v", err)
+	}
+	if bob.ClientIp != "10.8.0.50/32" {
+		t.Errorf("bob IP = %s, want reserved 10.8.0.50/32", bob.ClientIp)
+	}
+
+	// После удаления клиента адрес остаётся за bob
+	if _, err := s.DeleteClient(ctx, &proto.DeleteClientRequest{UserId: "bob"}); err != nil {
+		t.Fatalf("DeleteClient() error = %v", err)
+	}
+	_, err = s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "carol", RequestedIp: "10.8.0.50"})
+	wantCode(t, err, codes.FailedPrecondition)
+
+	list, _ := s.ListReservations(ctx, &proto.ListReservationsRequest{})
+	if len(list.Reservations) != 1 || list.Reservations[0].UserId != "bob" {
+		t.Errorf("ListReservations() = %v", list.Reservations)
+	}
+
+	if _, err := s.ReleaseReservation(ctx, &proto.ReleaseReservationRequest{Ip: "10.8.0.50"}); err != nil {
+		t.Fatalf("ReleaseReservation() error = %v", err)
+	}
+	_, err = s.ReleaseReservation(ctx, &proto.ReleaseReservationRequest{Ip: "10.8.0.50"})
+	wantCode(t, err, codes.NotFound)
+
+	if _, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "carol", RequestedIp: "10.8.0.50"}); err != nil {
+		t.Errorf("CreateClient() after release error = %v", err)
+	}
+}
+
+func TestAddressPools(t *testing.T) {
+	s, _ := newTestService(t, func(cfg *config.Config) {
+		cfg.Pools = "paid=10.9.0.0/22"
+	})
+	ctx := context.Background()
+
+	free, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	paid, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "bob", Pool: "paid"})
+	if err != nil {
+		t.Fatalf("CreateClient(pool=paid) error = %v", err)
+	}
+	if free.ClientIp != "10.8.0.2/32" || paid.ClientIp != "10.9.0.2/32" {
+		t.Errorf("IPs = %s, %s, want 10.8.0.2/32, 10.9.0.2/32", free.ClientIp, paid.ClientIp)
+	}
+
+	if got, _ := s.GetClient(ctx, &proto.GetClientRequest{UserId: "bob"}); got.Pool != "paid" {
+		t.Errorf("GetClient().Pool = %q, want paid", got.Pool)
+	}
+	list, _ := s.ListClients(ctx, &proto.ListClientsRequest{})
+	pools := map[string]string{}
+	for _, c := range list.Clients {
+		pools[c.UserId] = c.Pool
+	}
+	if pools["alice"] != config.DefaultPoolName || pools["bob"] != "paid" {
+		t.Errorf("ListClients() pools = %v", pools)
+	}
+
+	_, err = s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "carol", Pool: "gold"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateClient(unknown pool) error = %v, want InvalidArgument", err)
+	}
+	_, err = s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "carol", Pool: "paid", RequestedIp: "10.8.0.9"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateClient(IP from another pool) error = %v, want InvalidArgument", err)
+	}
+	carol, err := s.CreateClient(ctx, &proto.CreateClientRequest{UserId: "carol", Pool: "paid", RequestedIp: "10.9.1.7"})
+	if err != nil || carol.ClientIp != "10.9.1.7/32" {
+		t.Errorf("CreateClient(requested IP in pool) = %v, %v", carol, err)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quibex/wg-agent/internal/wireguard"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// RotateClientKeys перевыпускает ключи клиента (или его устройства) с сохранением IP.
+func (s *agentService) RotateClientKeys(ctx context.Context, req *proto.RotateClientKeysRequest) (*proto.ClientConfigResponse, error) {
+	userID := req.UserId
+	if userID == "" {
+		return nil, userIDRequired()
+	}
+	render, err := parseRenderOptions(req.QrOptions, req.OutputFormats, req.DeepLinkOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+	defer s.users.lock(userID)()
+
+	client, err := s.getDevice(userID, req.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	device, err := s.wgClient.Device(s.iface)
+	if err != nil {
+		return nil, s.deviceError("failed to get WireGuard device", err)
+	}
+	serverPublicKey := device.PublicKey.String()
+
+	updated, err := s.rotateKeys(client, req.PublicKey, serverPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts, err := s.renderClient(updated, serverPublicKey, render)
+	if err != nil {
+		return nil, err
+	}
+	return clientConfigResponse(updated, artifacts), nil
+}
+
+// rotateKeys перевыпускает ключи клиента (или ставит присланный публичный ключ)
+// на устройстве и в хранилище. Вызывается под s.syncMu.RLock.
+func (s *agentService) rotateKeys(client *wireguard.ClientData, requestedPublicKey, serverPublicKey string) (*wireguard.ClientData, error) {
+	if requestedPublicKey != "" {
+		defer s.users.lock(publicKeyLock(requestedPublicKey))()
+	}
+	storedKey, publicKey, err := s.clientKeys(requestedPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if publicKey == serverPublicKey {
+		return nil, invalidArgument("public_key", "public_key must not be the server key")
+	}
+
+	updated := *client
+	updated.PublicKey = publicKey
+	updated.PrivateKey = storedKey
+
+	// Preshared key меняется вместе с ключами, если клиент его использует
+	if client.PresharedKey != "" {
+		if updated.PresharedKey, err = s.newPresharedKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Отключенный клиент получит новые ключи на устройстве при включении
+	if client.Enabled {
+		if err := s.swapPeer(client, &updated); err != nil {
+			return nil, s.deviceError("failed to replace peer in WireGuard", err)
+		}
+	}
+
+	if err := s.clients.Add(&updated); err != nil {
+		if client.Enabled {
+			s.rollback(s.swapPeer(&updated, client))
+		}
+		return nil, storeError("failed to save client", err)
+	}
+
+	s.syncConfig()
+	s.log.Info("client keys rotated", "user_id", updated.UserID, "device_id", updated.DeviceID, "client_ip", updated.AllowedIP, "key_mode", updated.KeyMode())
+	return &updated, nil
+}
+
+// swapPeer заменяет пира old на next одним вызовом ConfigureDevice,
+// чтобы на устройстве не было момента с обоими ключами или без пира
+func (s *agentService) swapPeer(old, next *wireguard.ClientData) error {
+	oldKey, err := wgtypes.ParseKey(old.PublicKey)
+	if err != nil {
+		return internalError(fmt.Errorf("invalid public key: %w", err))
+	}
+	peer, err := s.peerConfig(next)
+	if err != nil {
+		return err
+	}
+	cfg := wgtypes.Config{
+		Peers: []wgtypes.PeerConfig{
+			{PublicKey: oldKey, Remove: true},
+			peer,
+		},
+	}
+	return s.wgClient.ConfigureDevice(s.iface, cfg)
+}
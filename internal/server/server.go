@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -22,16 +23,27 @@ type Server struct {
 	config     *config.Config
 	logger     *slog.Logger
 	wgClient   wireguard.Client
+	clients    *wireguard.ClientStore
+	sealer     *wireguard.KeySealer
 	limiter    *ratelimit.Limiter
 	httpServer *HTTPServer
+
+	// ctx отменяется в Stop и останавливает фоновые задачи
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // New создает новый сервер
-func New(cfg *config.Config, log *slog.Logger, wgClient wireguard.Client) *Server {
+func New(cfg *config.Config, log *slog.Logger, wgClient wireguard.Client, clients *wireguard.ClientStore, sealer *wireguard.KeySealer) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Server{
+		ctx:        ctx,
+		cancel:     cancel,
 		config:     cfg,
 		logger:     log,
 		wgClient:   wgClient,
+		clients:    clients,
+		sealer:     sealer,
 		limiter:    ratelimit.NewLimiter(cfg.RateLimit),
 		httpServer: NewHTTPServer(cfg.HTTPAddr, log),
 	}
@@ -49,6 +61,23 @@ func (s *Server) Start() error {
 		}
 	}()
 
+	service, err := newAgentService(s.config, s.logger, s.wgClient, s.clients, s.sealer)
+	if err != nil {
+		return err
+	}
+
+	// Сверяем хранилище с устройством до приёма запросов
+	service.runReconcile()
+	if s.config.ReconcileInterval > 0 {
+		go service.reconcileLoop(s.ctx, s.config.ReconcileInterval)
+	}
+
+	// Адреса, карантин которых истёк, и подписки, закончившиеся,
+	// пока агент был остановлен
+	service.expireQuarantine()
+	service.expireClients()
+	go service.sweepLoop(s.ctx)
+
 	// Настройка TLS
 	tlsConfig, err := s.setupTLS()
 	if err != nil {
@@ -68,13 +97,7 @@ func (s *Server) Start() error {
 	)
 
 	// Регистрация сервиса
-	proto.RegisterWireGuardAgentServer(grpcServer, newAgentService(
-		s.logger,
-		s.wgClient,
-		s.config.Interface,
-		s.config.Subnet,
-		s.config.ServerEndpoint(),
-	))
+	proto.RegisterWireGuardAgentServer(grpcServer, service)
 
 	s.logger.Info("gRPC server started", "addr", s.config.Addr)
 
@@ -111,6 +134,7 @@ func (s *Server) setupTLS() (*tls.Config, error) {
 // Stop останавливает сервер
 func (s *Server) Stop() error {
 	s.logger.Info("Stopping server")
+	s.cancel()
 
 	if s.httpServer != nil {
 		if err := s.httpServer.Stop(); err != nil {
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/quibex/wg-agent/internal/config"
+	"github.com/quibex/wg-agent/internal/wireguard"
+	proto "github.com/quibex/wg-agent/pkg/api/proto"
+)
+
+// parseClientTemplate собирает шаблон конфига клиента из настроек сервера
+func parseClientTemplate(cfg *config.Config) (wireguard.ClientTemplate, error) {
+	t := wireguard.ClientTemplate{
+		DNS:                 splitList(cfg.ClientDNS),
+		AllowedIPs:          splitList(cfg.ClientAllowedIPs),
+		MTU:                 cfg.ClientMTU,
+		PersistentKeepalive: cfg.ClientKeepalive,
+	}
+	if err := t.Validate(); err != nil {
+		return wireguard.ClientTemplate{}, fmt.Errorf("invalid client config template: %w", err)
+	}
+	return t, nil
+}
+
+// parseSplitTunnels вычисляет AllowedIPs пресетов split-tunnel: весь трафик
+// (и IPv6, если он включён) за вычетом подсетей пресета
+func parseSplitTunnels(cfg *config.Config, ipv6 bool) (map[string][]string, error) {
+	presets, err := cfg.SplitTunnelPresets()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]string, len(presets))
+	for _, p := range presets {
+		routes, err := wireguard.ComplementCIDRs(fullTunnel(ipv6), p.Exclude)
+		if err != nil {
+			return nil, fmt.Errorf("split tunnel %s: %w", p.Name, err)
+		}
+		if len(routes) == 0 {
+			return nil, fmt.Errorf("split tunnel %s excludes all traffic", p.Name)
+		}
+		result[p.Name] = routes
+	}
+	return result, nil
+}
+
+// newClientTemplate шаблон для нового клиента: настройки сервера,
+// переопределённые параметрами запроса. Без маршрутов в настройках и запросе
+// через туннель идёт весь трафик.
+func (s *agentService) newClientTemplate(opts *proto.ClientConfigOptions) (wireguard.ClientTemplate, error) {
+	t := s.template.Clone()
+	if len(opts.GetDns()) > 0 {
+		t.DNS = trimAll(opts.GetDns())
+	}
+	switch name := opts.GetSplitTunnel(); {
+	case name != "" && len(opts.GetAllowedIps()) > 0:
+		return wireguard.ClientTemplate{}, invalidArgument("config_options", "invalid config_options: allowed_ips and split_tunnel are mutually exclusive")
+	case name != "":
+		routes, ok := s.splitTunnels[name]
+		if !ok {
+			return wireguard.ClientTemplate{}, invalidArgument("config_options", fmt.Sprintf("invalid config_options: unknown split_tunnel %q", name))
+		}
+		t.AllowedIPs, t.SplitTunnel = slices.Clone(routes), name
+	case len(opts.GetAllowedIps()) > 0:
+		t.AllowedIPs, t.SplitTunnel = trimAll(opts.GetAllowedIps()), ""
+	}
+	if opts.GetMtu() != 0 {
+		t.MTU = int(opts.GetMtu())
+	}
+	if opts != nil && opts.PersistentKeepalive != nil {
+		t.PersistentKeepalive = int(opts.GetPersistentKeepalive())
+	}
+	if len(opts.GetPostUp()) > 0 {
+		t.PostUp = trimAll(opts.GetPostUp())
+	}
+	if len(t.AllowedIPs) == 0 {
+		t.AllowedIPs = fullTunnel(s.pool6 != nil)
+	}
+	if err := t.Validate(); err != nil {
+		return wireguard.ClientTemplate{}, invalidField("config_options", err)
+	}
+	return t, nil
+}
+
+// clientTemplate шаблон конфига клиента. У клиентов, созданных до шаблонов,
+// берутся текущие настройки сервера.
+func (s *agentService) clientTemplate(client *wireguard.ClientData) wireguard.ClientTemplate {
+	if client.Template != nil {
+		return *client.Template
+	}
+	t := s.template
+	if len(t.AllowedIPs) == 0 {
+		t.AllowedIPs = fullTunnel(client.AllowedIP6 != "")
+	}
+	return t
+}
+
+// fullTunnel маршруты всего трафика через VPN, IPv6 - если он включён
+func fullTunnel(ipv6 bool) []string {
+	if ipv6 {
+		return []string{"0.0.0.0/0", "::/0"}
+	}
+	return []string{"0.0.0.0/0"}
+}
+
+// splitList разбирает список через запятую, пропуская пустые элементы
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func trimAll(items []string) []string {
+	result := make([]string, len(items))
+	for i, item := range items {
+		result[i] = strings.TrimSpace(item)
+	}
+	return result
+}
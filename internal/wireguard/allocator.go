@@ -0,0 +1,317 @@
+package wireguard
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net/netip"
+	"strings"
+	"sync"
+)
+
+// maxPoolSize ограничивает размер пула: из больших подсетей (например IPv6 /64)
+// выдаются только первые maxPoolSize адресов
+const maxPoolSize = 1 << 20
+
+// Ошибки выделения адресов
+var (
+	ErrPoolExhausted = errors.New("no available IPs")
+	ErrIPOutsidePool = errors.New("IP is outside the subnet")
+	ErrIPReserved    = errors.New("IP is reserved")
+	ErrIPInUse       = errors.New("IP is already in use")
+)
+
+// PoolState состояние пула адресов, которое сохраняется вместе с хранилищем.
+// Занятость адресов восстанавливается из клиентов того же снапшота,
+// поэтому отдельно хранится только курсор поиска.
+type PoolState struct {
+	Subnet string `json:"subnet"`
+	Next   string `json:"next,omitempty"` // адрес, с которого начнётся поиск свободного
+}
+
+// IPAllocator выделяет адреса клиентам из подсети. Занятость хранится в
+// битовой карте, поиск свободного адреса идёт от курсора по 64 адреса за шаг,
+// поэтому выделение в среднем O(1) и не зависит от числа клиентов.
+// Адрес сети, первый адрес (шлюз/сервер), broadcast для IPv4 и
+// зарезервированные адреса не выдаются никогда. Безопасен для конкурентного использования.
+type IPAllocator struct {
+	mu       sync.Mutex
+	prefix   netip.Prefix
+	size     uint64   // число адресов в пуле
+	hostBits int      // 32 для IPv4, 128 для IPv6: маска выдаваемого адреса
+	used     []uint64 // выданные адреса
+	reserved []uint64 // адреса, которые не выдаются
+	free     uint64   // число свободных адресов
+	next     uint64   // курсор поиска
+}
+
+// NewIPAllocator создает пул адресов подсети. reserved - адреса и подсети,
+// которые нельзя выдавать; записи вне подсети пула игнорируются.
+func NewIPAllocator(subnet string, reserved []string) (*IPAllocator, error) {
+	prefix, err := netip.ParsePrefix(subnet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subnet %q: %w", subnet, err)
+	}
+	prefix = prefix.Masked()
+
+	a := &IPAllocator{prefix: prefix, hostBits: prefix.Addr().BitLen()}
+	size := uint64(maxPoolSize)
+	host := a.hostBits - prefix.Bits()
+	if host < 20 {
+		size = 1 << host
+	}
+	a.size = size
+
+	words := (size + 63) / 64
+	a.used = make([]uint64, words)
+	a.reserved = make([]uint64, words)
+
+	// Хвост последнего слова за пределами пула
+	for off := size; off < words*64; off++ {
+		setBit(a.reserved, off)
+	}
+	// Адрес сети и первый адрес - сервер/шлюз
+	a.reserveOffset(0)
+	a.reserveOffset(1)
+	// Broadcast в IPv4, если пул покрывает подсеть целиком
+	if prefix.Addr().Is4() && host < 20 {
+		a.reserveOffset(size - 1)
+	}
+
+	for _, entry := range reserved {
+		if err := a.reserve(strings.TrimSpace(entry)); err != nil {
+			return nil, err
+		}
+	}
+
+	for w := range a.used {
+		a.free += uint64(64 - bits.OnesCount64(a.reserved[w]))
+	}
+	return a, nil
+}
+
+// reserve исключает адрес или подсеть из выдачи
+func (a *IPAllocator) reserve(entry string) error {
+	if entry == "" {
+		return nil
+	}
+	if !strings.Contains(entry, "/") {
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return fmt.Errorf("invalid reserved IP %q: %w", entry, err)
+		}
+		entry = netip.PrefixFrom(addr, addr.BitLen()).String()
+	}
+	prefix, err := netip.ParsePrefix(entry)
+	if err != nil {
+		return fmt.Errorf("invalid reserved range %q: %w", entry, err)
+	}
+	prefix = prefix.Masked()
+	if prefix.Addr().Is4() != a.prefix.Addr().Is4() || !a.prefix.Overlaps(prefix) {
+		return nil
+	}
+
+	// Пересечение зарезервированной подсети с пулом
+	first, last := uint64(0), a.size-1
+	if prefix.Bits() > a.prefix.Bits() {
+		start, ok := a.offset(prefix.Addr())
+		if !ok {
+			return nil
+		}
+		first = start
+		if hostBits := prefix.Addr().BitLen() - prefix.Bits(); hostBits < 64 {
+			last = min(last, start+(uint64(1)<<hostBits)-1)
+		}
+	}
+	for off := first; off <= last; off++ {
+		a.reserveOffset(off)
+	}
+	return nil
+}
+
+func (a *IPAllocator) reserveOffset(off uint64) {
+	if off < a.size {
+		setBit(a.reserved, off)
+	}
+}
+
+// Subnet возвращает подсеть пула
+func (a *IPAllocator) Subnet() string {
+	return a.prefix.String()
+}
+
+// Available возвращает число свободных адресов
+func (a *IPAllocator) Available() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.free
+}
+
+// Allocate выделяет свободный адрес и возвращает его с маской хоста
+// ("10.8.0.2/32" или "fd42::2/128")
+func (a *IPAllocator) Allocate() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.free == 0 {
+		return "", fmt.Errorf("%w in subnet %s", ErrPoolExhausted, a.prefix)
+	}
+
+	// Обходим слова от курсора по кругу. Первое слово просматривается дважды:
+	// сначала адреса после курсора, в конце - адреса перед ним.
+	words := uint64(len(a.used))
+	start := a.next / 64
+	for i := uint64(0); i <= words; i++ {
+		w := (start + i) % words
+		avail := ^(a.used[w] | a.reserved[w])
+		if i == 0 {
+			avail &= ^uint64(0) << (a.next % 64)
+		}
+		if avail == 0 {
+			continue
+		}
+		off := w*64 + uint64(bits.TrailingZeros64(avail))
+		setBit(a.used, off)
+		a.free--
+		a.next = (off + 1) % a.size
+		return a.format(off), nil
+	}
+	return "", fmt.Errorf("%w in subnet %s", ErrPoolExhausted, a.prefix)
+}
+
+// Claim занимает конкретный адрес и возвращает его с маской хоста.
+// Адрес сети, сервера и зарезервированные конфигурацией адреса занять нельзя.
+func (a *IPAllocator) Claim(ip string) (string, error) {
+	off, ok := a.parseOffset(ip)
+	if !ok {
+		return "", fmt.Errorf("%w %s: %s", ErrIPOutsidePool, a.prefix, ip)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch {
+	case hasBit(a.reserved, off):
+		return "", fmt.Errorf("%w: %s", ErrIPReserved, ip)
+	case hasBit(a.used, off):
+		return "", fmt.Errorf("%w: %s", ErrIPInUse, ip)
+	}
+	setBit(a.used, off)
+	a.free--
+	return a.format(off), nil
+}
+
+// Mark отмечает адрес (или CIDR клиента) как занятый.
+// Возвращает false, если адрес не принадлежит пулу.
+func (a *IPAllocator) Mark(ip string) bool {
+	off, ok := a.parseOffset(ip)
+	if !ok {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !hasBit(a.used, off) {
+		setBit(a.used, off)
+		if !hasBit(a.reserved, off) {
+			a.free--
+		}
+	}
+	return true
+}
+
+// Release возвращает адрес в пул
+func (a *IPAllocator) Release(ip string) {
+	off, ok := a.parseOffset(ip)
+	if !ok {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if hasBit(a.used, off) {
+		clearBit(a.used, off)
+		if !hasBit(a.reserved, off) {
+			a.free++
+		}
+	}
+}
+
+// HostCIDR приводит адрес пула к виду с маской хоста ("10.8.0.5" → "10.8.0.5/32")
+func (a *IPAllocator) HostCIDR(ip string) (string, bool) {
+	off, ok := a.parseOffset(ip)
+	if !ok {
+		return "", false
+	}
+	return a.format(off), true
+}
+
+// Contains проверяет, принадлежит ли адрес пулу
+func (a *IPAllocator) Contains(ip string) bool {
+	_, ok := a.parseOffset(ip)
+	return ok
+}
+
+// State возвращает состояние пула для сохранения
+func (a *IPAllocator) State() *PoolState {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &PoolState{Subnet: a.Subnet(), Next: a.addr(a.next).String()}
+}
+
+// Restore восстанавливает курсор из сохранённого состояния того же пула
+func (a *IPAllocator) Restore(state *PoolState) {
+	if state == nil || state.Subnet != a.Subnet() {
+		return
+	}
+	if off, ok := a.parseOffset(state.Next); ok {
+		a.mu.Lock()
+		a.next = off
+		a.mu.Unlock()
+	}
+}
+
+// parseOffset разбирает адрес или CIDR и возвращает его номер в пуле
+func (a *IPAllocator) parseOffset(ip string) (uint64, bool) {
+	ip, _, _ = strings.Cut(ip, "/")
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return 0, false
+	}
+	return a.offset(addr.Unmap())
+}
+
+// offset номер адреса в пуле
+func (a *IPAllocator) offset(addr netip.Addr) (uint64, bool) {
+	if !a.prefix.Contains(addr) {
+		return 0, false
+	}
+	base, ip := a.prefix.Addr().As16(), addr.As16()
+	// Пул не больше maxPoolSize, поэтому смещение целиком в младших 64 битах
+	off := binary.BigEndian.Uint64(ip[8:]) - binary.BigEndian.Uint64(base[8:])
+	if binary.BigEndian.Uint64(ip[:8]) != binary.BigEndian.Uint64(base[:8]) || off >= a.size {
+		return 0, false
+	}
+	return off, true
+}
+
+// addr адрес по номеру в пуле
+func (a *IPAllocator) addr(off uint64) netip.Addr {
+	b := a.prefix.Addr().As16()
+	binary.BigEndian.PutUint64(b[8:], binary.BigEndian.Uint64(b[8:])+off)
+	addr := netip.AddrFrom16(b)
+	if a.prefix.Addr().Is4() {
+		addr = addr.Unmap()
+	}
+	return addr
+}
+
+func (a *IPAllocator) format(off uint64) string {
+	return fmt.Sprintf("%s/%d", a.addr(off), a.hostBits)
+}
+
+func setBit(bitmap []uint64, i uint64)   { bitmap[i/64] |= 1 << (i % 64) }
+func clearBit(bitmap []uint64, i uint64) { bitmap[i/64] &^= 1 << (i % 64) }
+func hasBit(bitmap []uint64, i uint64) bool {
+	return bitmap[i/64]&(1<<(i%64)) != 0
+}
@@ -0,0 +1,237 @@
+package wireguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIPAllocator_Exclusions(t *testing.T) {
+	a, err := NewIPAllocator("10.8.0.0/29", []string{"10.8.0.3", "10.8.0.4/31", "fd42::/64"})
+	if err != nil {
+		t.Fatalf("NewIPAllocator() error = %v", err)
+	}
+
+	// .0 сеть, .1 сервер, .3-.5 зарезервированы, .7 broadcast
+	var got []string
+	for {
+		ip, err := a.Allocate()
+		if err != nil {
+			break
+		}
+		got = append(got, ip)
+	}
+	want := []string{"10.8.0.2/32", "10.8.0.6/32"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("allocated %v, want %v", got, want)
+	}
+	if a.Available() != 0 {
+		t.Errorf("Available() = %d, want 0", a.Available())
+	}
+
+	// Освобождённый адрес выдаётся снова
+	a.Release("10.8.0.2/32")
+	if ip, err := a.Allocate(); err != nil || ip != "10.8.0.2/32" {
+		t.Errorf("Allocate() after release = %q, %v", ip, err)
+	}
+}
+
+func TestIPAllocator_CursorAndRestore(t *testing.T) {
+	a, _ := NewIPAllocator("10.8.0.0/24", nil)
+	first, _ := a.Allocate()
+	second, _ := a.Allocate()
+	a.Release(first)
+
+	// Курсор не возвращается к только что освобождённому адресу
+	if ip, _ := a.Allocate(); ip == first {
+		t.Errorf("Allocate() reused %s right after release", first)
+	}
+
+	b, _ := NewIPAllocator("10.8.0.0/24", nil)
+	b.Restore(a.State())
+	b.Mark(second)
+	if ip, _ := b.Allocate(); ip != "10.8.0.5/32" {
+		t.Errorf("Allocate() after restore = %s, want 10.8.0.5/32", ip)
+	}
+}
+
+func TestIPAllocator_IPv6(t *testing.T) {
+	a, err := NewIPAllocator("fd42:42:42::/64", []string{"fd42:42:42::2"})
+	if err != nil {
+		t.Fatalf("NewIPAllocator() error = %v", err)
+	}
+	if a.Available() != maxPoolSize-3 {
+		t.Errorf("Available() = %d, want %d", a.Available(), maxPoolSize-3)
+	}
+	if ip, _ := a.Allocate(); ip != "fd42:42:42::3/128" {
+		t.Errorf("Allocate() = %s, want fd42:42:42::3/128", ip)
+	}
+	if a.Mark("10.8.0.2/32") || a.Contains("fd42:42:43::5") {
+		t.Error("addresses outside the pool must be ignored")
+	}
+}
+
+func TestIPAllocator_Concurrent(t *testing.T) {
+	a, _ := NewIPAllocator("10.8.0.0/22", nil)
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ip, err := a.Allocate()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				if seen[ip] {
+					t.Errorf("%s allocated twice", ip)
+				}
+				seen[ip] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestClientStore_AttachAllocator(t *testing.T) {
+	storage, err := NewFileStorage(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("NewFileStorage() error = %v", err)
+	}
+	store, _ := NewPersistentClientStore(storage)
+	a, _ := NewIPAllocator("10.8.0.0/24", nil)
+	store.AttachAllocator(a)
+
+	ip, _ := a.Allocate()
+	if err := store.Add(&ClientData{UserID: "alice", PublicKey: "a", AllowedIP: ip, Enabled: true}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Add(&ClientData{UserID: "bob", PublicKey: "b", AllowedIP: "10.8.0.9/32", Enabled: true}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if _, err := a.Claim("10.8.0.3"); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if err := store.AddReservation(&Reservation{IP: "10.8.0.3/32", UserID: "carol"}); err != nil {
+		t.Fatalf("AddReservation() error = %v", err)
+	}
+
+	// После перезапуска занятость восстанавливается из клиентов и резервирований,
+	// курсор - из снапшота
+	reopened, _ := NewPersistentClientStore(storage)
+	b, _ := NewIPAllocator("10.8.0.0/24", nil)
+	reopened.AttachAllocator(b)
+	if b.Available() != a.Available() {
+		t.Errorf("Available() after reopen = %d, want %d", b.Available(), a.Available())
+	}
+	if next, _ := b.Allocate(); next != "10.8.0.4/32" {
+		t.Errorf("Allocate() after reopen = %s, want 10.8.0.4/32", next)
+	}
+	if _, ok := reopened.GetReservation("10.8.0.3/32"); !ok {
+		t.Error("reservation should survive reopen")
+	}
+
+	before := b.Available()
+	if err := reopened.Delete("bob"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if b.Available() != before+1 {
+		t.Error("Delete() should release the client's address")
+	}
+}
+
+func TestClientStore_Quarantine(t *testing.T) {
+	storage, err := NewFileStorage(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("NewFileStorage() error = %v", err)
+	}
+	store, _ := NewPersistentClientStore(storage)
+	store.SetQuarantine(time.Hour)
+	a, _ := NewIPAllocator("10.8.0.0/24", nil)
+	store.AttachAllocator(a)
+
+	if err := store.Add(&ClientData{UserID: "alice", PublicKey: "a", AllowedIP: "10.8.0.2/32", Enabled: true}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	before := a.Available()
+	if err := store.Delete("alice"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if a.Available() != before {
+		t.Error("Delete() must not release a quarantined address")
+	}
+
+	// Карантин переживает перезапуск и удерживает адрес в новом пуле
+	reopened, _ := NewPersistentClientStore(storage)
+	b, _ := NewIPAllocator("10.8.0.0/24", nil)
+	reopened.AttachAllocator(b)
+	q, ok := reopened.GetQuarantine("10.8.0.2/32")
+	if !ok || q.UserID != "alice" {
+		t.Fatalf("GetQuarantine() after reopen = %v, %v", q, ok)
+	}
+	if _, err := b.Claim("10.8.0.2"); err == nil {
+		t.Error("quarantined address must stay taken after reopen")
+	}
+
+	if released, _ := reopened.ExpireQuarantine(q.Until.Add(-time.Second)); len(released) != 0 {
+		t.Errorf("ExpireQuarantine() before deadline released %v", released)
+	}
+	released, err := reopened.ExpireQuarantine(q.Until)
+	if err != nil || len(released) != 1 {
+		t.Fatalf("ExpireQuarantine() = %v, %v", released, err)
+	}
+	if _, err := b.Claim("10.8.0.2"); err != nil {
+		t.Errorf("Claim() after expiry error = %v", err)
+	}
+	if _, err := reopened.ReleaseQuarantine("10.8.0.2/32"); !errors.Is(err, ErrNotQuarantined) {
+		t.Errorf("ReleaseQuarantine() error = %v, want ErrNotQuarantined", err)
+	}
+}
+
+// benchUsed занятых адресов: сравнение с линейным AllocateIP на /16, заполненной наполовину
+const benchUsed = 32000
+
+func BenchmarkAllocateIP_Linear(b *testing.B) {
+	used := make([]string, 0, benchUsed)
+	ip := net.ParseIP("10.8.0.2").To4()
+	for i := 0; i < benchUsed; i++ {
+		used = append(used, ip.String())
+		inc(ip)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := AllocateIP("10.8.0.0/16", used); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIPAllocator_Allocate(b *testing.B) {
+	a, _ := NewIPAllocator("10.8.0.0/16", nil)
+	for i := 0; i < benchUsed; i++ {
+		if _, err := a.Allocate(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ip, err := a.Allocate()
+		if err != nil {
+			b.Fatal(err)
+		}
+		a.Release(ip)
+	}
+}
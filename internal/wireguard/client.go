@@ -1,7 +1,10 @@
 package wireguard
 
 import (
+	"errors"
+	"fmt"
 	"net"
+	"regexp"
 
 	"golang.zx2c4.com/wireguard/wgctrl"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
@@ -54,3 +57,91 @@ func ValidateAllowedIP(ipStr string) error {
 	_, _, err := net.ParseCIDR(ipStr)
 	return err
 }
+
+// ValidateDNS проверяет адрес DNS сервера для конфига клиента
+func ValidateDNS(dns string) error {
+	if net.ParseIP(dns) == nil {
+		return fmt.Errorf("invalid DNS server %q", dns)
+	}
+	return nil
+}
+
+// Ограничения имени туннеля и меток клиента
+const (
+	maxLabels        = 32
+	maxLabelKeyLen   = 63
+	maxLabelValueLen = 256
+)
+
+// tunnelNameRe имя туннеля в приложениях WireGuard: оно же имя интерфейса
+// в wg-quick, поэтому не длиннее 15 символов
+var tunnelNameRe = regexp.MustCompile(`^[a-zA-Z0-9_=+.-]{1,15}$`)
+
+// deviceIDRe device_id устройства пользователя
+var deviceIDRe = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,32}$`)
+
+// labelKeyRe ключ метки: буквы, цифры и "_.-/"
+var labelKeyRe = regexp.MustCompile(`^[a-zA-Z0-9_./-]+$`)
+
+// ValidateTunnelName проверяет имя туннеля
+func ValidateTunnelName(name string) error {
+	if !tunnelNameRe.MatchString(name) {
+		return fmt.Errorf("invalid tunnel name %q: 1-15 characters a-z, A-Z, 0-9, _=+.-", name)
+	}
+	return nil
+}
+
+// ValidateDeviceID проверяет device_id устройства пользователя
+func ValidateDeviceID(id string) error {
+	if !deviceIDRe.MatchString(id) {
+		return fmt.Errorf("invalid device_id %q: 1-32 characters a-z, A-Z, 0-9, _.-", id)
+	}
+	return nil
+}
+
+// ValidateLabels проверяет метки клиента
+func ValidateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("too many labels: %d, max %d", len(labels), maxLabels)
+	}
+	for k, v := range labels {
+		if len(k) > maxLabelKeyLen || !labelKeyRe.MatchString(k) {
+			return fmt.Errorf("invalid label key %q", k)
+		}
+		if len(v) > maxLabelValueLen {
+			return fmt.Errorf("label %s value is longer than %d", k, maxLabelValueLen)
+		}
+	}
+	return nil
+}
+
+// SplitAllowedIPs выбирает из AllowedIPs пира первый IPv4 и первый IPv6 адрес
+func SplitAllowedIPs(cidrs []string) (v4, v6 string, err error) {
+	for _, cidr := range cidrs {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return "", "", err
+		}
+		switch {
+		case ip.To4() != nil && v4 == "":
+			v4 = cidr
+		case ip.To4() == nil && v6 == "":
+			v6 = cidr
+		}
+	}
+	if v4 == "" && v6 == "" {
+		return "", "", errors.New("no allowed IPs")
+	}
+	return v4, v6, nil
+}
+
+// JoinAllowedIPs собирает непустые IPv4 и IPv6 адреса клиента в список
+func JoinAllowedIPs(v4, v6 string) []string {
+	ips := make([]string, 0, 2)
+	for _, ip := range []string{v4, v6} {
+		if ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
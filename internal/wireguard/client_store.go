@@ -1,102 +1,522 @@
 package wireguard
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
+	"time"
+)
+
+// Ошибки хранилища клиентов
+var (
+	ErrClientNotFound = errors.New("client not found")
+	ErrClientExists   = errors.New("client already exists")
+	ErrPublicKeyInUse = errors.New("public key is already used by another client")
 )
 
 // ClientData хранит информацию о клиенте VPN
 type ClientData struct {
-	UserID     string // ID пользователя из внешней системы
-	PublicKey  string // публичный ключ клиента
-	PrivateKey string // приватный ключ клиента (для генерации конфига)
-	AllowedIP  string // выделенный IP (например "10.8.0.10/32")
-	Enabled    bool   // включен/отключен
+	UserID     string `json:"user_id"`               // ID пользователя из внешней системы
+	DeviceID   string `json:"device_id,omitempty"`   // ID устройства пользователя, пусто у основного (созданного CreateClient)
+	PublicKey  string `json:"public_key"`            // публичный ключ клиента
+	PrivateKey string `json:"private_key"`           // приватный ключ клиента (для генерации конфига), зашифрован KEK если он задан
+	AllowedIP  string `json:"allowed_ip"`            // выделенный IP (например "10.8.0.10/32")
+	AllowedIP6 string `json:"allowed_ip6,omitempty"` // выделенный IPv6 (например "fd42::a/128"), пусто без IPv6
+	Pool       string `json:"pool,omitempty"`        // пул адресов (тариф), пусто у клиентов, созданных до пулов
+	Enabled    bool   `json:"enabled"`               // включен/отключен
+
+	PresharedKey string `json:"preshared_key,omitempty"` // preshared key пира, зашифрован KEK если он задан
+
+	Template *ClientTemplate `json:"template,omitempty"` // параметры конфига клиента, nil - настройки сервера
+
+	TunnelName string            `json:"tunnel_name,omitempty"` // имя туннеля в приложении и имя файла конфига
+	Labels     map[string]string `json:"labels,omitempty"`      // произвольные метки для отображения и фильтрации
+
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // срок подписки (у основного устройства), nil - бессрочно
+}
+
+// Режимы ключей клиента
+const (
+	KeyModeServer = "server" // ключи сгенерированы агентом, приватный ключ хранится на сервере
+	KeyModeClient = "client" // клиент прислал свой публичный ключ, приватного ключа на сервере нет
+)
+
+// ID возвращает идентификатор записи для логов: user_id или user_id/device_id
+func (c *ClientData) ID() string {
+	if c.DeviceID == "" {
+		return c.UserID
+	}
+	return c.UserID + "/" + c.DeviceID
+}
+
+// storeKey ключ клиента в хранилище. Основное устройство хранится под
+// user_id, поэтому записи, созданные до устройств, остаются на месте.
+func storeKey(userID, deviceID string) string {
+	if deviceID == "" {
+		return userID
+	}
+	return userID + "\x00" + deviceID
+}
+
+// key возвращает ключ клиента в хранилище
+func (c *ClientData) key() string {
+	return storeKey(c.UserID, c.DeviceID)
+}
+
+// KeyMode возвращает режим ключей клиента
+func (c *ClientData) KeyMode() string {
+	if c.PrivateKey == "" {
+		return KeyModeClient
+	}
+	return KeyModeServer
+}
+
+// HasLabels проверяет, что у клиента есть все метки selector с теми же значениями
+func (c *ClientData) HasLabels(selector map[string]string) bool {
+	for k, v := range selector {
+		if got, ok := c.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowedIPs возвращает все адреса клиента: IPv4 и IPv6, если они выделены
+func (c *ClientData) AllowedIPs() []string {
+	return JoinAllowedIPs(c.AllowedIP, c.AllowedIP6)
 }
 
-// ClientStore хранит состояние клиентов в памяти
-// TODO: в будущем можно сделать персистентное хранилище
+// clone возвращает независимую копию клиента
+func (c *ClientData) clone() *ClientData {
+	cp := *c
+	if c.Template != nil {
+		t := c.Template.Clone()
+		cp.Template = &t
+	}
+	cp.Labels = maps.Clone(c.Labels)
+	if c.ExpiresAt != nil {
+		t := *c.ExpiresAt
+		cp.ExpiresAt = &t
+	}
+	return &cp
+}
+
+// ClientStore хранит состояние клиентов в памяти.
+// Если задан Storage, каждое изменение синхронно сохраняется в него,
+// а при ошибке сохранения изменение откатывается.
 type ClientStore struct {
-	mu      sync.RWMutex
-	clients map[string]*ClientData // ключ - user_id
+	mu         sync.RWMutex
+	clients    map[string]*ClientData // ключ - storeKey(user_id, device_id)
+	storage    Storage                // nil - только память
+	allocators []*IPAllocator         // пулы адресов, занятость которых ведёт хранилище
+	pools      map[string]*PoolState  // сохранённые состояния пулов по подсети
+
+	reservations  map[string]*Reservation   // ключ - адрес с маской хоста
+	quarantine    map[string]*QuarantinedIP // освобождённые адреса на карантине
+	quarantineFor time.Duration             // срок карантина, 0 - адреса освобождаются сразу
+
+	idempotency    map[string]*IdempotencyKey // ключи идемпотентности CreateClient
+	idempotencyTTL time.Duration              // сколько помнить ключи, 0 - не помнить
 }
 
-// NewClientStore создает новый ClientStore
+// NewClientStore создает новый ClientStore без персистентности
 func NewClientStore() *ClientStore {
 	return &ClientStore{
 		clients: make(map[string]*ClientData),
+		pools:   make(map[string]*PoolState),
+
+		reservations: make(map[string]*Reservation),
+		quarantine:   make(map[string]*QuarantinedIP),
+		idempotency:  make(map[string]*IdempotencyKey),
 	}
 }
 
-// Add добавляет клиента
-func (cs *ClientStore) Add(client *ClientData) {
+// NewPersistentClientStore создает ClientStore поверх storage и загружает сохранённое состояние
+func NewPersistentClientStore(storage Storage) (*ClientStore, error) {
+	cs := NewClientStore()
+	cs.storage = storage
+
+	snapshot, err := storage.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client store: %w", err)
+	}
+	if snapshot != nil {
+		for _, c := range snapshot.Clients {
+			cs.clients[c.key()] = c
+		}
+		for _, p := range snapshot.Pools {
+			cs.pools[p.Subnet] = p
+		}
+		for _, r := range snapshot.Reservations {
+			cs.reservations[r.IP] = r
+		}
+		for _, q := range snapshot.Quarantine {
+			cs.quarantine[q.IP] = q
+		}
+		for _, k := range snapshot.IdempotencyKeys {
+			cs.idempotency[k.Key] = k
+		}
+	}
+	return cs, nil
+}
+
+// AttachAllocator подключает пул адресов: занятость пула восстанавливается
+// из клиентов хранилища и дальше обновляется при Add/Delete, а курсор пула
+// сохраняется вместе с клиентами.
+func (cs *ClientStore) AttachAllocator(a *IPAllocator) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
-	cs.clients[client.UserID] = client
+
+	a.Restore(cs.pools[a.Subnet()])
+	for _, c := range cs.clients {
+		for _, ip := range c.AllowedIPs() {
+			a.Mark(ip)
+		}
+	}
+	for ip := range cs.reservations {
+		a.Mark(ip)
+	}
+	for ip := range cs.quarantine {
+		a.Mark(ip)
+	}
+	cs.allocators = append(cs.allocators, a)
+}
+
+// moveAllocationsLocked освобождает адреса prev и занимает адреса next
+// в подключенных пулах (prev или next может быть nil), вызывается под cs.mu.
+// При quarantine освобождённые адреса уходят в карантин, если он включён.
+// Зарезервированные и находящиеся в карантине адреса остаются занятыми.
+func (cs *ClientStore) moveAllocationsLocked(prev, next *ClientData, quarantine bool) {
+	kept := make(map[string]bool)
+	if next != nil {
+		for _, ip := range next.AllowedIPs() {
+			kept[ip] = true
+			delete(cs.quarantine, ip)
+			for _, a := range cs.allocators {
+				a.Mark(ip)
+			}
+		}
+	}
+	if prev == nil {
+		return
+	}
+	for _, ip := range prev.AllowedIPs() {
+		if kept[ip] {
+			continue
+		}
+		if quarantine && cs.quarantineFor > 0 {
+			cs.quarantineLocked(ip, prev.UserID)
+		}
+		if cs.heldLocked(ip) {
+			continue
+		}
+		for _, a := range cs.allocators {
+			a.Release(ip)
+		}
+	}
+}
+
+// heldLocked проверяет, удерживается ли адрес резервированием или карантином
+func (cs *ClientStore) heldLocked(ip string) bool {
+	_, reserved := cs.reservations[ip]
+	_, quarantined := cs.quarantine[ip]
+	return reserved || quarantined
 }
 
-// Get возвращает клиента по user_id
+// persistLocked сохраняет текущее состояние в storage, вызывается под cs.mu
+func (cs *ClientStore) persistLocked() error {
+	if cs.storage == nil {
+		return nil
+	}
+
+	snapshot := &Snapshot{
+		Clients: make([]*ClientData, 0, len(cs.clients)),
+	}
+	for _, c := range cs.clients {
+		snapshot.Clients = append(snapshot.Clients, c)
+	}
+
+	// Состояния неподключенных пулов (например, при запуске CLI) не теряем
+	attached := make(map[string]bool, len(cs.allocators))
+	for _, a := range cs.allocators {
+		attached[a.Subnet()] = true
+		snapshot.Pools = append(snapshot.Pools, a.State())
+	}
+	for subnet, p := range cs.pools {
+		if !attached[subnet] {
+			snapshot.Pools = append(snapshot.Pools, p)
+		}
+	}
+	for _, r := range cs.reservations {
+		snapshot.Reservations = append(snapshot.Reservations, r)
+	}
+	for _, q := range cs.quarantine {
+		snapshot.Quarantine = append(snapshot.Quarantine, q)
+	}
+	for _, k := range cs.idempotency {
+		snapshot.IdempotencyKeys = append(snapshot.IdempotencyKeys, k)
+	}
+
+	if err := cs.storage.Save(snapshot); err != nil {
+		return fmt.Errorf("failed to persist client store: %w", err)
+	}
+	return nil
+}
+
+// Add добавляет или заменяет клиента. Публичный ключ не должен
+// принадлежать другому клиенту.
+func (cs *ClientStore) Add(client *ClientData) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.putLocked(client)
+}
+
+// Create добавляет нового клиента или устройство: проверка и запись
+// выполняются атомарно, поэтому из параллельных Create одного user_id
+// (устройства) успешен только один
+func (cs *ClientStore) Create(client *ClientData) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if _, exists := cs.clients[client.key()]; exists {
+		return fmt.Errorf("%w: %s", ErrClientExists, client.ID())
+	}
+	return cs.putLocked(client)
+}
+
+// putLocked записывает клиента и сохраняет состояние, при ошибке
+// сохранения всё откатывается. Публичный ключ и адреса не должны
+// принадлежать другому клиенту. Вызывается под cs.mu
+func (cs *ClientStore) putLocked(client *ClientData) error {
+	ips := client.AllowedIPs()
+	for _, c := range cs.clients {
+		if c.key() == client.key() {
+			continue
+		}
+		if client.PublicKey != "" && c.PublicKey == client.PublicKey {
+			return fmt.Errorf("%w: %s", ErrPublicKeyInUse, c.ID())
+		}
+		for _, ip := range c.AllowedIPs() {
+			if slices.Contains(ips, ip) {
+				return fmt.Errorf("%w: %s is held by %s", ErrIPInUse, ip, c.ID())
+			}
+		}
+	}
+
+	key := client.key()
+	prev, existed := cs.clients[key]
+	quarantine := maps.Clone(cs.quarantine)
+	cs.clients[key] = client.clone()
+	cs.moveAllocationsLocked(prev, client, true)
+
+	if err := cs.persistLocked(); err != nil {
+		cs.quarantine = quarantine
+		cs.moveAllocationsLocked(client, prev, false)
+		if existed {
+			cs.clients[key] = prev
+		} else {
+			delete(cs.clients, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Get возвращает основное устройство клиента по user_id
 func (cs *ClientStore) Get(userID string) (*ClientData, bool) {
+	return cs.GetDevice(userID, "")
+}
+
+// GetDevice возвращает устройство пользователя, пустой deviceID - основное
+func (cs *ClientStore) GetDevice(userID, deviceID string) (*ClientData, bool) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 
-	client, exists := cs.clients[userID]
+	client, exists := cs.clients[storeKey(userID, deviceID)]
 	if !exists {
 		return nil, false
 	}
 
 	// Возвращаем копию
-	return &ClientData{
-		UserID:     client.UserID,
-		PublicKey:  client.PublicKey,
-		PrivateKey: client.PrivateKey,
-		AllowedIP:  client.AllowedIP,
-		Enabled:    client.Enabled,
-	}, true
+	return client.clone(), true
+}
+
+// ListDevices возвращает все устройства пользователя: основное первым,
+// остальные по device_id
+func (cs *ClientStore) ListDevices(userID string) []*ClientData {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	var devices []*ClientData
+	for _, c := range cs.clients {
+		if c.UserID == userID {
+			devices = append(devices, c.clone())
+		}
+	}
+	slices.SortFunc(devices, func(a, b *ClientData) int {
+		return strings.Compare(a.DeviceID, b.DeviceID)
+	})
+	return devices
+}
+
+// GetByIP возвращает клиента, которому выделен адрес ip (с маской хоста)
+func (cs *ClientStore) GetByIP(ip string) (*ClientData, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	for _, c := range cs.clients {
+		if slices.Contains(c.AllowedIPs(), ip) {
+			return c.clone(), true
+		}
+	}
+	return nil, false
+}
+
+// GetByPublicKey возвращает клиента по публичному ключу
+func (cs *ClientStore) GetByPublicKey(publicKey string) (*ClientData, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	for _, c := range cs.clients {
+		if c.PublicKey == publicKey {
+			return c.clone(), true
+		}
+	}
+	return nil, false
+}
+
+// Delete удаляет клиента со всеми его устройствами
+func (cs *ClientStore) Delete(userID string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	return cs.deleteLocked(func(c *ClientData) bool { return c.UserID == userID })
 }
 
-// Delete удаляет клиента
-func (cs *ClientStore) Delete(userID string) bool {
+// DeleteDevice удаляет одно устройство пользователя
+func (cs *ClientStore) DeleteDevice(userID, deviceID string) error {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 
-	if _, exists := cs.clients[userID]; exists {
-		delete(cs.clients, userID)
-		return true
+	key := storeKey(userID, deviceID)
+	return cs.deleteLocked(func(c *ClientData) bool { return c.key() == key })
+}
+
+// deleteLocked удаляет клиентов, подходящих под match, одной записью.
+// Вызывается под cs.mu
+func (cs *ClientStore) deleteLocked(match func(*ClientData) bool) error {
+	removed := make(map[string]*ClientData)
+	for key, c := range cs.clients {
+		if match(c) {
+			removed[key] = c
+		}
+	}
+	if len(removed) == 0 {
+		return ErrClientNotFound
+	}
+
+	quarantine := maps.Clone(cs.quarantine)
+	for key, prev := range removed {
+		delete(cs.clients, key)
+		cs.moveAllocationsLocked(prev, nil, true)
 	}
-	return false
+
+	if err := cs.persistLocked(); err != nil {
+		cs.quarantine = quarantine
+		for key, prev := range removed {
+			cs.moveAllocationsLocked(nil, prev, false)
+			cs.clients[key] = prev
+		}
+		return err
+	}
+	return nil
 }
 
-// SetEnabled включает/отключает клиента
-func (cs *ClientStore) SetEnabled(userID string, enabled bool) bool {
+// SetEnabled включает/отключает все устройства клиента
+func (cs *ClientStore) SetEnabled(userID string, enabled bool) error {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 
-	if client, exists := cs.clients[userID]; exists {
-		client.Enabled = enabled
-		return true
+	prev := make(map[*ClientData]bool)
+	for _, c := range cs.clients {
+		if c.UserID == userID {
+			prev[c] = c.Enabled
+			c.Enabled = enabled
+		}
+	}
+	if len(prev) == 0 {
+		return ErrClientNotFound
+	}
+
+	if err := cs.persistLocked(); err != nil {
+		for c, enabled := range prev {
+			c.Enabled = enabled
+		}
+		return err
+	}
+	return nil
+}
+
+// ResealKeys перешифровывает приватные и preshared ключи клиентов текущим KEK
+// sealer'а (включая ключи, сохранённые в открытом виде). Изменения применяются
+// одной записью: при любой ошибке хранилище не меняется.
+func (cs *ClientStore) ResealKeys(sealer *KeySealer) (int, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	resealed := make(map[string]*ClientData)
+	for key, c := range cs.clients {
+		if !sealer.NeedsReseal(c.PrivateKey) && !sealer.NeedsReseal(c.PresharedKey) {
+			continue
+		}
+		updated := c.clone()
+		for _, field := range []*string{&updated.PrivateKey, &updated.PresharedKey} {
+			if !sealer.NeedsReseal(*field) {
+				continue
+			}
+			value, err := sealer.Reseal(*field)
+			if err != nil {
+				return 0, fmt.Errorf("client %s: %w", c.ID(), err)
+			}
+			*field = value
+		}
+		resealed[key] = updated
+	}
+	if len(resealed) == 0 {
+		return 0, nil
+	}
+
+	prev := make(map[string]*ClientData, len(resealed))
+	for key, updated := range resealed {
+		prev[key] = cs.clients[key]
+		cs.clients[key] = updated
+	}
+
+	if err := cs.persistLocked(); err != nil {
+		for key, c := range prev {
+			cs.clients[key] = c
+		}
+		return 0, err
 	}
-	return false
+	return len(resealed), nil
 }
 
-// List возвращает список всех клиентов
+// List возвращает список всех клиентов, включая дополнительные устройства
 func (cs *ClientStore) List() []*ClientData {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 
 	clients := make([]*ClientData, 0, len(cs.clients))
 	for _, c := range cs.clients {
-		clients = append(clients, &ClientData{
-			UserID:     c.UserID,
-			PublicKey:  c.PublicKey,
-			PrivateKey: c.PrivateKey,
-			AllowedIP:  c.AllowedIP,
-			Enabled:    c.Enabled,
-		})
+		clients = append(clients, c.clone())
 	}
 	return clients
 }
 
-// Exists проверяет существует ли клиент
+// Exists проверяет существует ли клиент (его основное устройство)
 func (cs *ClientStore) Exists(userID string) bool {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
@@ -111,12 +531,11 @@ func (cs *ClientStore) GetUsedIPs() []string {
 
 	ips := make([]string, 0, len(cs.clients))
 	for _, c := range cs.clients {
-		// Убираем маску для сравнения
-		ip := c.AllowedIP
-		if len(ip) > 3 && ip[len(ip)-3:] == "/32" {
-			ip = ip[:len(ip)-3]
+		for _, cidr := range c.AllowedIPs() {
+			// Убираем маску для сравнения
+			ip, _, _ := strings.Cut(cidr, "/")
+			ips = append(ips, ip)
 		}
-		ips = append(ips, ip)
 	}
 	return ips
 }
@@ -0,0 +1,243 @@
+package wireguard
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// failingStorage storage, который всегда возвращает ошибку при сохранении
+type failingStorage struct{}
+
+func (failingStorage) Load() (*Snapshot, error) { return nil, nil }
+func (failingStorage) Save(*Snapshot) error     { return errors.New("disk full") }
+
+func TestPersistentClientStore_SurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	storage, err := NewFileStorage(path)
+	if err != nil {
+		t.Fatalf("NewFileStorage() error = %v", err)
+	}
+	store, err := NewPersistentClientStore(storage)
+	if err != nil {
+		t.Fatalf("NewPersistentClientStore() error = %v", err)
+	}
+
+	if err := store.Add(&ClientData{UserID: "alice", PublicKey: "pub-a", AllowedIP: "10.8.0.2/32", Enabled: true}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Add(&ClientData{UserID: "bob", PublicKey: "pub-b", AllowedIP: "10.8.0.3/32", Enabled: true}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.SetEnabled("alice", false); err != nil {
+		t.Fatalf("SetEnabled() error = %v", err)
+	}
+	if err := store.Delete("bob"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	reopened, err := NewPersistentClientStore(storage)
+	if err != nil {
+		t.Fatalf("NewPersistentClientStore() reopen error = %v", err)
+	}
+
+	alice, ok := reopened.Get("alice")
+	if !ok {
+		t.Fatal("alice not found after reopen")
+	}
+	if alice.Enabled || alice.AllowedIP != "10.8.0.2/32" {
+		t.Errorf("alice = %+v, want disabled with 10.8.0.2/32", alice)
+	}
+	if reopened.Exists("bob") {
+		t.Error("bob should stay deleted after reopen")
+	}
+}
+
+func TestClientStore_RollbackOnSaveError(t *testing.T) {
+	store := NewClientStore()
+	if err := store.Add(&ClientData{UserID: "alice", Enabled: true}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	store.storage = failingStorage{}
+
+	if err := store.Add(&ClientData{UserID: "bob"}); err == nil {
+		t.Error("Add() expected error")
+	}
+	if store.Exists("bob") {
+		t.Error("bob should not be added when save fails")
+	}
+
+	if err := store.SetEnabled("alice", false); err == nil {
+		t.Error("SetEnabled() expected error")
+	}
+	if alice, _ := store.Get("alice"); !alice.Enabled {
+		t.Error("alice should stay enabled when save fails")
+	}
+
+	if err := store.Delete("alice"); err == nil {
+		t.Error("Delete() expected error")
+	}
+	if !store.Exists("alice") {
+		t.Error("alice should not be deleted when save fails")
+	}
+
+	if err := store.Delete("nobody"); !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("Delete() error = %v, want ErrClientNotFound", err)
+	}
+}
+
+func TestClientStore_IdempotencyKeys(t *testing.T) {
+	storage, err := NewFileStorage(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("NewFileStorage() error = %v", err)
+	}
+	store, err := NewPersistentClientStore(storage)
+	if err != nil {
+		t.Fatalf("NewPersistentClientStore() error = %v", err)
+	}
+
+	// Без срока хранения ключи не запоминаются
+	if err := store.AddIdempotencyKey("op-0", "alice"); err != nil {
+		t.Fatalf("AddIdempotencyKey() error = %v", err)
+	}
+	if _, ok := store.GetIdempotencyKey("op-0"); ok {
+		t.Error("key remembered with zero TTL")
+	}
+
+	store.SetIdempotencyTTL(time.Hour)
+	if err := store.AddIdempotencyKey("op-1", "alice"); err != nil {
+		t.Fatalf("AddIdempotencyKey() error = %v", err)
+	}
+
+	reopened, err := NewPersistentClientStore(storage)
+	if err != nil {
+		t.Fatalf("NewPersistentClientStore() reopen error = %v", err)
+	}
+	if k, ok := reopened.GetIdempotencyKey("op-1"); !ok || k.UserID != "alice" {
+		t.Fatalf("GetIdempotencyKey() after reopen = %+v, %v", k, ok)
+	}
+
+	if n, err := reopened.ExpireIdempotencyKeys(time.Now()); err != nil || n != 0 {
+		t.Errorf("ExpireIdempotencyKeys(now) = %d, %v, want 0", n, err)
+	}
+	if n, err := reopened.ExpireIdempotencyKeys(time.Now().Add(2 * time.Hour)); err != nil || n != 1 {
+		t.Errorf("ExpireIdempotencyKeys(+2h) = %d, %v, want 1", n, err)
+	}
+	if _, ok := reopened.GetIdempotencyKey("op-1"); ok {
+		t.Error("expired key is still returned")
+	}
+}
+
+func TestClientStore_Devices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	storage, err := NewFileStorage(path)
+	if err != nil {
+		t.Fatalf("NewFileStorage() error = %v", err)
+	}
+	store, err := NewPersistentClientStore(storage)
+	if err != nil {
+		t.Fatalf("NewPersistentClientStore() error = %v", err)
+	}
+
+	for _, c := range []*ClientData{
+		{UserID: "alice", PublicKey: "pub-a", AllowedIP: "10.8.0.2/32", Enabled: true},
+		{UserID: "alice", DeviceID: "phone", PublicKey: "pub-a-phone", AllowedIP: "10.8.0.3/32", Enabled: true},
+		{UserID: "bob", PublicKey: "pub-b", AllowedIP: "10.8.0.4/32", Enabled: true},
+	} {
+		if err := store.Create(c); err != nil {
+			t.Fatalf("Create(%s) error = %v", c.ID(), err)
+		}
+	}
+	if err := store.Create(&ClientData{UserID: "alice", DeviceID: "phone", PublicKey: "pub-x"}); !errors.Is(err, ErrClientExists) {
+		t.Errorf("Create() duplicate device error = %v, want ErrClientExists", err)
+	}
+	if err := store.Create(&ClientData{UserID: "alice", DeviceID: "tablet", PublicKey: "pub-a"}); !errors.Is(err, ErrPublicKeyInUse) {
+		t.Errorf("Create() with primary's key error = %v, want ErrPublicKeyInUse", err)
+	}
+	if err := store.Add(&ClientData{UserID: "carol", PublicKey: "pub-c", AllowedIP: "10.8.0.4/32"}); !errors.Is(err, ErrIPInUse) {
+		t.Errorf("Add() with bob's address error = %v, want ErrIPInUse", err)
+	}
+
+	if err := store.SetEnabled("alice", false); err != nil {
+		t.Fatalf("SetEnabled() error = %v", err)
+	}
+
+	// Устройства переживают перезапуск
+	store, err = NewPersistentClientStore(storage)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	devices := store.ListDevices("alice")
+	if len(devices) != 2 || devices[0].DeviceID != "" || devices[1].DeviceID != "phone" {
+		t.Fatalf("ListDevices() = %v", devices)
+	}
+	for _, d := range devices {
+		if d.Enabled {
+			t.Errorf("device %s is enabled after SetEnabled(false)", d.ID())
+		}
+	}
+	if c, ok := store.Get("alice"); !ok || c.PublicKey != "pub-a" {
+		t.Errorf("Get() = %v, %v, want primary device", c, ok)
+	}
+
+	if err := store.DeleteDevice("alice", "phone"); err != nil {
+		t.Fatalf("DeleteDevice() error = %v", err)
+	}
+	if _, ok := store.GetDevice("alice", "phone"); ok {
+		t.Error("device still exists after DeleteDevice")
+	}
+	if err := store.Delete("bob"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got := len(store.List()); got != 1 {
+		t.Errorf("store has %d clients, want 1", got)
+	}
+}
+
+func TestClientStore_ExpiresAt(t *testing.T) {
+	storage, err := NewFileStorage(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("NewFileStorage() error = %v", err)
+	}
+	store, err := NewPersistentClientStore(storage)
+	if err != nil {
+		t.Fatalf("NewPersistentClientStore() error = %v", err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Hour)
+	for _, c := range []*ClientData{
+		{UserID: "alice", PublicKey: "pub-a", AllowedIP: "10.8.0.2/32", Enabled: true, ExpiresAt: &expiresAt},
+		{UserID: "alice", DeviceID: "phone", PublicKey: "pub-a-phone", AllowedIP: "10.8.0.3/32", Enabled: true},
+		{UserID: "bob", PublicKey: "pub-b", AllowedIP: "10.8.0.4/32", Enabled: true},
+	} {
+		if err := store.Add(c); err != nil {
+			t.Fatalf("Add(%s) error = %v", c.ID(), err)
+		}
+	}
+	if err := store.SetExpiresAt("carol", &expiresAt); !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("SetExpiresAt(unknown) error = %v, want ErrClientNotFound", err)
+	}
+
+	// Срок переживает перезапуск
+	store, err = NewPersistentClientStore(storage)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	if expired := store.ListExpired(now); len(expired) != 0 {
+		t.Errorf("ListExpired(now) = %v, want none", expired)
+	}
+	expired := store.ListExpired(now.Add(2 * time.Hour))
+	if len(expired) != 1 || expired[0].UserID != "alice" || expired[0].DeviceID != "" {
+		t.Errorf("ListExpired(now+2h) = %v, want alice's primary device", expired)
+	}
+
+	if err := store.SetExpiresAt("alice", nil); err != nil {
+		t.Fatalf("SetExpiresAt(nil) error = %v", err)
+	}
+	if expired := store.ListExpired(now.Add(2 * time.Hour)); len(expired) != 0 {
+		t.Errorf("ListExpired() after clearing expiry = %v", expired)
+	}
+}
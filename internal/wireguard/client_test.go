@@ -84,3 +84,48 @@ func TestValidateAllowedIP(t *testing.T) {
 		})
 	}
 }
+
+func TestAllocateIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		subnet  string
+		used    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "first IPv4", subnet: "10.8.0.0/24", want: "10.8.0.1/32"},
+		{name: "skip used IPv4", subnet: "10.8.0.0/24", used: []string{"10.8.0.1", "10.8.0.2"}, want: "10.8.0.3/32"},
+		{name: "first IPv6", subnet: "fd42:42:42::/64", want: "fd42:42:42::1/128"},
+		{name: "skip used IPv6", subnet: "fd42:42:42::/64", used: []string{"10.8.0.1", "fd42:42:42::1"}, want: "fd42:42:42::2/128"},
+		{name: "exhausted", subnet: "10.8.0.0/31", used: []string{"10.8.0.1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AllocateIP(tt.subnet, tt.used)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AllocateIP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("AllocateIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitAllowedIPs(t *testing.T) {
+	v4, v6, err := SplitAllowedIPs([]string{"fd42::3/128", "10.8.0.3/32", "10.9.0.0/24"})
+	if err != nil {
+		t.Fatalf("SplitAllowedIPs() error = %v", err)
+	}
+	if v4 != "10.8.0.3/32" || v6 != "fd42::3/128" {
+		t.Errorf("SplitAllowedIPs() = %q, %q", v4, v6)
+	}
+
+	if _, _, err := SplitAllowedIPs(nil); err == nil {
+		t.Error("SplitAllowedIPs(nil) expected error")
+	}
+	if _, _, err := SplitAllowedIPs([]string{"garbage"}); err == nil {
+		t.Error("SplitAllowedIPs(garbage) expected error")
+	}
+}
@@ -1,10 +1,9 @@
 package wireguard
 
 import (
-	"encoding/base64"
 	"fmt"
 	"net"
-	"os/exec"
+	"slices"
 	"strings"
 
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
@@ -19,50 +18,119 @@ func GenerateKeyPair() (privateKey, publicKey string, err error) {
 	return privKey.String(), privKey.PublicKey().String(), nil
 }
 
-// GenerateClientConfig создает конфигурацию для клиента WireGuard
-func GenerateClientConfig(clientPrivateKey, serverPublicKey, serverEndpoint, allowedIPs, dnsServers, clientIP string) string {
-	config := fmt.Sprintf(`[Interface]
-PrivateKey = %s
-Address = %s
-DNS = %s
-
-[Peer]
-PublicKey = %s
-AllowedIPs = %s
-Endpoint = %s
-PersistentKeepalive = 25
-`, clientPrivateKey, clientIP, dnsServers, serverPublicKey, allowedIPs, serverEndpoint)
-
-	return config
+// PrivateKeyPlaceholder подставляется вместо приватного ключа в шаблон конфига,
+// когда ключи сгенерировал сам клиент
+const PrivateKeyPlaceholder = "<YOUR_PRIVATE_KEY>"
+
+// ClientConfigParams параметры конфигурации клиента WireGuard
+type ClientConfigParams struct {
+	Name                string   // имя туннеля: имя файла и профиля
+	ID                  string   // стабильный идентификатор клиента, из него выводятся UUID профилей
+	PrivateKey          string   // приватный ключ клиента (или PrivateKeyPlaceholder)
+	Address             string   // адрес клиента в туннеле ("10.8.0.10/32")
+	DNS                 string   // DNS серверы через запятую, пусто - без DNS
+	MTU                 int      // 0 - MTU по умолчанию
+	PostUp              []string // команды PostUp, по строке на команду
+	ServerPublicKey     string
+	PresharedKey        string // пусто - без preshared key
+	AllowedIPs          string // что маршрутизировать через туннель
+	Endpoint            string // host:port сервера
+	PersistentKeepalive int    // секунды, 0 - без keepalive
 }
 
-// GenerateQRCode создает QR код из конфигурации (возвращает base64)
-func GenerateQRCode(config string) (string, error) {
-	// Проверяем доступность qrencode
-	if _, err := exec.LookPath("qrencode"); err != nil {
-		return "", fmt.Errorf("qrencode not found: %w", err)
+// GenerateClientConfig создает конфигурацию для клиента WireGuard
+func GenerateClientConfig(p ClientConfigParams) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "[Interface]\nPrivateKey = %s\nAddress = %s\n", p.PrivateKey, p.Address)
+	if p.DNS != "" {
+		fmt.Fprintf(&b, "DNS = %s\n", p.DNS)
+	}
+	if p.MTU > 0 {
+		fmt.Fprintf(&b, "MTU = %d\n", p.MTU)
+	}
+	for _, cmd := range p.PostUp {
+		fmt.Fprintf(&b, "PostUp = %s\n", cmd)
+	}
+
+	fmt.Fprintf(&b, "\n[Peer]\nPublicKey = %s\n", p.ServerPublicKey)
+	if p.PresharedKey != "" {
+		fmt.Fprintf(&b, "PresharedKey = %s\n", p.PresharedKey)
+	}
+	fmt.Fprintf(&b, "AllowedIPs = %s\nEndpoint = %s\n", p.AllowedIPs, p.Endpoint)
+	if p.PersistentKeepalive > 0 {
+		fmt.Fprintf(&b, "PersistentKeepalive = %d\n", p.PersistentKeepalive)
 	}
 
-	// Создаем QR код
-	cmd := exec.Command("qrencode", "-t", "PNG", "-o", "-")
-	cmd.Stdin = strings.NewReader(config)
+	return b.String()
+}
 
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to generate QR code: %w", err)
+// Допустимые значения MTU и keepalive в конфиге клиента
+const (
+	minClientMTU       = 576
+	maxClientMTU       = 9000
+	maxClientKeepalive = 65535
+)
+
+// ClientTemplate параметры конфига клиента помимо ключей и адресов.
+// Значения по умолчанию задаёт сервер, при создании клиента их можно
+// переопределить. Шаблон сохраняется вместе с клиентом, поэтому повторная
+// выдача конфига даёт тот же файл даже после смены настроек сервера.
+type ClientTemplate struct {
+	DNS                 []string `json:"dns,omitempty"`                  // DNS серверы, пусто - без DNS
+	AllowedIPs          []string `json:"allowed_ips,omitempty"`          // маршруты через туннель
+	SplitTunnel         string   `json:"split_tunnel,omitempty"`         // пресет, из которого вычислены AllowedIPs
+	MTU                 int      `json:"mtu,omitempty"`                  // 0 - MTU по умолчанию
+	PersistentKeepalive int      `json:"persistent_keepalive,omitempty"` // секунды, 0 - без keepalive
+	PostUp              []string `json:"post_up,omitempty"`              // подсказки PostUp для wg-quick
+}
+
+// Validate проверяет параметры шаблона
+func (t ClientTemplate) Validate() error {
+	for _, dns := range t.DNS {
+		if err := ValidateDNS(dns); err != nil {
+			return err
+		}
+	}
+	for _, cidr := range t.AllowedIPs {
+		if err := ValidateAllowedIP(cidr); err != nil {
+			return fmt.Errorf("invalid allowed IP %q: %w", cidr, err)
+		}
+	}
+	if t.MTU != 0 && (t.MTU < minClientMTU || t.MTU > maxClientMTU) {
+		return fmt.Errorf("MTU must be between %d and %d", minClientMTU, maxClientMTU)
+	}
+	if t.PersistentKeepalive < 0 || t.PersistentKeepalive > maxClientKeepalive {
+		return fmt.Errorf("persistent keepalive must be between 0 and %d", maxClientKeepalive)
+	}
+	for _, cmd := range t.PostUp {
+		// Команда - одна строка конфига, перевод строки позволил бы дописать произвольные секции
+		if strings.TrimSpace(cmd) == "" || strings.ContainsAny(cmd, "\r\n") {
+			return fmt.Errorf("invalid PostUp command %q", cmd)
+		}
 	}
+	return nil
+}
 
-	// Кодируем в base64
-	return base64.StdEncoding.EncodeToString(output), nil
+// Clone возвращает независимую копию шаблона
+func (t ClientTemplate) Clone() ClientTemplate {
+	t.DNS = slices.Clone(t.DNS)
+	t.AllowedIPs = slices.Clone(t.AllowedIPs)
+	t.PostUp = slices.Clone(t.PostUp)
+	return t
 }
 
-// GenerateWireGuardLink создает ссылку для автоматического подключения
-func GenerateWireGuardLink(config string) string {
-	encoded := base64.StdEncoding.EncodeToString([]byte(config))
-	return fmt.Sprintf("wireguard://tunnels/add/%s", encoded)
+// GeneratePresharedKey создает новый preshared key WireGuard
+func GeneratePresharedKey() (string, error) {
+	key, err := wgtypes.GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	return key.String(), nil
 }
 
-// AllocateIP выделяет свободный IP адрес из подсети
+// AllocateIP выделяет свободный IP адрес из подсети линейным перебором.
+// Агент выдаёт адреса через IPAllocator, функция оставлена для разовых вызовов.
 func AllocateIP(subnet string, usedIPs []string) (string, error) {
 	_, ipNet, err := net.ParseCIDR(subnet)
 	if err != nil {
@@ -75,11 +143,16 @@ func AllocateIP(subnet string, usedIPs []string) (string, error) {
 		used[ip] = true
 	}
 
+	// Клиенту выдаётся один адрес: /32 для IPv4, /128 для IPv6
+	bits := 32
+	if ipNet.IP.To4() == nil {
+		bits = 128
+	}
+
 	// Итерируемся по подсети
 	for ip := ipNet.IP.Mask(ipNet.Mask); ipNet.Contains(ip); inc(ip) {
 		if !used[ip.String()] && !ip.Equal(ipNet.IP) {
-			// Возвращаем IP с маской /32 для клиента
-			return fmt.Sprintf("%s/32", ip.String()), nil
+			return fmt.Sprintf("%s/%d", ip.String(), bits), nil
 		}
 	}
 
@@ -0,0 +1,79 @@
+package wireguard
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClientTemplate_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template ClientTemplate
+		wantErr  bool
+	}{
+		{
+			name: "valid",
+			template: ClientTemplate{
+				DNS:                 []string{"1.1.1.1", "2606:4700:4700::1111"},
+				AllowedIPs:          []string{"10.0.0.0/8", "fd00::/8"},
+				MTU:                 1420,
+				PersistentKeepalive: 25,
+				PostUp:              []string{"ip route add 192.168.1.0/24 dev eth0"},
+			},
+		},
+		{name: "empty", template: ClientTemplate{}},
+		{name: "DNS with mask", template: ClientTemplate{DNS: []string{"1.1.1.1/32"}}, wantErr: true},
+		{name: "DNS hostname", template: ClientTemplate{DNS: []string{"dns.example.com"}}, wantErr: true},
+		{name: "allowed IP without mask", template: ClientTemplate{AllowedIPs: []string{"10.0.0.0"}}, wantErr: true},
+		{name: "MTU too small", template: ClientTemplate{MTU: 100}, wantErr: true},
+		{name: "negative keepalive", template: ClientTemplate{PersistentKeepalive: -1}, wantErr: true},
+		{name: "PostUp with newline", template: ClientTemplate{PostUp: []string{"true\n[Peer]"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.template.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateClientConfig(t *testing.T) {
+	got := GenerateClientConfig(ClientConfigParams{
+		PrivateKey:          "priv",
+		Address:             "10.8.0.2/32",
+		DNS:                 "1.1.1.1",
+		MTU:                 1380,
+		PostUp:              []string{"echo up"},
+		ServerPublicKey:     "server",
+		AllowedIPs:          "10.0.0.0/8",
+		Endpoint:            "vpn.example.com:51820",
+		PersistentKeepalive: 15,
+	})
+	want := `[Interface]
+PrivateKey = priv
+Address = 10.8.0.2/32
+DNS = 1.1.1.1
+MTU = 1380
+PostUp = echo up
+
+[Peer]
+PublicKey = server
+AllowedIPs = 10.0.0.0/8
+Endpoint = vpn.example.com:51820
+PersistentKeepalive = 15
+`
+	if got != want {
+		t.Errorf("GenerateClientConfig() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Без DNS, MTU и keepalive строки не выводятся
+	minimal := GenerateClientConfig(ClientConfigParams{PrivateKey: "priv", Address: "10.8.0.2/32"})
+	for _, key := range []string{"DNS", "MTU", "PostUp", "PersistentKeepalive"} {
+		if strings.Contains(minimal, key) {
+			t.Errorf("minimal config contains %s:\n%s", key, minimal)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package wireguard
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DeepLinkScheme формат ссылки для импорта туннеля в приложение
+type DeepLinkScheme string
+
+const (
+	// DeepLinkWireGuardV1 прежний формат агента: wireguard://tunnels/add/<base64>,
+	// стандартный base64 без имени туннеля. Формат по умолчанию для совместимости.
+	DeepLinkWireGuardV1 DeepLinkScheme = "wireguard-v1"
+	// DeepLinkWireGuard wireguard://tunnels/add/<base64url>?v=2&name=<имя>[&z=1]:
+	// URL-safe base64 без паддинга, z=1 - конфиг сжат zlib.
+	// Официальные приложения WireGuard ссылки не обрабатывают, схема рассчитана
+	// на свои клиенты и обработчики бота.
+	DeepLinkWireGuard DeepLinkScheme = "wireguard"
+	// DeepLinkAmnezia vpn://<base64url> - ключ AmneziaVPN: JSON конфига с
+	// контейнером amnezia-wireguard, сжатый как qCompress (длина + zlib)
+	DeepLinkAmnezia DeepLinkScheme = "amnezia"
+)
+
+// deepLinkVersion версия формата DeepLinkWireGuard
+const deepLinkVersion = "2"
+
+// DeepLinkOptions параметры ссылки для импорта
+type DeepLinkOptions struct {
+	Scheme   DeepLinkScheme
+	Compress bool // сжать конфиг (для DeepLinkAmnezia сжатие есть всегда)
+}
+
+// DefaultDeepLinkOptions возвращает параметры по умолчанию: прежний формат wireguard://
+func DefaultDeepLinkOptions() DeepLinkOptions {
+	return DeepLinkOptions{Scheme: DeepLinkWireGuardV1}
+}
+
+// Validate проверяет параметры ссылки
+func (o DeepLinkOptions) Validate() error {
+	switch o.Scheme {
+	case DeepLinkWireGuardV1:
+		if o.Compress {
+			return fmt.Errorf("deep link scheme %s does not support compression", o.Scheme)
+		}
+	case DeepLinkWireGuard, DeepLinkAmnezia:
+	default:
+		return fmt.Errorf("unknown deep link scheme %q (expected wireguard-v1, wireguard or amnezia)", o.Scheme)
+	}
+	return nil
+}
+
+// BuildDeepLink собирает ссылку для импорта туннеля в формате opts.Scheme
+func BuildDeepLink(p ClientConfigParams, opts DeepLinkOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	config := GenerateClientConfig(p)
+
+	switch opts.Scheme {
+	case DeepLinkWireGuard:
+		payload := []byte(config)
+		query := url.Values{"v": {deepLinkVersion}, "name": {p.tunnelName()}}
+		if opts.Compress {
+			var err error
+			if payload, err = zlibCompress(payload); err != nil {
+				return "", err
+			}
+			query.Set("z", "1")
+		}
+		return "wireguard://tunnels/add/" + base64.RawURLEncoding.EncodeToString(payload) + "?" + query.Encode(), nil
+	case DeepLinkAmnezia:
+		return amneziaLink(p, config)
+	default:
+		return GenerateWireGuardLink(config), nil
+	}
+}
+
+// GenerateWireGuardLink создает ссылку прежнего формата (DeepLinkWireGuardV1)
+func GenerateWireGuardLink(config string) string {
+	encoded := base64.StdEncoding.EncodeToString([]byte(config))
+	return fmt.Sprintf("wireguard://tunnels/add/%s", encoded)
+}
+
+// amneziaConfig ключ AmneziaVPN с одним контейнером WireGuard
+type amneziaConfig struct {
+	Containers       []amneziaContainer `json:"containers"`
+	DefaultContainer string             `json:"defaultContainer"`
+	Description      string             `json:"description"`
+	DNS1             string             `json:"dns1,omitempty"`
+	DNS2             string             `json:"dns2,omitempty"`
+	HostName         string             `json:"hostName"`
+}
+
+type amneziaContainer struct {
+	Container string `json:"container"`
+	WireGuard struct {
+		LastConfig     string `json:"last_config"` // JSON amneziaLastConfig строкой
+		Port           string `json:"port"`
+		TransportProto string `json:"transport_proto"`
+	} `json:"wireguard"`
+}
+
+type amneziaLastConfig struct {
+	Config              string   `json:"config"`
+	HostName            string   `json:"hostName"`
+	Port                int      `json:"port"`
+	ClientIP            string   `json:"client_ip"`
+	ClientPrivateKey    string   `json:"client_priv_key"`
+	ServerPublicKey     string   `json:"server_pub_key"`
+	PresharedKey        string   `json:"psk_key,omitempty"`
+	AllowedIPs          []string `json:"allowed_ips"`
+	MTU                 string   `json:"mtu,omitempty"`
+	PersistentKeepalive string   `json:"persistent_keep_alive,omitempty"`
+}
+
+func amneziaLink(p ClientConfigParams, config string) (string, error) {
+	host, portStr, err := net.SplitHostPort(p.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %q: %w", p.Endpoint, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint port %q", portStr)
+	}
+
+	last := amneziaLastConfig{
+		Config:           config,
+		HostName:         host,
+		Port:             port,
+		ClientPrivateKey: p.PrivateKey,
+		ServerPublicKey:  p.ServerPublicKey,
+		PresharedKey:     p.PresharedKey,
+		AllowedIPs:       splitConfigList(p.AllowedIPs),
+	}
+	if addrs := splitConfigList(p.Address); len(addrs) > 0 {
+		last.ClientIP, _, _ = strings.Cut(addrs[0], "/")
+	}
+	if p.MTU > 0 {
+		last.MTU = strconv.Itoa(p.MTU)
+	}
+	if p.PersistentKeepalive > 0 {
+		last.PersistentKeepalive = strconv.Itoa(p.PersistentKeepalive)
+	}
+	lastJSON, err := json.Marshal(last)
+	if err != nil {
+		return "", err
+	}
+
+	container := amneziaContainer{Container: "amnezia-wireguard"}
+	container.WireGuard.LastConfig = string(lastJSON)
+	container.WireGuard.Port = portStr
+	container.WireGuard.TransportProto = "udp"
+	c := amneziaConfig{
+		Containers:       []amneziaContainer{container},
+		DefaultContainer: container.Container,
+		Description:      p.tunnelName(),
+		HostName:         host,
+	}
+	if dns := splitConfigList(p.DNS); len(dns) > 0 {
+		c.DNS1 = dns[0]
+		if len(dns) > 1 {
+			c.DNS2 = dns[1]
+		}
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	// qCompress: длина несжатых данных (uint32 big-endian) и поток zlib
+	compressed, err := zlibCompress(data)
+	if err != nil {
+		return "", err
+	}
+	payload := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(compressed)), uint32(len(data)))
+	payload = append(payload, compressed...)
+	return "vpn://" + base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+func zlibCompress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
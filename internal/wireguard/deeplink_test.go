@@ -0,0 +1,128 @@
+package wireguard
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "перезаписать golden-файлы в testdata")
+
+func TestBuildDeepLink_Golden(t *testing.T) {
+	p := testConfigParams()
+
+	tests := []struct {
+		name string
+		opts DeepLinkOptions
+	}{
+		{"wireguard-v1", DefaultDeepLinkOptions()},
+		{"wireguard", DeepLinkOptions{Scheme: DeepLinkWireGuard}},
+		{"wireguard-compressed", DeepLinkOptions{Scheme: DeepLinkWireGuard, Compress: true}},
+		{"amnezia", DeepLinkOptions{Scheme: DeepLinkAmnezia}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link, err := BuildDeepLink(p, tt.opts)
+			if err != nil {
+				t.Fatalf("BuildDeepLink() error = %v", err)
+			}
+
+			golden := filepath.Join("testdata", "deeplink", tt.name+".golden")
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, []byte(link+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden (run with -update to create): %v", err)
+			}
+			if link != strings.TrimSuffix(string(want), "\n") {
+				t.Errorf("BuildDeepLink() =\n%s\nwant\n%s", link, want)
+			}
+		})
+	}
+}
+
+func TestBuildDeepLink_Decode(t *testing.T) {
+	p := testConfigParams()
+	config := GenerateClientConfig(p)
+
+	for _, compress := range []bool{false, true} {
+		link, _ := BuildDeepLink(p, DeepLinkOptions{Scheme: DeepLinkWireGuard, Compress: compress})
+		u, err := url.Parse(link)
+		if err != nil {
+			t.Fatalf("url.Parse() error = %v", err)
+		}
+		if u.Scheme != "wireguard" || u.Host != "tunnels" || u.Query().Get("v") != "2" || u.Query().Get("name") != "wg0" {
+			t.Errorf("link = %s", link)
+		}
+		payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(u.Path, "/add/"))
+		if err != nil {
+			t.Fatalf("payload is not base64url: %v", err)
+		}
+		if u.Query().Get("z") == "1" {
+			payload = inflate(t, payload)
+		}
+		if string(payload) != config {
+			t.Errorf("compress=%v: decoded config =\n%s\nwant\n%s", compress, payload, config)
+		}
+	}
+
+	// vpn:// - qCompress (длина + zlib) от JSON с конфигом внутри last_config
+	link, _ := BuildDeepLink(p, DeepLinkOptions{Scheme: DeepLinkAmnezia})
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(link, "vpn://"))
+	if err != nil {
+		t.Fatalf("payload is not base64url: %v", err)
+	}
+	data := inflate(t, payload[4:])
+	if int(binary.BigEndian.Uint32(payload[:4])) != len(data) {
+		t.Errorf("qCompress length = %d, want %d", binary.BigEndian.Uint32(payload[:4]), len(data))
+	}
+	var c amneziaConfig
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	var last amneziaLastConfig
+	if err := json.Unmarshal([]byte(c.Containers[0].WireGuard.LastConfig), &last); err != nil {
+		t.Fatalf("last_config: %v", err)
+	}
+	if c.HostName != "vpn.example.com" || c.Description != "wg0" || last.Config != config || last.ClientIP != "10.8.0.2" {
+		t.Errorf("amnezia config = %+v, last_config = %+v", c, last)
+	}
+}
+
+func TestDeepLinkOptions_Validate(t *testing.T) {
+	if err := (DeepLinkOptions{Scheme: "tg"}).Validate(); err == nil {
+		t.Error("unknown scheme expected error")
+	}
+	if err := (DeepLinkOptions{Scheme: DeepLinkWireGuardV1, Compress: true}).Validate(); err == nil {
+		t.Error("compressed v1 link expected error")
+	}
+}
+
+func inflate(t *testing.T, data []byte) []byte {
+	t.Helper()
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("zlib: %v", err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("zlib: %v", err)
+	}
+	return out
+}
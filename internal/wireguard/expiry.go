@@ -0,0 +1,47 @@
+package wireguard
+
+import "time"
+
+// Expired сообщает, что срок подписки клиента наступил к моменту at
+func (c *ClientData) Expired(at time.Time) bool {
+	return c.ExpiresAt != nil && !c.ExpiresAt.After(at)
+}
+
+// SetExpiresAt задает срок подписки клиента (nil - бессрочно).
+// Срок хранится у основного устройства и действует на все устройства.
+func (cs *ClientStore) SetExpiresAt(userID string, expiresAt *time.Time) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	client, exists := cs.clients[storeKey(userID, "")]
+	if !exists {
+		return ErrClientNotFound
+	}
+
+	prev := client.ExpiresAt
+	client.ExpiresAt = nil
+	if expiresAt != nil {
+		t := expiresAt.UTC()
+		client.ExpiresAt = &t
+	}
+
+	if err := cs.persistLocked(); err != nil {
+		client.ExpiresAt = prev
+		return err
+	}
+	return nil
+}
+
+// ListExpired возвращает включенных клиентов, срок подписки которых наступил к моменту at
+func (cs *ClientStore) ListExpired(at time.Time) []*ClientData {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	var expired []*ClientData
+	for _, c := range cs.clients {
+		if c.DeviceID == "" && c.Enabled && c.Expired(at) {
+			expired = append(expired, c.clone())
+		}
+	}
+	return expired
+}
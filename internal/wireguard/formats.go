@@ -0,0 +1,307 @@
+package wireguard
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ConfigFormat формат конфига клиента
+type ConfigFormat string
+
+const (
+	ConfigFormatWGQuick         ConfigFormat = "wg-quick"           // .conf для wg-quick и приложений WireGuard
+	ConfigFormatJSON            ConfigFormat = "json"               // структурированный конфиг для бэкендов
+	ConfigFormatMobileConfig    ConfigFormat = "mobileconfig"       // профиль iOS/iPadOS для приложения WireGuard
+	ConfigFormatMobileConfigMac ConfigFormat = "mobileconfig-macos" // профиль macOS для приложения WireGuard
+	ConfigFormatNetworkManager  ConfigFormat = "nmconnection"       // keyfile NetworkManager (Linux)
+)
+
+// ConfigArtifact конфиг клиента в одном из форматов
+type ConfigArtifact struct {
+	Format      ConfigFormat
+	Filename    string // предлагаемое имя файла
+	ContentType string
+	Content     []byte
+}
+
+// ConfigRenderer собирает конфиг клиента в своём формате
+type ConfigRenderer func(p ClientConfigParams) (*ConfigArtifact, error)
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = map[ConfigFormat]ConfigRenderer{
+		ConfigFormatWGQuick:         renderWGQuick,
+		ConfigFormatJSON:            renderJSON,
+		ConfigFormatMobileConfig:    mobileConfigRenderer("com.wireguard.ios"),
+		ConfigFormatMobileConfigMac: mobileConfigRenderer("com.wireguard.macos"),
+		ConfigFormatNetworkManager:  renderNetworkManager,
+	}
+)
+
+// RegisterConfigRenderer добавляет или заменяет рендерер формата
+func RegisterConfigRenderer(format ConfigFormat, r ConfigRenderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	renderers[format] = r
+}
+
+// ConfigFormats возвращает зарегистрированные форматы по алфавиту
+func ConfigFormats() []ConfigFormat {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+
+	formats := make([]ConfigFormat, 0, len(renderers))
+	for f := range renderers {
+		formats = append(formats, f)
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i] < formats[j] })
+	return formats
+}
+
+// RenderClientConfig собирает конфиг клиента в формате format
+func RenderClientConfig(format ConfigFormat, p ClientConfigParams) (*ConfigArtifact, error) {
+	renderersMu.RLock()
+	r, ok := renderers[format]
+	renderersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown config format %q", format)
+	}
+	artifact, err := r(p)
+	if err != nil {
+		return nil, fmt.Errorf("render %s: %w", format, err)
+	}
+	artifact.Format = format
+	return artifact, nil
+}
+
+// tunnelName имя туннеля для файлов и профилей
+func (p ClientConfigParams) tunnelName() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return "wireguard"
+}
+
+// splitConfigList разбирает список конфига wg-quick ("a, b") в элементы
+func splitConfigList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// stableUUID UUID, выведенный из строк: повторная выдача профиля даёт те же идентификаторы
+func stableUUID(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	sum[6] = sum[6]&0x0f | 0x50 // версия 5
+	sum[8] = sum[8]&0x3f | 0x80 // вариант RFC 4122
+	return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16]))
+}
+
+func renderWGQuick(p ClientConfigParams) (*ConfigArtifact, error) {
+	return &ConfigArtifact{
+		Filename:    p.tunnelName() + ".conf",
+		ContentType: "text/plain; charset=utf-8",
+		Content:     []byte(GenerateClientConfig(p)),
+	}, nil
+}
+
+// jsonConfig конфиг клиента в формате json
+type jsonConfig struct {
+	Name      string `json:"name"`
+	Interface struct {
+		PrivateKey string   `json:"private_key"`
+		Addresses  []string `json:"addresses"`
+		DNS        []string `json:"dns,omitempty"`
+		MTU        int      `json:"mtu,omitempty"`
+		PostUp     []string `json:"post_up,omitempty"`
+	} `json:"interface"`
+	Peer struct {
+		PublicKey           string   `json:"public_key"`
+		PresharedKey        string   `json:"preshared_key,omitempty"`
+		AllowedIPs          []string `json:"allowed_ips"`
+		Endpoint            string   `json:"endpoint"`
+		PersistentKeepalive int      `json:"persistent_keepalive,omitempty"`
+	} `json:"peer"`
+}
+
+func renderJSON(p ClientConfigParams) (*ConfigArtifact, error) {
+	var c jsonConfig
+	c.Name = p.tunnelName()
+	c.Interface.PrivateKey = p.PrivateKey
+	c.Interface.Addresses = splitConfigList(p.Address)
+	c.Interface.DNS = splitConfigList(p.DNS)
+	c.Interface.MTU = p.MTU
+	c.Interface.PostUp = p.PostUp
+	c.Peer.PublicKey = p.ServerPublicKey
+	c.Peer.PresharedKey = p.PresharedKey
+	c.Peer.AllowedIPs = splitConfigList(p.AllowedIPs)
+	c.Peer.Endpoint = p.Endpoint
+	c.Peer.PersistentKeepalive = p.PersistentKeepalive
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigArtifact{
+		Filename:    p.tunnelName() + ".json",
+		ContentType: "application/json",
+		Content:     append(data, '\n'),
+	}, nil
+}
+
+// mobileConfigRenderer профиль конфигурации Apple с туннелем приложения WireGuard.
+// subtype - bundle id приложения: com.wireguard.ios или com.wireguard.macos.
+// Профиль не подписан, подписывается при необходимости на стороне бота.
+func mobileConfigRenderer(subtype string) ConfigRenderer {
+	return func(p ClientConfigParams) (*ConfigArtifact, error) {
+		name := p.tunnelName()
+		esc := func(s string) string {
+			var b strings.Builder
+			_ = xml.EscapeText(&b, []byte(s))
+			return b.String()
+		}
+
+		var b bytes.Buffer
+		fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>PayloadDisplayName</key>
+	<string>%[1]s</string>
+	<key>PayloadType</key>
+	<string>Configuration</string>
+	<key>PayloadVersion</key>
+	<integer>1</integer>
+	<key>PayloadIdentifier</key>
+	<string>com.wireguard.agent.%[2]s</string>
+	<key>PayloadUUID</key>
+	<string>%[2]s</string>
+	<key>PayloadContent</key>
+	<array>
+		<dict>
+			<key>PayloadDisplayName</key>
+			<string>VPN</string>
+			<key>PayloadType</key>
+			<string>com.apple.vpn.managed</string>
+			<key>PayloadVersion</key>
+			<integer>1</integer>
+			<key>PayloadIdentifier</key>
+			<string>com.wireguard.agent.%[2]s.%[3]s</string>
+			<key>PayloadUUID</key>
+			<string>%[3]s</string>
+			<key>UserDefinedName</key>
+			<string>%[1]s</string>
+			<key>VPNType</key>
+			<string>VPN</string>
+			<key>VPNSubType</key>
+			<string>%[4]s</string>
+			<key>VendorConfig</key>
+			<dict>
+				<key>WgQuickConfig</key>
+				<string>%[5]s</string>
+			</dict>
+			<key>VPN</key>
+			<dict>
+				<key>RemoteAddress</key>
+				<string>%[6]s</string>
+				<key>AuthenticationMethod</key>
+				<string>Password</string>
+			</dict>
+		</dict>
+	</array>
+</dict>
+</plist>
+`, esc(name), stableUUID("profile", subtype, p.ID, name), stableUUID("tunnel", subtype, p.ID, name),
+			subtype, esc(GenerateClientConfig(p)), esc(p.Endpoint))
+
+		return &ConfigArtifact{
+			Filename:    name + ".mobileconfig",
+			ContentType: "application/x-apple-aspen-config",
+			Content:     b.Bytes(),
+		}, nil
+	}
+}
+
+// renderNetworkManager keyfile NetworkManager для nmcli connection import
+// или /etc/NetworkManager/system-connections (права 0600)
+func renderNetworkManager(p ClientConfigParams) (*ConfigArtifact, error) {
+	name := p.tunnelName()
+	// Имя интерфейса Linux - не длиннее 15 символов
+	iface := name
+	if len(iface) > 15 {
+		iface = iface[:15]
+	}
+
+	var v4, v6 []string
+	for _, addr := range splitConfigList(p.Address) {
+		prefix, err := netip.ParsePrefix(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", addr, err)
+		}
+		if prefix.Addr().Is4() {
+			v4 = append(v4, addr)
+		} else {
+			v6 = append(v6, addr)
+		}
+	}
+	var dns4, dns6 []string
+	for _, dns := range splitConfigList(p.DNS) {
+		if addr, err := netip.ParseAddr(dns); err == nil && addr.Is6() {
+			dns6 = append(dns6, dns)
+		} else {
+			dns4 = append(dns4, dns)
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "[connection]\nid=%s\nuuid=%s\ntype=wireguard\ninterface-name=%s\nautoconnect=false\n\n",
+		name, strings.ToLower(stableUUID("nm", p.ID, name)), iface)
+
+	fmt.Fprintf(&b, "[wireguard]\nprivate-key=%s\n", p.PrivateKey)
+	if p.MTU > 0 {
+		fmt.Fprintf(&b, "mtu=%d\n", p.MTU)
+	}
+
+	fmt.Fprintf(&b, "\n[wireguard-peer.%s]\nendpoint=%s\n", p.ServerPublicKey, p.Endpoint)
+	if p.PresharedKey != "" {
+		fmt.Fprintf(&b, "preshared-key=%s\npreshared-key-flags=0\n", p.PresharedKey)
+	}
+	if p.PersistentKeepalive > 0 {
+		fmt.Fprintf(&b, "persistent-keepalive=%d\n", p.PersistentKeepalive)
+	}
+	fmt.Fprintf(&b, "allowed-ips=%s;\n", strings.Join(splitConfigList(p.AllowedIPs), ";"))
+
+	writeIP := func(section string, addrs, dns []string) {
+		fmt.Fprintf(&b, "\n[%s]\n", section)
+		if len(addrs) == 0 {
+			b.WriteString("method=disabled\n")
+			return
+		}
+		for i, addr := range addrs {
+			fmt.Fprintf(&b, "address%d=%s\n", i+1, addr)
+		}
+		if len(dns) > 0 {
+			fmt.Fprintf(&b, "dns=%s;\n", strings.Join(dns, ";"))
+		}
+		b.WriteString("method=manual\n")
+	}
+	writeIP("ipv4", v4, dns4)
+	writeIP("ipv6", v6, dns6)
+
+	return &ConfigArtifact{
+		Filename:    name + ".nmconnection",
+		ContentType: "text/plain; charset=utf-8",
+		Content:     b.Bytes(),
+	}, nil
+}
@@ -0,0 +1,135 @@
+package wireguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func testConfigParams() ClientConfigParams {
+	return ClientConfigParams{
+		Name:                "wg0",
+		ID:                  "alice",
+		PrivateKey:          "cHJpdmF0ZQ==",
+		Address:             "10.8.0.2/32, fd42::2/128",
+		DNS:                 "1.1.1.1, 2606:4700:4700::1111",
+		ServerPublicKey:     "c2VydmVy",
+		PresharedKey:        "cHNr",
+		AllowedIPs:          "0.0.0.0/0, ::/0",
+		Endpoint:            "vpn.example.com:51820",
+		PersistentKeepalive: 25,
+	}
+}
+
+func TestRenderClientConfig(t *testing.T) {
+	p := testConfigParams()
+
+	tests := []struct {
+		format   ConfigFormat
+		filename string
+		contains []string
+	}{
+		{ConfigFormatWGQuick, "wg0.conf", []string{"[Interface]\n", "Endpoint = vpn.example.com:51820\n"}},
+		{ConfigFormatJSON, "wg0.json", []string{`"endpoint": "vpn.example.com:51820"`}},
+		{ConfigFormatMobileConfig, "wg0.mobileconfig", []string{"<string>com.wireguard.ios</string>"}},
+		{ConfigFormatMobileConfigMac, "wg0.mobileconfig", []string{"<string>com.wireguard.macos</string>"}},
+		{ConfigFormatNetworkManager, "wg0.nmconnection", []string{
+			"type=wireguard\n",
+			"[wireguard-peer.c2VydmVy]\nendpoint=vpn.example.com:51820\npreshared-key=cHNr\n",
+			"allowed-ips=0.0.0.0/0;::/0;\n",
+			"[ipv4]\naddress1=10.8.0.2/32\ndns=1.1.1.1;\nmethod=manual\n",
+			"[ipv6]\naddress1=fd42::2/128\ndns=2606:4700:4700::1111;\nmethod=manual\n",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			a, err := RenderClientConfig(tt.format, p)
+			if err != nil {
+				t.Fatalf("RenderClientConfig() error = %v", err)
+			}
+			if a.Format != tt.format || a.Filename != tt.filename || a.ContentType == "" {
+				t.Errorf("artifact = %s %s %s", a.Format, a.Filename, a.ContentType)
+			}
+			for _, want := range tt.contains {
+				if !bytes.Contains(a.Content, []byte(want)) {
+					t.Errorf("content missing %q:\n%s", want, a.Content)
+				}
+			}
+
+			// Повторная выдача даёт тот же файл
+			again, _ := RenderClientConfig(tt.format, p)
+			if !bytes.Equal(again.Content, a.Content) {
+				t.Error("render is not deterministic")
+			}
+		})
+	}
+
+	if _, err := RenderClientConfig("pdf", p); err == nil {
+		t.Error("RenderClientConfig(unknown) expected error")
+	}
+}
+
+func TestRenderClientConfig_Structured(t *testing.T) {
+	p := testConfigParams()
+
+	a, _ := RenderClientConfig(ConfigFormatJSON, p)
+	var c jsonConfig
+	if err := json.Unmarshal(a.Content, &c); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if len(c.Interface.Addresses) != 2 || c.Peer.PersistentKeepalive != 25 || c.Peer.PresharedKey != "cHNr" {
+		t.Errorf("json config = %+v", c)
+	}
+
+	// Конфиг wg-quick внутри профиля Apple восстанавливается без искажений
+	a, _ = RenderClientConfig(ConfigFormatMobileConfig, p)
+	dec := xml.NewDecoder(bytes.NewReader(a.Content))
+	var wgQuick string
+	for next := false; ; {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			if next && el.Name.Local == "string" {
+				_ = dec.DecodeElement(&wgQuick, &el)
+				next = false
+			}
+		case xml.CharData:
+			if strings.TrimSpace(string(el)) == "WgQuickConfig" {
+				next = true
+			}
+		}
+	}
+	if wgQuick != GenerateClientConfig(p) {
+		t.Errorf("WgQuickConfig =\n%s\nwant\n%s", wgQuick, GenerateClientConfig(p))
+	}
+}
+
+func TestRegisterConfigRenderer(t *testing.T) {
+	const format ConfigFormat = "test-endpoint"
+	RegisterConfigRenderer(format, func(p ClientConfigParams) (*ConfigArtifact, error) {
+		return &ConfigArtifact{Filename: "endpoint.txt", Content: []byte(p.Endpoint)}, nil
+	})
+	t.Cleanup(func() {
+		renderersMu.Lock()
+		delete(renderers, format)
+		renderersMu.Unlock()
+	})
+
+	found := false
+	for _, f := range ConfigFormats() {
+		found = found || f == format
+	}
+	if !found {
+		t.Errorf("ConfigFormats() = %v, want %s", ConfigFormats(), format)
+	}
+	a, err := RenderClientConfig(format, testConfigParams())
+	if err != nil || string(a.Content) != "vpn.example.com:51820" || a.Format != format {
+		t.Errorf("RenderClientConfig() = %+v, %v", a, err)
+	}
+}
@@ -0,0 +1,87 @@
+package wireguard
+
+import (
+	"time"
+)
+
+// IdempotencyKey ключ идемпотентности CreateClient: повтор запроса с тем же
+// ключом до Until возвращает клиента, созданного первым запросом
+type IdempotencyKey struct {
+	Key       string    `json:"key"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Until     time.Time `json:"until"`
+}
+
+// SetIdempotencyTTL задает, сколько помнить ключи идемпотентности (0 - не помнить)
+func (cs *ClientStore) SetIdempotencyTTL(d time.Duration) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.idempotencyTTL = d
+}
+
+// GetIdempotencyKey возвращает действующий ключ идемпотентности
+func (cs *ClientStore) GetIdempotencyKey(key string) (*IdempotencyKey, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	k, ok := cs.idempotency[key]
+	if !ok || !k.Until.After(time.Now()) {
+		return nil, false
+	}
+	cp := *k
+	return &cp, true
+}
+
+// AddIdempotencyKey запоминает ключ запроса, выполненного для userID.
+// При выключенном сроке хранения ничего не делает.
+func (cs *ClientStore) AddIdempotencyKey(key, userID string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.idempotencyTTL <= 0 {
+		return nil
+	}
+	prev, existed := cs.idempotency[key]
+	now := time.Now()
+	cs.idempotency[key] = &IdempotencyKey{
+		Key:       key,
+		UserID:    userID,
+		CreatedAt: now,
+		Until:     now.Add(cs.idempotencyTTL),
+	}
+	if err := cs.persistLocked(); err != nil {
+		if existed {
+			cs.idempotency[key] = prev
+		} else {
+			delete(cs.idempotency, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// ExpireIdempotencyKeys забывает ключи, срок которых истёк к now.
+// Возвращает число удалённых ключей.
+func (cs *ClientStore) ExpireIdempotencyKeys(now time.Time) (int, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	expired := make(map[string]*IdempotencyKey)
+	for key, k := range cs.idempotency {
+		if !k.Until.After(now) {
+			expired[key] = k
+			delete(cs.idempotency, key)
+		}
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	if err := cs.persistLocked(); err != nil {
+		for key, k := range expired {
+			cs.idempotency[key] = k
+		}
+		return 0, err
+	}
+	return len(expired), nil
+}
@@ -0,0 +1,178 @@
+package wireguard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ImportStatus итог импорта одного пира
+type ImportStatus string
+
+const (
+	ImportImported ImportStatus = "imported" // клиент добавлен в хранилище
+	ImportExists   ImportStatus = "exists"   // такой клиент с тем же ключом уже есть
+	ImportSkipped  ImportStatus = "skipped"  // нет user_id или конфликт с существующим клиентом
+	ImportFailed   ImportStatus = "error"    // некорректный пир или ошибка сохранения
+)
+
+// ImportOptions параметры импорта пиров
+type ImportOptions struct {
+	UserIDs map[string]string // public_key → user_id, приоритетнее тега в комментарии
+	DryRun  bool              // ничего не сохранять, только вернуть результат
+	Sealer  *KeySealer        // шифрование preshared ключей (nil - без шифрования)
+}
+
+// ImportResult результат импорта одного пира
+type ImportResult struct {
+	PublicKey  string
+	UserID     string
+	DeviceID   string // из тега DeviceIDTag, пусто - основное устройство
+	AllowedIP  string
+	AllowedIP6 string
+	Status     ImportStatus
+	Message    string
+}
+
+// ImportPeers регистрирует пиры из конфига wg-quick как клиентов в хранилище.
+// Устройство WireGuard не изменяется: пиры там уже есть.
+// user_id берётся из opts.UserIDs, иначе из тега UserIDTag в комментарии,
+// device_id - из тега DeviceIDTag.
+// При DryRun хранилище не изменяется, а результаты показывают, что было бы сделано.
+func ImportPeers(store *ClientStore, cfg *WGQuickConfig, opts ImportOptions) []ImportResult {
+	results := make([]ImportResult, 0, len(cfg.Peers))
+	seen := make(map[string]string) // user_id/device_id → public_key в рамках этого импорта
+
+	for _, peer := range cfg.Peers {
+		results = append(results, importPeer(store, peer, opts, seen))
+	}
+	return results
+}
+
+// importPeer импортирует один пир, seen отслеживает устройства внутри одного импорта
+func importPeer(store *ClientStore, peer *WGQuickPeer, opts ImportOptions, seen map[string]string) ImportResult {
+	res := ImportResult{PublicKey: peer.PublicKey}
+	result := func(status ImportStatus, message string) ImportResult {
+		res.Status = status
+		res.Message = message
+		return res
+	}
+
+	if err := ValidatePublicKey(peer.PublicKey); err != nil {
+		return result(ImportFailed, fmt.Sprintf("invalid public key: %v", err))
+	}
+
+	res.UserID = opts.UserIDs[peer.PublicKey]
+	if res.UserID == "" {
+		res.UserID = peer.Tags[UserIDTag]
+	}
+	if res.UserID == "" {
+		return result(ImportSkipped, "no user_id in mapping or comment tag")
+	}
+	res.DeviceID = peer.Tags[DeviceIDTag]
+	if res.DeviceID != "" {
+		if err := ValidateDeviceID(res.DeviceID); err != nil {
+			return result(ImportFailed, err.Error())
+		}
+	}
+	client := &ClientData{UserID: res.UserID, DeviceID: res.DeviceID}
+
+	if len(peer.AllowedIPs) == 0 {
+		return result(ImportSkipped, "peer has no AllowedIPs")
+	}
+	v4, v6, err := SplitAllowedIPs(peer.AllowedIPs)
+	if err != nil {
+		return result(ImportFailed, fmt.Sprintf("invalid AllowedIPs: %v", err))
+	}
+	// Клиент агента - один адрес на семейство и без Endpoint: остальное
+	// потерялось бы при следующей записи пира, поэтому такие пиры не трогаем
+	if len(JoinAllowedIPs(v4, v6)) != len(peer.AllowedIPs) {
+		return result(ImportSkipped, fmt.Sprintf("peer has %d AllowedIPs, only one IPv4 and one IPv6 address are supported", len(peer.AllowedIPs)))
+	}
+	if peer.Endpoint != "" {
+		return result(ImportSkipped, "peer has an Endpoint, which is not supported for clients")
+	}
+	res.AllowedIP, res.AllowedIP6 = v4, v6
+
+	if existing, ok := store.GetByPublicKey(peer.PublicKey); ok {
+		if existing.ID() == client.ID() {
+			return result(ImportExists, "already imported")
+		}
+		return result(ImportSkipped, fmt.Sprintf("public key already belongs to %s", existing.ID()))
+	}
+	if _, exists := store.GetDevice(res.UserID, res.DeviceID); exists {
+		return result(ImportSkipped, "user_id already has a different key")
+	}
+	if msg := addressConflict(store, res.UserID, res.AllowedIP, res.AllowedIP6); msg != "" {
+		return result(ImportSkipped, msg)
+	}
+	if other, ok := seen[client.ID()]; ok && other != peer.PublicKey {
+		return result(ImportSkipped, "user_id used by another peer in this config")
+	}
+	seen[client.ID()] = peer.PublicKey
+
+	if opts.DryRun {
+		return result(ImportImported, "dry run")
+	}
+
+	psk, err := opts.Sealer.Seal(peer.PresharedKey)
+	if err != nil {
+		return result(ImportFailed, fmt.Sprintf("failed to seal preshared key: %v", err))
+	}
+
+	client.PublicKey = peer.PublicKey
+	client.AllowedIP, client.AllowedIP6 = res.AllowedIP, res.AllowedIP6
+	client.Enabled = true
+	client.PresharedKey = psk
+	if err := store.Add(client); err != nil {
+		return result(ImportFailed, err.Error())
+	}
+	return result(ImportImported, "")
+}
+
+// ParseUserIDMap разбирает файл соответствий "public_key user_id" (по одному на строку, # - комментарий)
+func ParseUserIDMap(r io.Reader) (map[string]string, error) {
+	userIDs := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"public_key user_id\"", lineNum)
+		}
+		if err := ValidatePublicKey(fields[0]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid public key: %w", lineNum, err)
+		}
+		userIDs[fields[0]] = fields[1]
+	}
+	return userIDs, scanner.Err()
+}
+
+// addressConflict проверяет, что адреса пира не заняты другим клиентом,
+// не зарезервированы и не в карантине за другим user_id. Пусто - конфликта нет.
+func addressConflict(store *ClientStore, userID string, ips ...string) string {
+	for _, ip := range ips {
+		if ip == "" {
+			continue
+		}
+		if owner, ok := store.GetByIP(ip); ok {
+			return fmt.Sprintf("address %s already belongs to %s", ip, owner.ID())
+		}
+		if r, ok := store.GetReservation(ip); ok && r.UserID != userID {
+			return fmt.Sprintf("address %s is reserved for %s", ip, r.UserID)
+		}
+		if q, ok := store.GetQuarantine(ip); ok && q.UserID != userID {
+			return fmt.Sprintf("address %s is in quarantine until %s", ip, q.Until.Format(time.RFC3339))
+		}
+	}
+	return ""
+}
@@ -2,6 +2,8 @@ package wireguard
 
 import (
 	"fmt"
+	"net"
+	"slices"
 	"sync"
 
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
@@ -21,7 +23,8 @@ func NewMockClient() *MockClient {
 	}
 }
 
-// Device возвращает информацию об устройстве
+// Device возвращает копию состояния устройства, как настоящий клиент:
+// вызывающий может читать её параллельно с ConfigureDevice
 func (m *MockClient) Device(name string) (*wgtypes.Device, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -35,7 +38,9 @@ func (m *MockClient) Device(name string) (*wgtypes.Device, error) {
 		return nil, fmt.Errorf("device %s not found", name)
 	}
 
-	return device, nil
+	cp := *device
+	cp.Peers = slices.Clone(device.Peers)
+	return &cp, nil
 }
 
 // ConfigureDevice конфигурирует устройство
@@ -76,6 +81,9 @@ func (m *MockClient) ConfigureDevice(name string, cfg wgtypes.Config) error {
 				}
 			}
 		} else {
+			// Как и ядро, забираем адреса у других пиров
+			takeAllowedIPs(device, peerCfg.PublicKey, peerCfg.AllowedIPs)
+
 			// Добавляем или обновляем peer
 			found := false
 			for i, peer := range device.Peers {
@@ -87,6 +95,9 @@ func (m *MockClient) ConfigureDevice(name string, cfg wgtypes.Config) error {
 					if peerCfg.PersistentKeepaliveInterval != nil {
 						device.Peers[i].PersistentKeepaliveInterval = *peerCfg.PersistentKeepaliveInterval
 					}
+					if peerCfg.PresharedKey != nil {
+						device.Peers[i].PresharedKey = *peerCfg.PresharedKey
+					}
 					found = true
 					break
 				}
@@ -102,6 +113,9 @@ func (m *MockClient) ConfigureDevice(name string, cfg wgtypes.Config) error {
 				if peerCfg.PersistentKeepaliveInterval != nil {
 					newPeer.PersistentKeepaliveInterval = *peerCfg.PersistentKeepaliveInterval
 				}
+				if peerCfg.PresharedKey != nil {
+					newPeer.PresharedKey = *peerCfg.PresharedKey
+				}
 				device.Peers = append(device.Peers, newPeer)
 			}
 		}
@@ -110,6 +124,22 @@ func (m *MockClient) ConfigureDevice(name string, cfg wgtypes.Config) error {
 	return nil
 }
 
+// takeAllowedIPs убирает адреса ips у всех пиров устройства, кроме key
+func takeAllowedIPs(device *wgtypes.Device, key wgtypes.Key, ips []net.IPNet) {
+	for i, peer := range device.Peers {
+		if peer.PublicKey == key {
+			continue
+		}
+		kept := peer.AllowedIPs[:0:0]
+		for _, allowed := range peer.AllowedIPs {
+			if !slices.ContainsFunc(ips, func(ip net.IPNet) bool { return ip.String() == allowed.String() }) {
+				kept = append(kept, allowed)
+			}
+		}
+		device.Peers[i].AllowedIPs = kept
+	}
+}
+
 // Close закрывает клиент
 func (m *MockClient) Close() error {
 	m.mu.Lock()
@@ -0,0 +1,165 @@
+package wireguard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// QRFormat формат изображения QR кода
+type QRFormat string
+
+const (
+	QRFormatPNG QRFormat = "png"
+	QRFormatSVG QRFormat = "svg"
+)
+
+// QRErrorCorrection уровень коррекции ошибок QR кода
+type QRErrorCorrection string
+
+const (
+	QRErrorCorrectionLow     QRErrorCorrection = "low"     // ~7%
+	QRErrorCorrectionMedium  QRErrorCorrection = "medium"  // ~15%
+	QRErrorCorrectionHigh    QRErrorCorrection = "high"    // ~25%
+	QRErrorCorrectionHighest QRErrorCorrection = "highest" // ~30%
+)
+
+// Ограничения параметров QR кода
+const (
+	maxQRModuleSize = 32
+	maxQRQuietZone  = 16
+)
+
+// QROptions параметры рендеринга QR кода
+type QROptions struct {
+	Format          QRFormat
+	ModuleSize      int // размер модуля в пикселях
+	ErrorCorrection QRErrorCorrection
+	QuietZone       int // отступ вокруг кода в модулях
+}
+
+// DefaultQROptions возвращает параметры по умолчанию: PNG, 8px, medium, отступ 4 модуля
+func DefaultQROptions() QROptions {
+	return QROptions{
+		Format:          QRFormatPNG,
+		ModuleSize:      8,
+		ErrorCorrection: QRErrorCorrectionMedium,
+		QuietZone:       4,
+	}
+}
+
+// Validate проверяет параметры QR кода
+func (o QROptions) Validate() error {
+	switch o.Format {
+	case QRFormatPNG, QRFormatSVG:
+	default:
+		return fmt.Errorf("unknown QR format %q (expected png or svg)", o.Format)
+	}
+	if _, err := o.ErrorCorrection.level(); err != nil {
+		return err
+	}
+	if o.ModuleSize < 1 || o.ModuleSize > maxQRModuleSize {
+		return fmt.Errorf("QR module size must be between 1 and %d, got %d", maxQRModuleSize, o.ModuleSize)
+	}
+	if o.QuietZone < 0 || o.QuietZone > maxQRQuietZone {
+		return fmt.Errorf("QR quiet zone must be between 0 and %d, got %d", maxQRQuietZone, o.QuietZone)
+	}
+	return nil
+}
+
+// level переводит уровень коррекции в значение библиотеки
+func (e QRErrorCorrection) level() (qrcode.RecoveryLevel, error) {
+	switch e {
+	case QRErrorCorrectionLow:
+		return qrcode.Low, nil
+	case QRErrorCorrectionMedium:
+		return qrcode.Medium, nil
+	case QRErrorCorrectionHigh:
+		return qrcode.High, nil
+	case QRErrorCorrectionHighest:
+		return qrcode.Highest, nil
+	default:
+		return 0, fmt.Errorf("unknown QR error correction %q (expected low, medium, high or highest)", e)
+	}
+}
+
+// GenerateQRCode создает QR код из конфигурации (возвращает base64 изображения)
+func GenerateQRCode(config string, opts QROptions) (string, error) {
+	data, err := RenderQRCode(config, opts)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// RenderQRCode создает изображение QR кода в формате opts.Format
+func RenderQRCode(content string, opts QROptions) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	level, _ := opts.ErrorCorrection.level()
+
+	code, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code: %w", err)
+	}
+	// Отступ рисуем сами, у библиотеки он фиксированный
+	code.DisableBorder = true
+	modules := code.Bitmap()
+
+	if opts.Format == QRFormatSVG {
+		return renderQRSVG(modules, opts), nil
+	}
+	return renderQRPNG(modules, opts)
+}
+
+// renderQRPNG рисует модули в черно-белый PNG
+func renderQRPNG(modules [][]bool, opts QROptions) ([]byte, error) {
+	size := (len(modules) + 2*opts.QuietZone) * opts.ModuleSize
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			x0 := (x + opts.QuietZone) * opts.ModuleSize
+			y0 := (y + opts.QuietZone) * opts.ModuleSize
+			for py := y0; py < y0+opts.ModuleSize; py++ {
+				for px := x0; px < x0+opts.ModuleSize; px++ {
+					img.SetColorIndex(px, py, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderQRSVG рисует модули одним path в координатах модулей
+func renderQRSVG(modules [][]bool, opts QROptions) []byte {
+	n := len(modules) + 2*opts.QuietZone
+	size := n * opts.ModuleSize
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, n, n)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, n, n)
+	for y, row := range modules {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+opts.QuietZone, y+opts.QuietZone)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
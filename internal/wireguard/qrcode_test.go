@@ -0,0 +1,87 @@
+package wireguard
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestRenderQRCode_PNG(t *testing.T) {
+	opts := DefaultQROptions()
+	opts.ModuleSize = 3
+	opts.QuietZone = 2
+
+	data, err := RenderQRCode("hello", opts)
+	if err != nil {
+		t.Fatalf("RenderQRCode() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+
+	// "hello" с коррекцией medium помещается в версию 1: 21x21 модуль
+	want := (21 + 2*opts.QuietZone) * opts.ModuleSize
+	if b := img.Bounds(); b.Dx() != want || b.Dy() != want {
+		t.Fatalf("image size = %dx%d, want %dx%d", b.Dx(), b.Dy(), want, want)
+	}
+
+	isDark := func(x, y int) bool {
+		r, _, _, _ := img.At(x, y).RGBA()
+		return r == 0
+	}
+	if isDark(0, 0) {
+		t.Error("quiet zone should be white")
+	}
+	// Левый верхний угол поискового узора
+	corner := opts.QuietZone * opts.ModuleSize
+	if !isDark(corner, corner) || !isDark(corner+opts.ModuleSize-1, corner+opts.ModuleSize-1) {
+		t.Error("finder pattern should start right after the quiet zone")
+	}
+}
+
+func TestRenderQRCode_SVG(t *testing.T) {
+	opts := DefaultQROptions()
+	opts.Format = QRFormatSVG
+	opts.QuietZone = 0
+
+	data, err := RenderQRCode("hello", opts)
+	if err != nil {
+		t.Fatalf("RenderQRCode() error = %v", err)
+	}
+	svg := string(data)
+
+	for _, want := range []string{`<svg xmlns="http://www.w3.org/2000/svg"`, `width="168"`, `viewBox="0 0 21 21"`, `d="M0 0h1v1h-1z`} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG missing %q:\n%s", want, svg)
+		}
+	}
+}
+
+func TestQROptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*QROptions)
+		wantErr bool
+	}{
+		{name: "defaults", modify: func(*QROptions) {}},
+		{name: "svg highest", modify: func(o *QROptions) { o.Format = QRFormatSVG; o.ErrorCorrection = QRErrorCorrectionHighest }},
+		{name: "no quiet zone", modify: func(o *QROptions) { o.QuietZone = 0 }},
+		{name: "unknown format", modify: func(o *QROptions) { o.Format = "gif" }, wantErr: true},
+		{name: "unknown error correction", modify: func(o *QROptions) { o.ErrorCorrection = "max" }, wantErr: true},
+		{name: "zero module size", modify: func(o *QROptions) { o.ModuleSize = 0 }, wantErr: true},
+		{name: "huge module size", modify: func(o *QROptions) { o.ModuleSize = 100 }, wantErr: true},
+		{name: "negative quiet zone", modify: func(o *QROptions) { o.QuietZone = -1 }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultQROptions()
+			tt.modify(&opts)
+			if err := opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
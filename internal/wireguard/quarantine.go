@@ -0,0 +1,129 @@
+package wireguard
+
+import (
+	"errors"
+	"maps"
+	"sort"
+	"time"
+)
+
+// ErrNotQuarantined адреса нет в карантине
+var ErrNotQuarantined = errors.New("IP is not in quarantine")
+
+// QuarantinedIP адрес удалённого клиента, который не выдаётся другим
+// пользователям до Until, чтобы новому клиенту не достался трафик
+// или правила файрвола, предназначенные предыдущему
+type QuarantinedIP struct {
+	IP         string    `json:"ip"`
+	UserID     string    `json:"user_id"` // предыдущий владелец, может вернуть адрес себе
+	ReleasedAt time.Time `json:"released_at"`
+	Until      time.Time `json:"until"`
+}
+
+// SetQuarantine задает срок карантина освобождённых адресов (0 - без карантина)
+func (cs *ClientStore) SetQuarantine(d time.Duration) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.quarantineFor = d
+}
+
+// quarantineLocked помещает адрес в карантин, вызывается под cs.mu
+func (cs *ClientStore) quarantineLocked(ip, userID string) {
+	now := time.Now()
+	cs.quarantine[ip] = &QuarantinedIP{
+		IP:         ip,
+		UserID:     userID,
+		ReleasedAt: now,
+		Until:      now.Add(cs.quarantineFor),
+	}
+}
+
+// GetQuarantine возвращает запись карантина для адреса
+func (cs *ClientStore) GetQuarantine(ip string) (*QuarantinedIP, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	q, ok := cs.quarantine[ip]
+	if !ok {
+		return nil, false
+	}
+	cp := *q
+	return &cp, true
+}
+
+// ListQuarantine возвращает адреса в карантине, отсортированные по сроку освобождения
+func (cs *ClientStore) ListQuarantine() []*QuarantinedIP {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	result := make([]*QuarantinedIP, 0, len(cs.quarantine))
+	for _, q := range cs.quarantine {
+		cp := *q
+		result = append(result, &cp)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Until.Equal(result[j].Until) {
+			return result[i].Until.Before(result[j].Until)
+		}
+		return result[i].IP < result[j].IP
+	})
+	return result
+}
+
+// ExpireQuarantine возвращает в пулы адреса, чей карантин истёк к now.
+// Возвращает освобождённые адреса.
+func (cs *ClientStore) ExpireQuarantine(now time.Time) ([]string, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var expired []string
+	for ip, q := range cs.quarantine {
+		if !q.Until.After(now) {
+			expired = append(expired, ip)
+		}
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	sort.Strings(expired)
+	return expired, cs.releaseQuarantineLocked(expired...)
+}
+
+// ReleaseQuarantine досрочно возвращает адреса в пулы. Без аргументов - все адреса в карантине.
+func (cs *ClientStore) ReleaseQuarantine(ips ...string) ([]string, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if len(ips) == 0 {
+		for ip := range cs.quarantine {
+			ips = append(ips, ip)
+		}
+		sort.Strings(ips)
+	}
+	for _, ip := range ips {
+		if _, ok := cs.quarantine[ip]; !ok {
+			return nil, ErrNotQuarantined
+		}
+	}
+	if len(ips) == 0 {
+		return nil, nil
+	}
+	return ips, cs.releaseQuarantineLocked(ips...)
+}
+
+// releaseQuarantineLocked снимает карантин с адресов и сохраняет состояние,
+// при ошибке сохранения карантин восстанавливается. Вызывается под cs.mu
+func (cs *ClientStore) releaseQuarantineLocked(ips ...string) error {
+	prev := maps.Clone(cs.quarantine)
+	for _, ip := range ips {
+		delete(cs.quarantine, ip)
+	}
+	if err := cs.persistLocked(); err != nil {
+		cs.quarantine = prev
+		return err
+	}
+	for _, ip := range ips {
+		cs.releaseUnusedLocked(ip)
+	}
+	return nil
+}
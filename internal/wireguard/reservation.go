@@ -0,0 +1,132 @@
+package wireguard
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrReservationNotFound резервирования для адреса нет
+var ErrReservationNotFound = errors.New("reservation not found")
+
+// Reservation закрепляет адрес за user_id, в том числе до создания клиента.
+// Зарезервированный адрес не выдаётся другим клиентам и не освобождается
+// при удалении клиента - только вместе с резервированием.
+type Reservation struct {
+	IP        string    `json:"ip"` // адрес с маской хоста ("10.8.0.50/32")
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddReservation сохраняет резервирование. Адрес должен быть уже занят
+// в пуле вызывающим (IPAllocator.Claim), хранилище лишь удерживает его.
+func (cs *ClientStore) AddReservation(r *Reservation) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if existing, ok := cs.reservations[r.IP]; ok {
+		return fmt.Errorf("%w: %s reserved for %s", ErrIPReserved, r.IP, existing.UserID)
+	}
+	cp := *r
+	cs.reservations[r.IP] = &cp
+	for _, a := range cs.allocators {
+		a.Mark(r.IP)
+	}
+	// Резервирование удерживает адрес дольше карантина
+	quarantined, inQuarantine := cs.quarantine[r.IP]
+	delete(cs.quarantine, r.IP)
+
+	if err := cs.persistLocked(); err != nil {
+		delete(cs.reservations, r.IP)
+		if inQuarantine {
+			cs.quarantine[r.IP] = quarantined
+		}
+		cs.releaseUnusedLocked(r.IP)
+		return err
+	}
+	return nil
+}
+
+// DeleteReservation снимает резервирование. Адрес возвращается в пул,
+// если им не пользуется клиент.
+func (cs *ClientStore) DeleteReservation(ip string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	prev, ok := cs.reservations[ip]
+	if !ok {
+		return ErrReservationNotFound
+	}
+	delete(cs.reservations, ip)
+
+	if err := cs.persistLocked(); err != nil {
+		cs.reservations[ip] = prev
+		return err
+	}
+	cs.releaseUnusedLocked(ip)
+	return nil
+}
+
+// GetReservation возвращает резервирование адреса
+func (cs *ClientStore) GetReservation(ip string) (*Reservation, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	r, ok := cs.reservations[ip]
+	if !ok {
+		return nil, false
+	}
+	cp := *r
+	return &cp, true
+}
+
+// ListReservations возвращает резервирования, отсортированные по user_id и адресу.
+// Пустой userID - все резервирования.
+func (cs *ClientStore) ListReservations(userID string) []*Reservation {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	result := make([]*Reservation, 0, len(cs.reservations))
+	for _, r := range cs.reservations {
+		if userID != "" && r.UserID != userID {
+			continue
+		}
+		cp := *r
+		result = append(result, &cp)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].UserID != result[j].UserID {
+			return result[i].UserID < result[j].UserID
+		}
+		return result[i].IP < result[j].IP
+	})
+	return result
+}
+
+// ReleaseIPs возвращает в пулы адреса, которые не закреплены ни за клиентом,
+// ни резервированием (например, выделенные под клиента, которого не удалось сохранить)
+func (cs *ClientStore) ReleaseIPs(ips ...string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for _, ip := range ips {
+		cs.releaseUnusedLocked(ip)
+	}
+}
+
+// releaseUnusedLocked освобождает адрес в пулах, если им не пользуется клиент
+// и он не удерживается резервированием или карантином, вызывается под cs.mu
+func (cs *ClientStore) releaseUnusedLocked(ip string) {
+	if ip == "" || cs.heldLocked(ip) {
+		return
+	}
+	for _, c := range cs.clients {
+		if c.AllowedIP == ip || c.AllowedIP6 == ip {
+			return
+		}
+	}
+	for _, a := range cs.allocators {
+		a.Release(ip)
+	}
+}
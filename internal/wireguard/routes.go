@@ -0,0 +1,81 @@
+package wireguard
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// ComplementCIDRs возвращает минимальный набор подсетей, покрывающий include
+// за вычетом exclude: например, "весь трафик, кроме локальной сети" для
+// AllowedIPs клиента. IPv4 и IPv6 считаются независимо, подсети exclude
+// другого семейства не влияют на результат. Подсети идут в порядке include,
+// внутри каждой - по возрастанию адреса.
+func ComplementCIDRs(include, exclude []string) ([]string, error) {
+	excluded := make([]netip.Prefix, 0, len(exclude))
+	for _, cidr := range exclude {
+		p, err := parseRoute(cidr)
+		if err != nil {
+			return nil, err
+		}
+		excluded = append(excluded, p)
+	}
+
+	var result []string
+	for _, cidr := range include {
+		p, err := parseRoute(cidr)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range complement(p, excluded, nil) {
+			result = append(result, r.String())
+		}
+	}
+	return result, nil
+}
+
+func parseRoute(cidr string) (netip.Prefix, error) {
+	p, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	return p.Masked(), nil
+}
+
+// complement добавляет в out части p, не пересекающиеся с exclude. Подсеть,
+// частично пересекающаяся с исключениями, делится пополам, пока половины не
+// окажутся целиком свободны или целиком исключены - так получается минимальный набор.
+func complement(p netip.Prefix, exclude []netip.Prefix, out []netip.Prefix) []netip.Prefix {
+	var overlapping []netip.Prefix
+	for _, e := range exclude {
+		if !p.Overlaps(e) {
+			continue
+		}
+		if e.Bits() <= p.Bits() {
+			return out // исключение покрывает p целиком
+		}
+		overlapping = append(overlapping, e)
+	}
+	if len(overlapping) == 0 {
+		return append(out, p)
+	}
+	lo, hi := splitPrefix(p)
+	out = complement(lo, overlapping, out)
+	return complement(hi, overlapping, out)
+}
+
+// splitPrefix делит подсеть на две половины
+func splitPrefix(p netip.Prefix) (lo, hi netip.Prefix) {
+	bits := p.Bits()
+	b := p.Addr().As16()
+	i := bits
+	if p.Addr().Is4() {
+		i += 96 // IPv4 занимает последние 4 байта As16
+	}
+	b[i/8] |= 0x80 >> (i % 8)
+	upper := netip.AddrFrom16(b)
+	if p.Addr().Is4() {
+		upper = upper.Unmap()
+	}
+	return netip.PrefixFrom(p.Addr(), bits+1), netip.PrefixFrom(upper, bits+1)
+}
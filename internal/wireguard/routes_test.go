@@ -0,0 +1,103 @@
+package wireguard
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestComplementCIDRs(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "nothing excluded",
+			include: []string{"0.0.0.0/0", "::/0"},
+			want:    []string{"0.0.0.0/0", "::/0"},
+		},
+		{
+			name:    "exclude half",
+			include: []string{"0.0.0.0/0"},
+			exclude: []string{"0.0.0.0/1"},
+			want:    []string{"128.0.0.0/1"},
+		},
+		{
+			name:    "exclude single host",
+			include: []string{"10.0.0.0/30"},
+			exclude: []string{"10.0.0.1/32"},
+			want:    []string{"10.0.0.0/32", "10.0.0.2/31"},
+		},
+		{
+			name:    "exclude LAN from IPv4",
+			include: []string{"0.0.0.0/0"},
+			exclude: []string{"192.168.0.0/16"},
+			want: []string{
+				"0.0.0.0/1", "128.0.0.0/2", "192.0.0.0/9", "192.128.0.0/11",
+				"192.160.0.0/13", "192.169.0.0/16", "192.170.0.0/15", "192.172.0.0/14",
+				"192.176.0.0/12", "192.192.0.0/10", "193.0.0.0/8", "194.0.0.0/7",
+				"196.0.0.0/6", "200.0.0.0/5", "208.0.0.0/4", "224.0.0.0/3",
+			},
+		},
+		{
+			name:    "IPv6 ULA",
+			include: []string{"::/0"},
+			exclude: []string{"fc00::/7", "10.0.0.0/8"},
+			want: []string{
+				"::/1", "8000::/2", "c000::/3", "e000::/4", "f000::/5", "f800::/6", "fe00::/7",
+			},
+		},
+		{
+			name:    "exclusion covers include",
+			include: []string{"10.1.0.0/16"},
+			exclude: []string{"10.0.0.0/8"},
+			want:    nil,
+		},
+		{
+			name:    "invalid exclusion",
+			include: []string{"0.0.0.0/0"},
+			exclude: []string{"192.168.0.0"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ComplementCIDRs(tt.include, tt.exclude)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ComplementCIDRs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ComplementCIDRs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComplementCIDRs_Coverage(t *testing.T) {
+	exclude := []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "169.254.0.0/16"}
+	got, err := ComplementCIDRs([]string{"0.0.0.0/0"}, exclude)
+	if err != nil {
+		t.Fatalf("ComplementCIDRs() error = %v", err)
+	}
+
+	// Результат не пересекается с исключениями и вместе с ними покрывает всё пространство
+	var total uint64
+	for _, cidr := range append(got, exclude...) {
+		p := netip.MustParsePrefix(cidr)
+		total += 1 << (32 - p.Bits())
+	}
+	if total != 1<<32 {
+		t.Errorf("routes and exclusions cover %d addresses, want %d", total, uint64(1)<<32)
+	}
+	for _, cidr := range got {
+		for _, e := range exclude {
+			if netip.MustParsePrefix(cidr).Overlaps(netip.MustParsePrefix(e)) {
+				t.Errorf("%s overlaps excluded %s", cidr, e)
+			}
+		}
+	}
+}
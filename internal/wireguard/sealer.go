@@ -0,0 +1,151 @@
+package wireguard
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedPrefix префикс зашифрованного значения: sealed:v1:<kek_id>:<base64(nonce|ciphertext)>
+const sealedPrefix = "sealed:v1:"
+
+// kekSize размер ключа шифрования ключей (AES-256)
+const kekSize = 32
+
+// KeySealer шифрует секреты клиентов (приватные и preshared ключи) ключом
+// шифрования ключей (KEK). Шифрует всегда текущим KEK, расшифровывает текущим
+// и предыдущими, что позволяет ротировать KEK без простоя.
+// nil *KeySealer означает, что шифрование не настроено: значения хранятся открыто.
+type KeySealer struct {
+	currentID string
+	keys      map[string]cipher.AEAD // kek_id → AEAD
+}
+
+// NewKeySealer создает KeySealer с текущим KEK и предыдущими (для расшифровки)
+func NewKeySealer(current []byte, previous ...[]byte) (*KeySealer, error) {
+	s := &KeySealer{keys: make(map[string]cipher.AEAD)}
+
+	id, err := s.addKey(current)
+	if err != nil {
+		return nil, fmt.Errorf("current KEK: %w", err)
+	}
+	s.currentID = id
+
+	for i, key := range previous {
+		if _, err := s.addKey(key); err != nil {
+			return nil, fmt.Errorf("previous KEK #%d: %w", i+1, err)
+		}
+	}
+	return s, nil
+}
+
+func (s *KeySealer) addKey(key []byte) (string, error) {
+	if len(key) != kekSize {
+		return "", fmt.Errorf("KEK must be %d bytes, got %d", kekSize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	// ID - отпечаток ключа, сам ключ не раскрывает
+	sum := sha256.Sum256(key)
+	id := hex.EncodeToString(sum[:4])
+	s.keys[id] = aead
+	return id, nil
+}
+
+// ParseKEK разбирает KEK в base64 (например, `head -c 32 /dev/urandom | base64`)
+func ParseKEK(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid KEK encoding: %w", err)
+	}
+	if len(key) != kekSize {
+		return nil, fmt.Errorf("KEK must be %d bytes, got %d", kekSize, len(key))
+	}
+	return key, nil
+}
+
+// IsSealed проверяет, зашифровано ли значение
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// Seal шифрует значение текущим KEK
+func (s *KeySealer) Seal(plaintext string) (string, error) {
+	if s == nil || plaintext == "" {
+		return plaintext, nil
+	}
+	aead := s.keys[s.currentID]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(s.currentID))
+
+	return sealedPrefix + s.currentID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open расшифровывает значение. Незашифрованные значения (записанные до
+// включения шифрования) возвращаются как есть.
+func (s *KeySealer) Open(value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+	if s == nil {
+		return "", errors.New("value is sealed but KEK is not configured")
+	}
+
+	id, payload, ok := strings.Cut(strings.TrimPrefix(value, sealedPrefix), ":")
+	if !ok {
+		return "", errors.New("malformed sealed value")
+	}
+	aead, ok := s.keys[id]
+	if !ok {
+		return "", fmt.Errorf("value sealed with unknown KEK %s", id)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("malformed sealed value: %w", err)
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("malformed sealed value: too short")
+	}
+
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value sealed with KEK %s: %w", id, err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsReseal возвращает true, если значение не зашифровано или зашифровано не текущим KEK
+func (s *KeySealer) NeedsReseal(value string) bool {
+	if s == nil || value == "" {
+		return false
+	}
+	return !strings.HasPrefix(value, sealedPrefix+s.currentID+":")
+}
+
+// Reseal перешифровывает значение текущим KEK
+func (s *KeySealer) Reseal(value string) (string, error) {
+	plaintext, err := s.Open(value)
+	if err != nil {
+		return "", err
+	}
+	return s.Seal(plaintext)
+}
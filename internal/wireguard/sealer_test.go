@@ -0,0 +1,82 @@
+package wireguard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestKeySealer_RoundTripAndRotation(t *testing.T) {
+	oldKEK := bytes.Repeat([]byte{1}, kekSize)
+	newKEK := bytes.Repeat([]byte{2}, kekSize)
+	privateKey, _, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair() error = %v", err)
+	}
+
+	oldSealer, err := NewKeySealer(oldKEK)
+	if err != nil {
+		t.Fatalf("NewKeySealer() error = %v", err)
+	}
+	sealed, err := oldSealer.Seal(privateKey)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if !IsSealed(sealed) || strings.Contains(sealed, privateKey) {
+		t.Fatalf("Seal() = %q, want ciphertext", sealed)
+	}
+	if opened, err := oldSealer.Open(sealed); err != nil || opened != privateKey {
+		t.Fatalf("Open() = %q, %v", opened, err)
+	}
+
+	// Новый KEK без старого не может расшифровать
+	onlyNew, _ := NewKeySealer(newKEK)
+	if _, err := onlyNew.Open(sealed); err == nil {
+		t.Error("Open() with unknown KEK expected error")
+	}
+
+	// Ротация: новый KEK текущий, старый - предыдущий
+	rotated, err := NewKeySealer(newKEK, oldKEK)
+	if err != nil {
+		t.Fatalf("NewKeySealer() error = %v", err)
+	}
+	store := NewClientStore()
+	store.Add(&ClientData{UserID: "sealed", PrivateKey: sealed})
+	store.Add(&ClientData{UserID: "plain", PrivateKey: privateKey})
+	store.Add(&ClientData{UserID: "byo"})
+
+	n, err := store.ResealKeys(rotated)
+	if err != nil || n != 2 {
+		t.Fatalf("ResealKeys() = %d, %v, want 2", n, err)
+	}
+	for _, c := range store.List() {
+		if c.UserID == "byo" {
+			if c.PrivateKey != "" {
+				t.Error("client without private key must stay without it")
+			}
+			continue
+		}
+		if rotated.NeedsReseal(c.PrivateKey) {
+			t.Errorf("%s not resealed with current KEK", c.UserID)
+		}
+		if opened, err := onlyNew.Open(c.PrivateKey); err != nil || opened != privateKey {
+			t.Errorf("%s: Open() with new KEK = %q, %v", c.UserID, opened, err)
+		}
+	}
+
+	if n, _ := store.ResealKeys(rotated); n != 0 {
+		t.Errorf("second ResealKeys() = %d, want 0", n)
+	}
+}
+
+func TestParseKEK(t *testing.T) {
+	if _, err := ParseKEK("AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="); err != nil {
+		t.Errorf("ParseKEK() valid key error = %v", err)
+	}
+	if _, err := ParseKEK("c2hvcnQ="); err == nil {
+		t.Error("ParseKEK() short key expected error")
+	}
+	if _, err := ParseKEK("not base64!"); err == nil {
+		t.Error("ParseKEK() invalid base64 expected error")
+	}
+}
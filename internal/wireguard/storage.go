@@ -0,0 +1,124 @@
+package wireguard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// snapshotVersion версия формата файла состояния
+const snapshotVersion = 1
+
+// Snapshot полное состояние ClientStore, которое сохраняется на диск
+type Snapshot struct {
+	Version int           `json:"version"`
+	Clients []*ClientData `json:"clients"`
+	Pools   []*PoolState  `json:"pools,omitempty"`
+
+	Reservations []*Reservation   `json:"reservations,omitempty"`
+	Quarantine   []*QuarantinedIP `json:"quarantine,omitempty"`
+
+	IdempotencyKeys []*IdempotencyKey `json:"idempotency_keys,omitempty"`
+}
+
+// Storage бэкенд для персистентного хранения состояния ClientStore
+type Storage interface {
+	// Load загружает последнее сохранённое состояние (nil, если состояния ещё нет)
+	Load() (*Snapshot, error)
+	// Save атомарно сохраняет состояние целиком
+	Save(snapshot *Snapshot) error
+}
+
+// FileStorage хранит состояние в JSON файле на диске.
+// Запись атомарная: данные пишутся во временный файл рядом,
+// синхронизируются на диск и переименовываются поверх старого файла,
+// поэтому после падения агента на диске всегда целый снапшот.
+type FileStorage struct {
+	path string
+}
+
+// NewFileStorage создает файловое хранилище, каталог создаётся при необходимости
+func NewFileStorage(path string) (*FileStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	return &FileStorage{path: path}, nil
+}
+
+// Path возвращает путь к файлу состояния
+func (fs *FileStorage) Path() string {
+	return fs.path
+}
+
+// Load читает состояние из файла
+func (fs *FileStorage) Load() (*Snapshot, error) {
+	data, err := os.ReadFile(fs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", fs.path, err)
+	}
+	if snapshot.Version > snapshotVersion {
+		return nil, fmt.Errorf("state file version %d is newer than supported %d", snapshot.Version, snapshotVersion)
+	}
+	return &snapshot, nil
+}
+
+// Save атомарно записывает состояние в файл
+func (fs *FileStorage) Save(snapshot *Snapshot) error {
+	snapshot.Version = snapshotVersion
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	return writeFileAtomic(fs.path, data, 0o600)
+}
+
+// writeFileAtomic записывает файл через временный файл и rename
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op после успешного rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to chmod temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	// Синхронизируем каталог, чтобы rename пережил падение питания
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
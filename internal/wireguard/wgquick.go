@@ -0,0 +1,290 @@
+package wireguard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UserIDTag тег в комментарии секции [Peer], из которого берётся user_id:
+//
+//	[Peer]
+//	# user_id = alice
+//	PublicKey = ...
+const UserIDTag = "user_id"
+
+// DeviceIDTag тег с device_id дополнительного устройства пользователя,
+// у основного устройства тега нет
+const DeviceIDTag = "device_id"
+
+// ManagedTag тег, которым агент помечает написанные им секции [Peer]:
+//
+//	[Peer]
+//	# wg-agent: managed
+//
+// Только такие секции агент убирает из файла, когда их клиента больше нет.
+const ManagedTag = "wg-agent"
+
+const managedTagValue = "managed"
+
+// WGQuickConfig разобранный конфиг в формате wg-quick (wg0.conf, `wg showconf`)
+type WGQuickConfig struct {
+	Interface []string // исходные строки до первой секции [Peer], включая [Interface], PostUp/PostDown и комментарии
+	Peers     []*WGQuickPeer
+}
+
+// WGQuickPeer секция [Peer] конфига wg-quick
+type WGQuickPeer struct {
+	PublicKey           string
+	PresharedKey        string
+	AllowedIPs          []string
+	Endpoint            string
+	PersistentKeepalive int
+	Tags                map[string]string // теги из комментариев вида "# key = value"
+	Lines               []string          // исходные строки секции
+}
+
+// ParseWGQuickConfig разбирает конфиг wg-quick.
+// Комментарии, стоящие непосредственно перед [Peer], относятся к этому пиру.
+func ParseWGQuickConfig(r io.Reader) (*WGQuickConfig, error) {
+	cfg := &WGQuickConfig{}
+	var peer *WGQuickPeer
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+
+		if strings.EqualFold(line, "[Peer]") {
+			peer = &WGQuickPeer{Tags: make(map[string]string)}
+			// Переносим комментарии перед заголовком в новый пир
+			prev := &cfg.Interface
+			if n := len(cfg.Peers); n > 0 {
+				prev = &cfg.Peers[n-1].Lines
+			}
+			i := len(*prev)
+			for i > 0 && strings.HasPrefix(strings.TrimSpace((*prev)[i-1]), "#") {
+				i--
+			}
+			peer.Lines = append(peer.Lines, (*prev)[i:]...)
+			*prev = (*prev)[:i]
+			for _, c := range peer.Lines {
+				peer.parseTag(c)
+			}
+			peer.Lines = append(peer.Lines, raw)
+			cfg.Peers = append(cfg.Peers, peer)
+			continue
+		}
+
+		if peer == nil {
+			cfg.Interface = append(cfg.Interface, raw)
+			continue
+		}
+		peer.Lines = append(peer.Lines, raw)
+
+		if strings.HasPrefix(line, "#") {
+			peer.parseTag(line)
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("line %d: unexpected section %s after [Peer]", lineNum, line)
+		}
+
+		// Как и wg-quick, отбрасываем всё после '#'
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value, got %q", lineNum, line)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "publickey":
+			peer.PublicKey = value
+		case "presharedkey":
+			peer.PresharedKey = value
+		case "allowedips":
+			for _, ip := range strings.Split(value, ",") {
+				if ip = strings.TrimSpace(ip); ip != "" {
+					peer.AllowedIPs = append(peer.AllowedIPs, ip)
+				}
+			}
+		case "endpoint":
+			peer.Endpoint = value
+		case "persistentkeepalive":
+			if value == "off" {
+				continue
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid PersistentKeepalive %q", lineNum, value)
+			}
+			peer.PersistentKeepalive = n
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, p := range cfg.Peers {
+		if p.PublicKey == "" {
+			return nil, fmt.Errorf("peer #%d has no PublicKey", i+1)
+		}
+	}
+	return cfg, nil
+}
+
+// parseTag разбирает комментарий вида "# key = value" или "# key: value"
+func (p *WGQuickPeer) parseTag(comment string) {
+	if key, value, ok := parseTagComment(comment); ok {
+		p.Tags[key] = value
+	}
+}
+
+// parseTagComment возвращает ключ и значение тега из комментария
+func parseTagComment(comment string) (key, value string, ok bool) {
+	body := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(comment), "#"))
+
+	sep := strings.IndexAny(body, "=:")
+	if sep <= 0 {
+		return "", "", false
+	}
+	key = strings.ToLower(strings.TrimSpace(body[:sep]))
+	value = strings.TrimSpace(body[sep+1:])
+	if key == "" || value == "" || strings.ContainsAny(key, " \t") {
+		return "", "", false
+	}
+	return key, value, true
+}
+
+// RenderWGQuickConfig собирает конфиг wg-quick из base и клиентов агента
+// (секреты клиентов должны быть уже расшифрованы).
+// Секция [Interface] и пиры, которых нет среди clients, сохраняются как есть
+// (вместе с комментариями). Включенные клиенты пишутся на место своих
+// прежних секций (с их комментариями, Endpoint и PersistentKeepalive) или
+// добавляются в конец, отключенные убираются из файла.
+// Секции с тегом ManagedTag, ключа которых нет среди clients, написаны агентом
+// для удалённых клиентов или старых ключей и тоже убираются. Ручные секции,
+// в том числе с тегом UserIDTag, без ManagedTag не трогаются.
+func RenderWGQuickConfig(base *WGQuickConfig, clients []*ClientData) string {
+	byKey := make(map[string]*ClientData, len(clients))
+	for _, c := range clients {
+		byKey[c.PublicKey] = c
+	}
+
+	var b strings.Builder
+	writeLines := func(lines []string) {
+		// Пустые строки в конце блока заменяем одним разделителем
+		end := len(lines)
+		for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+		for _, line := range lines[:end] {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+		b.WriteByte('\n')
+	}
+
+	writeLines(base.Interface)
+
+	written := make(map[string]bool)
+	for _, peer := range base.Peers {
+		client, managed := byKey[peer.PublicKey]
+		if !managed {
+			if peer.Tags[ManagedTag] != managedTagValue {
+				writeLines(peer.Lines)
+			}
+			continue
+		}
+		if client.Enabled && !written[client.PublicKey] {
+			writeLines(updatePeerLines(peer, client))
+			written[client.PublicKey] = true
+		}
+	}
+
+	rest := make([]*ClientData, 0)
+	for _, c := range clients {
+		if c.Enabled && !written[c.PublicKey] {
+			rest = append(rest, c)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool { return rest[i].ID() < rest[j].ID() })
+	for _, c := range rest {
+		writeLines(renderPeerLines(c))
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// renderPeerLines секция [Peer] для клиента агента (PresharedKey уже расшифрован)
+func renderPeerLines(client *ClientData) []string {
+	lines := []string{
+		"[Peer]",
+		fmt.Sprintf("# %s: %s", ManagedTag, managedTagValue),
+		fmt.Sprintf("# %s = %s", UserIDTag, client.UserID),
+	}
+	if client.DeviceID != "" {
+		lines = append(lines, fmt.Sprintf("# %s = %s", DeviceIDTag, client.DeviceID))
+	}
+	lines = append(lines, "PublicKey = "+client.PublicKey)
+	if client.PresharedKey != "" {
+		lines = append(lines, "PresharedKey = "+client.PresharedKey)
+	}
+	return append(lines,
+		"AllowedIPs = "+strings.Join(client.AllowedIPs(), ", "),
+		"PersistentKeepalive = 25",
+	)
+}
+
+// updatePeerLines переписывает прежнюю секцию клиента: ключи и AllowedIPs
+// берутся из client, теги агента ставятся после [Peer], остальные строки
+// (комментарии, Endpoint, PersistentKeepalive) остаются как были
+func updatePeerLines(peer *WGQuickPeer, client *ClientData) []string {
+	fresh := renderPeerLines(client)
+	header, rest := fresh[:2], fresh[2:] // [Peer] и ManagedTag
+	for len(rest) > 0 && strings.HasPrefix(rest[0], "#") {
+		header = append(header, rest[0])
+		rest = rest[1:]
+	}
+	keys := rest[:len(rest)-1] // PublicKey, PresharedKey, AllowedIPs без PersistentKeepalive
+
+	lines := make([]string, 0, len(peer.Lines)+len(header))
+	for _, raw := range peer.Lines {
+		line := strings.TrimSpace(raw)
+		if strings.HasPrefix(line, "#") {
+			switch key, _, _ := parseTagComment(line); key {
+			case ManagedTag, UserIDTag, DeviceIDTag:
+				continue
+			}
+			lines = append(lines, raw)
+			continue
+		}
+		if strings.EqualFold(line, "[Peer]") {
+			lines = append(lines, header...)
+			continue
+		}
+		key, _, _ := strings.Cut(line, "=")
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "publickey":
+			lines = append(lines, keys...)
+			keys = nil
+		case "presharedkey", "allowedips":
+		default:
+			lines = append(lines, raw)
+		}
+	}
+	return append(lines, keys...)
+}
@@ -0,0 +1,58 @@
+package wireguard
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ConfigSyncer переписывает конфиг wg-quick (/etc/wireguard/wg0.conf) по состоянию
+// ClientStore, чтобы пиры агента переживали `wg-quick down/up` и перезагрузку
+// даже без запущенного агента.
+type ConfigSyncer struct {
+	mu     sync.Mutex
+	path   string
+	store  *ClientStore
+	sealer *KeySealer // для расшифровки preshared ключей
+}
+
+// NewConfigSyncer создает синхронизатор конфига path с хранилищем store
+func NewConfigSyncer(path string, store *ClientStore, sealer *KeySealer) *ConfigSyncer {
+	return &ConfigSyncer{path: path, store: store, sealer: sealer}
+}
+
+// Sync атомарно переписывает конфиг по текущему состоянию хранилища.
+// Секция [Interface], PostUp/PostDown, комментарии и чужие пиры сохраняются.
+func (cs *ConfigSyncer) Sync() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	data, err := os.ReadFile(cs.path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", cs.path, err)
+	}
+
+	base, err := ParseWGQuickConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", cs.path, err)
+	}
+
+	// Состояние берём под cs.mu, поэтому последняя запись всегда самая свежая
+	clients := cs.store.List()
+	for _, c := range clients {
+		if c.PresharedKey, err = cs.sealer.Open(c.PresharedKey); err != nil {
+			return fmt.Errorf("client %s: %w", c.ID(), err)
+		}
+	}
+	rendered := RenderWGQuickConfig(base, clients)
+	if rendered == string(data) {
+		return nil
+	}
+
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(cs.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	return writeFileAtomic(cs.path, []byte(rendered), mode)
+}
@@ -0,0 +1,304 @@
+package wireguard
+
+import (
+	"strings"
+	"testing"
+)
+
+const testWGQuickConfig = `[Interface]
+Address = 10.8.0.1/24
+ListenPort = 51820
+PrivateKey = yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk=
+PostUp = iptables -A FORWARD -i %i -j ACCEPT
+PostDown = iptables -D FORWARD -i %i -j ACCEPT
+
+# user_id = alice
+[Peer]
+PublicKey = xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=
+AllowedIPs = 10.8.0.2/32
+
+[Peer]
+# user_id: bob
+PublicKey = TrMvSoP4jYQlY6RIzBgbssQqY3vxI2Pi+y71lOWWXX0=
+PresharedKey = FpCyhws9cxwWoV4xELtfJvjJN+zQVRPISllRWgeopVE=
+AllowedIPs = 10.8.0.3/32, fd42::3/128 # inline comment
+PersistentKeepalive = 25
+
+[Peer]
+PublicKey = gN65BkIKy1eCE9pP1wdc8ROUtkHLF2PfAqYdyYBz6EA=
+AllowedIPs = 10.8.0.4/32
+`
+
+func TestParseWGQuickConfig(t *testing.T) {
+	cfg, err := ParseWGQuickConfig(strings.NewReader(testWGQuickConfig))
+	if err != nil {
+		t.Fatalf("ParseWGQuickConfig() error = %v", err)
+	}
+
+	if len(cfg.Peers) != 3 {
+		t.Fatalf("got %d peers, want 3", len(cfg.Peers))
+	}
+	for _, line := range cfg.Interface {
+		if strings.Contains(line, "user_id") {
+			t.Errorf("comment before [Peer] should belong to the peer, found in [Interface]: %q", line)
+		}
+	}
+
+	alice := cfg.Peers[0]
+	if alice.Tags[UserIDTag] != "alice" {
+		t.Errorf("alice tags = %v", alice.Tags)
+	}
+
+	bob := cfg.Peers[1]
+	if bob.Tags[UserIDTag] != "bob" {
+		t.Errorf("bob tags = %v", bob.Tags)
+	}
+	if len(bob.AllowedIPs) != 2 || bob.AllowedIPs[1] != "fd42::3/128" {
+		t.Errorf("bob AllowedIPs = %v", bob.AllowedIPs)
+	}
+	if bob.PresharedKey == "" || bob.PersistentKeepalive != 25 {
+		t.Errorf("bob = %+v", bob)
+	}
+}
+
+func TestParseWGQuickConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{name: "peer without public key", config: "[Peer]\nAllowedIPs = 10.8.0.2/32\n"},
+		{name: "garbage line", config: "[Peer]\nPublicKey\n"},
+		{name: "bad keepalive", config: "[Peer]\nPublicKey = x\nPersistentKeepalive = often\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseWGQuickConfig(strings.NewReader(tt.config)); err == nil {
+				t.Error("ParseWGQuickConfig() expected error")
+			}
+		})
+	}
+}
+
+func TestImportPeers(t *testing.T) {
+	cfg, err := ParseWGQuickConfig(strings.NewReader(testWGQuickConfig))
+	if err != nil {
+		t.Fatalf("ParseWGQuickConfig() error = %v", err)
+	}
+
+	store := NewClientStore()
+	userIDs := map[string]string{
+		"gN65BkIKy1eCE9pP1wdc8ROUtkHLF2PfAqYdyYBz6EA=": "carol",
+	}
+
+	dry := ImportPeers(store, cfg, ImportOptions{UserIDs: userIDs, DryRun: true})
+	if len(store.List()) != 0 {
+		t.Fatal("dry run must not modify the store")
+	}
+	for _, r := range dry {
+		if r.Status != ImportImported {
+			t.Errorf("dry run %s: status = %s (%s)", r.UserID, r.Status, r.Message)
+		}
+	}
+
+	results := ImportPeers(store, cfg, ImportOptions{UserIDs: userIDs})
+	want := map[string]string{"alice": "10.8.0.2/32", "bob": "10.8.0.3/32", "carol": "10.8.0.4/32"}
+	for _, r := range results {
+		if r.Status != ImportImported {
+			t.Errorf("%s: status = %s (%s)", r.PublicKey, r.Status, r.Message)
+		}
+		client, ok := store.Get(r.UserID)
+		if !ok || client.AllowedIP != want[r.UserID] || !client.Enabled || client.PrivateKey != "" {
+			t.Errorf("%s: stored client = %+v", r.UserID, client)
+		}
+	}
+
+	// Повторный импорт ничего не меняет
+	for _, r := range ImportPeers(store, cfg, ImportOptions{UserIDs: userIDs}) {
+		if r.Status != ImportExists {
+			t.Errorf("reimport %s: status = %s, want exists", r.UserID, r.Status)
+		}
+	}
+
+	// Без соответствия и тега пир пропускается
+	for _, r := range ImportPeers(NewClientStore(), cfg, ImportOptions{DryRun: true}) {
+		if r.PublicKey == "gN65BkIKy1eCE9pP1wdc8ROUtkHLF2PfAqYdyYBz6EA=" && r.Status != ImportSkipped {
+			t.Errorf("peer without user_id: status = %s, want skipped", r.Status)
+		}
+	}
+}
+
+func TestImportPeers_AddressConflict(t *testing.T) {
+	cfg, err := ParseWGQuickConfig(strings.NewReader(testWGQuickConfig))
+	if err != nil {
+		t.Fatalf("ParseWGQuickConfig() error = %v", err)
+	}
+
+	store := NewClientStore()
+	if err := store.Add(&ClientData{UserID: "zed", PublicKey: "pub-zed", AllowedIP: "10.8.0.2/32", Enabled: true}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.AddReservation(&Reservation{IP: "10.8.0.3/32", UserID: "zoe"}); err != nil {
+		t.Fatalf("AddReservation() error = %v", err)
+	}
+
+	userIDs := map[string]string{"gN65BkIKy1eCE9pP1wdc8ROUtkHLF2PfAqYdyYBz6EA=": "carol"}
+	want := map[string]ImportStatus{"alice": ImportSkipped, "bob": ImportSkipped, "carol": ImportImported}
+	for _, r := range ImportPeers(store, cfg, ImportOptions{UserIDs: userIDs}) {
+		if r.Status != want[r.UserID] {
+			t.Errorf("%s: status = %s (%s), want %s", r.UserID, r.Status, r.Message, want[r.UserID])
+		}
+	}
+	if c, _ := store.GetByIP("10.8.0.2/32"); c.UserID != "zed" {
+		t.Errorf("10.8.0.2/32 owner = %s, want zed", c.UserID)
+	}
+}
+
+func TestImportPeers_Unsupported(t *testing.T) {
+	const config = `[Peer]
+# user_id = site
+PublicKey = xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=
+AllowedIPs = 10.8.0.2/32, 192.168.10.0/24
+
+[Peer]
+# user_id = router
+PublicKey = TrMvSoP4jYQlY6RIzBgbssQqY3vxI2Pi+y71lOWWXX0=
+Endpoint = 203.0.113.7:51820
+AllowedIPs = 10.8.0.3/32
+
+[Peer]
+# user_id = dual
+PublicKey = gN65BkIKy1eCE9pP1wdc8ROUtkHLF2PfAqYdyYBz6EA=
+AllowedIPs = 10.8.0.4/32, fd42::4/128
+`
+	cfg, err := ParseWGQuickConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("ParseWGQuickConfig() error = %v", err)
+	}
+
+	store := NewClientStore()
+	want := map[string]ImportStatus{"site": ImportSkipped, "router": ImportSkipped, "dual": ImportImported}
+	for _, r := range ImportPeers(store, cfg, ImportOptions{}) {
+		if r.Status != want[r.UserID] {
+			t.Errorf("%s: status = %s (%s), want %s", r.UserID, r.Status, r.Message, want[r.UserID])
+		}
+	}
+	if n := len(store.List()); n != 1 {
+		t.Errorf("store has %d clients, want 1", n)
+	}
+}
+
+func TestRenderWGQuickConfig(t *testing.T) {
+	base, err := ParseWGQuickConfig(strings.NewReader(testWGQuickConfig))
+	if err != nil {
+		t.Fatalf("ParseWGQuickConfig() error = %v", err)
+	}
+
+	clients := []*ClientData{
+		// alice отключена - должна исчезнуть из файла
+		{UserID: "alice", PublicKey: "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=", AllowedIP: "10.8.0.2/32", Enabled: false},
+		// bob управляется агентом - секция переписывается на месте
+		{UserID: "bob", PublicKey: "TrMvSoP4jYQlY6RIzBgbssQqY3vxI2Pi+y71lOWWXX0=", AllowedIP: "10.8.0.3/32", Enabled: true},
+		// dave новый - добавляется в конец
+		{UserID: "dave", PublicKey: "Oq8ZsPPyH4rUVMZW0RbUzJwoYxX6zPuMr+sAoTsB9Ho=", AllowedIP: "10.8.0.5/32", Enabled: true},
+		// второе устройство dave - с тегом device_id
+		{UserID: "dave", DeviceID: "phone", PublicKey: "MBtcB0W7n7mWBwNmPBMOWcBSFD5pxmv1/ezYhECTFmY=", AllowedIP: "10.8.0.6/32", Enabled: true},
+	}
+
+	got := RenderWGQuickConfig(base, clients)
+
+	for _, want := range []string{
+		"PostUp = iptables -A FORWARD -i %i -j ACCEPT",
+		"PostDown = iptables -D FORWARD -i %i -j ACCEPT",
+		"PublicKey = gN65BkIKy1eCE9pP1wdc8ROUtkHLF2PfAqYdyYBz6EA=", // чужой пир сохраняется
+		"# user_id = bob\nPublicKey = TrMvSoP4jYQlY6RIzBgbssQqY3vxI2Pi+y71lOWWXX0=\nAllowedIPs = 10.8.0.3/32\n",
+		"# user_id = dave\nPublicKey = Oq8ZsPPyH4rUVMZW0RbUzJwoYxX6zPuMr+sAoTsB9Ho=",
+		"# user_id = dave\n# device_id = phone\nPublicKey = MBtcB0W7n7mWBwNmPBMOWcBSFD5pxmv1/ezYhECTFmY=",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("rendered config missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "alice") || strings.Contains(got, "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=") {
+		t.Errorf("disabled client should be removed:\n%s", got)
+	}
+
+	// Ручная секция с тегом user_id, но без клиента (импорт пропустил) остаётся
+	if kept := RenderWGQuickConfig(base, clients[1:]); !strings.Contains(kept, "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=") {
+		t.Errorf("hand-written tagged section should be kept:\n%s", kept)
+	}
+	if strings.Index(got, "bob") > strings.Index(got, "gN65BkIKy1eCE9pP1wdc8ROUtkHLF2PfAqYdyYBz6EA=") {
+		t.Errorf("managed peer should keep its position:\n%s", got)
+	}
+
+	// Повторный рендер результата стабилен
+	reparsed, err := ParseWGQuickConfig(strings.NewReader(got))
+	if err != nil {
+		t.Fatalf("reparse error = %v", err)
+	}
+	if again := RenderWGQuickConfig(reparsed, clients); again != got {
+		t.Errorf("render is not idempotent:\n%s\n---\n%s", got, again)
+	}
+
+	// Секция агента без клиента (удалён или ключ перевыпущен) убирается
+	if stale := RenderWGQuickConfig(reparsed, clients[:3]); strings.Contains(stale, "MBtcB0W7n7mWBwNmPBMOWcBSFD5pxmv1/ezYhECTFmY=") {
+		t.Errorf("managed section of a deleted client should be removed:\n%s", stale)
+	}
+
+	// Импорт восстанавливает устройства по тегу device_id
+	store := NewClientStore()
+	ImportPeers(store, reparsed, ImportOptions{})
+	if d, ok := store.GetDevice("dave", "phone"); !ok || d.AllowedIP != "10.8.0.6/32" {
+		t.Errorf("imported device = %+v, %v", d, ok)
+	}
+	if d, ok := store.Get("dave"); !ok || d.AllowedIP != "10.8.0.5/32" {
+		t.Errorf("imported primary device = %+v, %v", d, ok)
+	}
+}
+
+func TestRenderWGQuickConfig_ImportedPeer(t *testing.T) {
+	const config = `[Interface]
+Address = 10.8.0.1/24
+
+# office laptop
+[Peer]
+# user_id = erin
+PublicKey = xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=
+AllowedIPs = 10.8.0.7/32
+PersistentKeepalive = 15 # behind NAT
+`
+	base, err := ParseWGQuickConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("ParseWGQuickConfig() error = %v", err)
+	}
+	clients := []*ClientData{{
+		UserID:       "erin",
+		PublicKey:    "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=",
+		PresharedKey: "FpCyhws9cxwWoV4xELtfJvjJN+zQVRPISllRWgeopVE=",
+		AllowedIP:    "10.8.0.7/32",
+		Enabled:      true,
+	}}
+
+	// Секция переписывается на месте: ключи из клиента, прочие строки как были
+	got := RenderWGQuickConfig(base, clients)
+	want := `# office laptop
+[Peer]
+# wg-agent: managed
+# user_id = erin
+PublicKey = xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=
+PresharedKey = FpCyhws9cxwWoV4xELtfJvjJN+zQVRPISllRWgeopVE=
+AllowedIPs = 10.8.0.7/32
+PersistentKeepalive = 15 # behind NAT
+`
+	if !strings.HasSuffix(got, want) {
+		t.Errorf("rendered config:\n%s\nwant section:\n%s", got, want)
+	}
+
+	reparsed, err := ParseWGQuickConfig(strings.NewReader(got))
+	if err != nil {
+		t.Fatalf("reparse error = %v", err)
+	}
+	if again := RenderWGQuickConfig(reparsed, clients); again != got {
+		t.Errorf("render is not idempotent:\n%s\n---\n%s", got, again)
+	}
+}
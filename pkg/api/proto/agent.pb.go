@@ -22,10 +22,171 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// ErrorReason - машиночитаемая причина ошибки. Передаётся в деталях статуса
+// gRPC как google.rpc.ErrorInfo{reason: <имя значения>, domain: "wg-agent"},
+// в metadata - подробности (user_id, ip, pool, field).
+type ErrorReason int32
+
+const (
+	ErrorReason_ERROR_REASON_UNSPECIFIED ErrorReason = 0
+	ErrorReason_INVALID_ARGUMENT         ErrorReason = 1  // InvalidArgument: поле запроса, см. metadata.field и BadRequest
+	ErrorReason_CLIENT_NOT_FOUND         ErrorReason = 2  // NotFound
+	ErrorReason_CLIENT_ALREADY_EXISTS    ErrorReason = 3  // AlreadyExists
+	ErrorReason_PUBLIC_KEY_IN_USE        ErrorReason = 4  // AlreadyExists: ключ уже у другого клиента
+	ErrorReason_UNKNOWN_POOL             ErrorReason = 5  // InvalidArgument
+	ErrorReason_IP_OUTSIDE_POOL          ErrorReason = 6  // InvalidArgument
+	ErrorReason_IP_IN_USE                ErrorReason = 7  // AlreadyExists
+	ErrorReason_IP_RESERVED              ErrorReason = 8  // FailedPrecondition: закреплён за другим user_id; InvalidArgument: служебный адрес
+	ErrorReason_IP_QUARANTINED           ErrorReason = 9  // FailedPrecondition: metadata.until
+	ErrorReason_POOL_EXHAUSTED           ErrorReason = 10 // ResourceExhausted
+	ErrorReason_RESERVATION_NOT_FOUND    ErrorReason = 11 // NotFound
+	ErrorReason_RESERVATION_EXISTS       ErrorReason = 12 // FailedPrecondition: у user_id уже есть резервирование в пуле
+	ErrorReason_NOT_QUARANTINED          ErrorReason = 13 // NotFound
+	ErrorReason_PRESHARED_KEY_DISABLED   ErrorReason = 14 // FailedPrecondition: WG_AGENT_PSK_POLICY=off
+	ErrorReason_PRIVATE_KEY_UNAVAILABLE  ErrorReason = 15 // FailedPrecondition: клиент хранит свой приватный ключ
+	ErrorReason_SERVER_NOT_CONFIGURED    ErrorReason = 16 // FailedPrecondition: не задан SERVER_PUBLIC_IP
+	ErrorReason_DEVICE_UNAVAILABLE       ErrorReason = 17 // Unavailable: интерфейс WireGuard недоступен, запрос можно повторить
+	ErrorReason_STORAGE_FAILED           ErrorReason = 18 // Internal: не удалось сохранить состояние
+	ErrorReason_INTERNAL                 ErrorReason = 19 // Internal
+	ErrorReason_IDEMPOTENCY_KEY_REUSED   ErrorReason = 20 // InvalidArgument: idempotency_key уже использован для другого user_id
+	ErrorReason_DEVICE_NOT_FOUND         ErrorReason = 21 // NotFound
+	ErrorReason_DEVICE_ALREADY_EXISTS    ErrorReason = 22 // AlreadyExists
+	ErrorReason_DEVICE_LIMIT_EXCEEDED    ErrorReason = 23 // ResourceExhausted: metadata.max_devices
+	ErrorReason_SUBSCRIPTION_EXPIRED     ErrorReason = 24 // FailedPrecondition: EnableClient после expires_at, продлить через ExtendClient(enable=true)
+)
+
+// Enum value maps for ErrorReason.
+var (
+	ErrorReason_name = map[int32]string{
+		0:  "ERROR_REASON_UNSPECIFIED",
+		1:  "INVALID_ARGUMENT",
+		2:  "CLIENT_NOT_FOUND",
+		3:  "CLIENT_ALREADY_EXISTS",
+		4:  "PUBLIC_KEY_IN_USE",
+		5:  "UNKNOWN_POOL",
+		6:  "IP_OUTSIDE_POOL",
+		7:  "IP_IN_USE",
+		8:  "IP_RESERVED",
+		9:  "IP_QUARANTINED",
+		10: "POOL_EXHAUSTED",
+		11: "RESERVATION_NOT_FOUND",
+		12: "RESERVATION_EXISTS",
+		13: "NOT_QUARANTINED",
+		14: "PRESHARED_KEY_DISABLED",
+		15: "PRIVATE_KEY_UNAVAILABLE",
+		16: "SERVER_NOT_CONFIGURED",
+		17: "DEVICE_UNAVAILABLE",
+		18: "STORAGE_FAILED",
+		19: "INTERNAL",
+		20: "IDEMPOTENCY_KEY_REUSED",
+		21: "DEVICE_NOT_FOUND",
+		22: "DEVICE_ALREADY_EXISTS",
+		23: "DEVICE_LIMIT_EXCEEDED",
+		24: "SUBSCRIPTION_EXPIRED",
+	}
+	ErrorReason_value = map[string]int32{
+		"ERROR_REASON_UNSPECIFIED": 0,
+		"INVALID_ARGUMENT":         1,
+		"CLIENT_NOT_FOUND":         2,
+		"CLIENT_ALREADY_EXISTS":    3,
+		"PUBLIC_KEY_IN_USE":        4,
+		"UNKNOWN_POOL":             5,
+		"IP_OUTSIDE_POOL":          6,
+		"IP_IN_USE":                7,
+		"IP_RESERVED":              8,
+		"IP_QUARANTINED":           9,
+		"POOL_EXHAUSTED":           10,
+		"RESERVATION_NOT_FOUND":    11,
+		"RESERVATION_EXISTS":       12,
+		"NOT_QUARANTINED":          13,
+		"PRESHARED_KEY_DISABLED":   14,
+		"PRIVATE_KEY_UNAVAILABLE":  15,
+		"SERVER_NOT_CONFIGURED":    16,
+		"DEVICE_UNAVAILABLE":       17,
+		"STORAGE_FAILED":           18,
+		"INTERNAL":                 19,
+		"IDEMPOTENCY_KEY_REUSED":   20,
+		"DEVICE_NOT_FOUND":         21,
+		"DEVICE_ALREADY_EXISTS":    22,
+		"DEVICE_LIMIT_EXCEEDED":    23,
+		"SUBSCRIPTION_EXPIRED":     24,
+	}
+)
+
+func (x ErrorReason) Enum() *ErrorReason {
+	p := new(ErrorReason)
+	*p = x
+	return p
+}
+
+func (x ErrorReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ErrorReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_agent_proto_enumTypes[0].Descriptor()
+}
+
+func (ErrorReason) Type() protoreflect.EnumType {
+	return &file_api_proto_agent_proto_enumTypes[0]
+}
+
+func (x ErrorReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ErrorReason.Descriptor instead.
+func (ErrorReason) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{0}
+}
+
 type CreateClientRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Уникальный ID пользователя из твоей системы (telegram user_id, username, и т.д.)
-	UserId        string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Публичный ключ клиента (опционально, base64).
+	// Если задан - ключи генерирует сам клиент, агент не хранит приватный ключ,
+	// а config_file возвращается шаблоном с PrivateKey = <YOUR_PRIVATE_KEY>.
+	PublicKey string `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	// Сгенерировать preshared key. Учитывается, если на сервере
+	// WG_AGENT_PSK_POLICY=per-request; при always ключ генерируется всегда
+	PresharedKey bool `protobuf:"varint,3,opt,name=preshared_key,json=presharedKey,proto3" json:"preshared_key,omitempty"`
+	// Параметры QR кода (опционально, по умолчанию PNG)
+	QrOptions *QrOptions `protobuf:"bytes,4,opt,name=qr_options,json=qrOptions,proto3" json:"qr_options,omitempty"`
+	// Конкретный адрес клиента (опционально, "10.8.0.50" или IPv6).
+	// Должен быть в подсети и свободен либо зарезервирован за этим user_id.
+	// Без него используется резервирование user_id или первый свободный адрес.
+	RequestedIp string `protobuf:"bytes,5,opt,name=requested_ip,json=requestedIp,proto3" json:"requested_ip,omitempty"`
+	// Пул адресов (тариф), например "paid" из WG_AGENT_POOLS (опционально).
+	// Если пусто - пул по умолчанию (WG_AGENT_DEFAULT_POOL)
+	Pool string `protobuf:"bytes,6,opt,name=pool,proto3" json:"pool,omitempty"`
+	// Параметры конфига клиента (опционально), незаданные берутся из настроек сервера.
+	// Сохраняются вместе с клиентом: GetClientConfig вернёт тот же файл
+	ConfigOptions *ClientConfigOptions `protobuf:"bytes,7,opt,name=config_options,json=configOptions,proto3" json:"config_options,omitempty"`
+	// Дополнительные форматы конфига (опционально): "json", "mobileconfig",
+	// "mobileconfig-macos", "nmconnection", "wg-quick". Возвращаются в artifacts
+	OutputFormats []string `protobuf:"bytes,8,rep,name=output_formats,json=outputFormats,proto3" json:"output_formats,omitempty"`
+	// Формат deep_link (опционально, по умолчанию wireguard-v1)
+	DeepLinkOptions *DeepLinkOptions `protobuf:"bytes,9,opt,name=deep_link_options,json=deepLinkOptions,proto3" json:"deep_link_options,omitempty"`
+	// Имя туннеля в приложении (опционально, 1-15 символов a-z, A-Z, 0-9, _=+.-).
+	// Используется как имя файла конфига, в deep link и профилях. По умолчанию - интерфейс (wg0)
+	TunnelName string `protobuf:"bytes,10,opt,name=tunnel_name,json=tunnelName,proto3" json:"tunnel_name,omitempty"`
+	// Произвольные метки клиента (опционально), по ним фильтрует ListClients
+	Labels map[string]string `protobuf:"bytes,11,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Ключ идемпотентности (опционально, до 128 символов), например ID операции в боте.
+	// Повтор запроса с тем же ключом в течение WG_AGENT_IDEMPOTENCY_TTL не создаёт
+	// клиента заново и не возвращает ошибку, а отдаёт конфиг уже созданного (result=replayed)
+	IdempotencyKey string `protobuf:"bytes,12,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	// Что делать, если клиент с user_id уже есть (опционально):
+	// "fail" (по умолчанию) - ошибка AlreadyExists,
+	// "return_existing" - вернуть конфиг существующего клиента (result=existing),
+	// "rotate" - перевыпустить ключи, как RotateClientKeys (result=rotated).
+	// Для существующего клиента pool, requested_ip, config_options, tunnel_name,
+	// labels и expires_at не применяются
+	IfExists string `protobuf:"bytes,13,opt,name=if_exists,json=ifExists,proto3" json:"if_exists,omitempty"`
+	// Срок подписки (опционально, unix timestamp). После него (плюс
+	// WG_AGENT_EXPIRY_GRACE) агент отключает клиента. 0 - бессрочно
+	ExpiresAt     int64 `protobuf:"varint,14,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -67,38 +228,124 @@ func (x *CreateClientRequest) GetUserId() string {
 	return ""
 }
 
-type CreateClientResponse struct {
+func (x *CreateClientRequest) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *CreateClientRequest) GetPresharedKey() bool {
+	if x != nil {
+		return x.PresharedKey
+	}
+	return false
+}
+
+func (x *CreateClientRequest) GetQrOptions() *QrOptions {
+	if x != nil {
+		return x.QrOptions
+	}
+	return nil
+}
+
+func (x *CreateClientRequest) GetRequestedIp() string {
+	if x != nil {
+		return x.RequestedIp
+	}
+	return ""
+}
+
+func (x *CreateClientRequest) GetPool() string {
+	if x != nil {
+		return x.Pool
+	}
+	return ""
+}
+
+func (x *CreateClientRequest) GetConfigOptions() *ClientConfigOptions {
+	if x != nil {
+		return x.ConfigOptions
+	}
+	return nil
+}
+
+func (x *CreateClientRequest) GetOutputFormats() []string {
+	if x != nil {
+		return x.OutputFormats
+	}
+	return nil
+}
+
+func (x *CreateClientRequest) GetDeepLinkOptions() *DeepLinkOptions {
+	if x != nil {
+		return x.DeepLinkOptions
+	}
+	return nil
+}
+
+func (x *CreateClientRequest) GetTunnelName() string {
+	if x != nil {
+		return x.TunnelName
+	}
+	return ""
+}
+
+func (x *CreateClientRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *CreateClientRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *CreateClientRequest) GetIfExists() string {
+	if x != nil {
+		return x.IfExists
+	}
+	return ""
+}
+
+func (x *CreateClientRequest) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+// DeepLinkOptions - формат ссылки для импорта туннеля
+type DeepLinkOptions struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
-	// Конфигурационный файл для клиента (.conf)
-	// Можно сохранить как файл и импортировать в WireGuard
-	ConfigFile string `protobuf:"bytes,1,opt,name=config_file,json=configFile,proto3" json:"config_file,omitempty"`
-	// QR код в base64 (PNG изображение)
-	// Для сканирования мобильным приложением
-	QrCodeBase64 string `protobuf:"bytes,2,opt,name=qr_code_base64,json=qrCodeBase64,proto3" json:"qr_code_base64,omitempty"`
-	// Deep link для автоимпорта в приложение WireGuard
-	// Формат: wireguard://import/<base64_config>
-	// На мобилке откроется приложение и предложит добавить туннель
-	DeepLink string `protobuf:"bytes,3,opt,name=deep_link,json=deepLink,proto3" json:"deep_link,omitempty"`
-	// Выделенный IP адрес клиента
-	ClientIp      string `protobuf:"bytes,4,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	// "wireguard-v1" (по умолчанию) - wireguard://tunnels/add/<base64>, прежний формат;
+	// "wireguard" - wireguard://tunnels/add/<base64url>?v=2&name=<туннель>[&z=1];
+	// "amnezia" - vpn://<base64url> ключ для AmneziaVPN
+	Scheme string `protobuf:"bytes,1,opt,name=scheme,proto3" json:"scheme,omitempty"`
+	// Сжать конфиг zlib (для "wireguard", у "amnezia" сжатие всегда)
+	Compress      bool `protobuf:"varint,2,opt,name=compress,proto3" json:"compress,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CreateClientResponse) Reset() {
-	*x = CreateClientResponse{}
+func (x *DeepLinkOptions) Reset() {
+	*x = DeepLinkOptions{}
 	mi := &file_api_proto_agent_proto_msgTypes[1]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CreateClientResponse) String() string {
+func (x *DeepLinkOptions) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CreateClientResponse) ProtoMessage() {}
+func (*DeepLinkOptions) ProtoMessage() {}
 
-func (x *CreateClientResponse) ProtoReflect() protoreflect.Message {
+func (x *DeepLinkOptions) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_agent_proto_msgTypes[1]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -110,60 +357,60 @@ func (x *CreateClientResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CreateClientResponse.ProtoReflect.Descriptor instead.
-func (*CreateClientResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use DeepLinkOptions.ProtoReflect.Descriptor instead.
+func (*DeepLinkOptions) Descriptor() ([]byte, []int) {
 	return file_api_proto_agent_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *CreateClientResponse) GetConfigFile() string {
-	if x != nil {
-		return x.ConfigFile
-	}
-	return ""
-}
-
-func (x *CreateClientResponse) GetQrCodeBase64() string {
-	if x != nil {
-		return x.QrCodeBase64
-	}
-	return ""
-}
-
-func (x *CreateClientResponse) GetDeepLink() string {
+func (x *DeepLinkOptions) GetScheme() string {
 	if x != nil {
-		return x.DeepLink
+		return x.Scheme
 	}
 	return ""
 }
 
-func (x *CreateClientResponse) GetClientIp() string {
+func (x *DeepLinkOptions) GetCompress() bool {
 	if x != nil {
-		return x.ClientIp
+		return x.Compress
 	}
-	return ""
+	return false
 }
 
-type DisableClientRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+// ClientConfigOptions - параметры конфига клиента помимо ключей и адресов
+type ClientConfigOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// DNS серверы ("1.1.1.1", "2606:4700:4700::1111")
+	Dns []string `protobuf:"bytes,1,rep,name=dns,proto3" json:"dns,omitempty"`
+	// Маршруты через туннель (split-tunnel), например ["10.0.0.0/8"].
+	// Если пусто - весь трафик (0.0.0.0/0, и ::/0 при IPv6)
+	AllowedIps []string `protobuf:"bytes,2,rep,name=allowed_ips,json=allowedIps,proto3" json:"allowed_ips,omitempty"`
+	// MTU туннеля (576-9000), 0 - по умолчанию
+	Mtu uint32 `protobuf:"varint,3,opt,name=mtu,proto3" json:"mtu,omitempty"`
+	// PersistentKeepalive в секундах, 0 - выключить
+	PersistentKeepalive *uint32 `protobuf:"varint,4,opt,name=persistent_keepalive,json=persistentKeepalive,proto3,oneof" json:"persistent_keepalive,omitempty"`
+	// Команды PostUp - подсказки для wg-quick на стороне клиента
+	PostUp []string `protobuf:"bytes,5,rep,name=post_up,json=postUp,proto3" json:"post_up,omitempty"`
+	// Пресет split-tunnel из WG_AGENT_SPLIT_TUNNELS: весь трафик, кроме подсетей
+	// пресета. AllowedIPs вычисляются сервером, несовместим с allowed_ips
+	SplitTunnel   string `protobuf:"bytes,6,opt,name=split_tunnel,json=splitTunnel,proto3" json:"split_tunnel,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DisableClientRequest) Reset() {
-	*x = DisableClientRequest{}
+func (x *ClientConfigOptions) Reset() {
+	*x = ClientConfigOptions{}
 	mi := &file_api_proto_agent_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DisableClientRequest) String() string {
+func (x *ClientConfigOptions) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DisableClientRequest) ProtoMessage() {}
+func (*ClientConfigOptions) ProtoMessage() {}
 
-func (x *DisableClientRequest) ProtoReflect() protoreflect.Message {
+func (x *ClientConfigOptions) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_agent_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -175,40 +422,82 @@ func (x *DisableClientRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DisableClientRequest.ProtoReflect.Descriptor instead.
-func (*DisableClientRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use ClientConfigOptions.ProtoReflect.Descriptor instead.
+func (*ClientConfigOptions) Descriptor() ([]byte, []int) {
 	return file_api_proto_agent_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *DisableClientRequest) GetUserId() string {
+func (x *ClientConfigOptions) GetDns() []string {
 	if x != nil {
-		return x.UserId
+		return x.Dns
+	}
+	return nil
+}
+
+func (x *ClientConfigOptions) GetAllowedIps() []string {
+	if x != nil {
+		return x.AllowedIps
+	}
+	return nil
+}
+
+func (x *ClientConfigOptions) GetMtu() uint32 {
+	if x != nil {
+		return x.Mtu
+	}
+	return 0
+}
+
+func (x *ClientConfigOptions) GetPersistentKeepalive() uint32 {
+	if x != nil && x.PersistentKeepalive != nil {
+		return *x.PersistentKeepalive
+	}
+	return 0
+}
+
+func (x *ClientConfigOptions) GetPostUp() []string {
+	if x != nil {
+		return x.PostUp
+	}
+	return nil
+}
+
+func (x *ClientConfigOptions) GetSplitTunnel() string {
+	if x != nil {
+		return x.SplitTunnel
 	}
 	return ""
 }
 
-type DisableClientResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"` // "disabled" или причина ошибки
+// QrOptions - параметры рендеринга QR кода
+type QrOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Формат изображения: "png" (по умолчанию) или "svg"
+	Format string `protobuf:"bytes,1,opt,name=format,proto3" json:"format,omitempty"`
+	// Размер модуля в пикселях, 1-32 (по умолчанию 8)
+	ModuleSize int32 `protobuf:"varint,2,opt,name=module_size,json=moduleSize,proto3" json:"module_size,omitempty"`
+	// Уровень коррекции ошибок: "low", "medium" (по умолчанию), "high", "highest"
+	ErrorCorrection string `protobuf:"bytes,3,opt,name=error_correction,json=errorCorrection,proto3" json:"error_correction,omitempty"`
+	// Отступ вокруг кода в модулях, 0-16 (по умолчанию 4)
+	QuietZone     *int32 `protobuf:"varint,4,opt,name=quiet_zone,json=quietZone,proto3,oneof" json:"quiet_zone,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DisableClientResponse) Reset() {
-	*x = DisableClientResponse{}
+func (x *QrOptions) Reset() {
+	*x = QrOptions{}
 	mi := &file_api_proto_agent_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DisableClientResponse) String() string {
+func (x *QrOptions) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DisableClientResponse) ProtoMessage() {}
+func (*QrOptions) ProtoMessage() {}
 
-func (x *DisableClientResponse) ProtoReflect() protoreflect.Message {
+func (x *QrOptions) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_agent_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -220,47 +509,2184 @@ func (x *DisableClientResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DisableClientResponse.ProtoReflect.Descriptor instead.
-func (*DisableClientResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use QrOptions.ProtoReflect.Descriptor instead.
+func (*QrOptions) Descriptor() ([]byte, []int) {
 	return file_api_proto_agent_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *DisableClientResponse) GetSuccess() bool {
+func (x *QrOptions) GetFormat() string {
 	if x != nil {
-		return x.Success
+		return x.Format
 	}
-	return false
+	return ""
 }
 
-func (x *DisableClientResponse) GetMessage() string {
+func (x *QrOptions) GetModuleSize() int32 {
 	if x != nil {
-		return x.Message
+		return x.ModuleSize
+	}
+	return 0
+}
+
+func (x *QrOptions) GetErrorCorrection() string {
+	if x != nil {
+		return x.ErrorCorrection
 	}
 	return ""
 }
 
-type EnableClientRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+func (x *QrOptions) GetQuietZone() int32 {
+	if x != nil && x.QuietZone != nil {
+		return *x.QuietZone
+	}
+	return 0
+}
+
+type CreateClientResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Конфигурационный файл для клиента (.conf)
+	// Можно сохранить как файл и импортировать в WireGuard
+	ConfigFile string `protobuf:"bytes,1,opt,name=config_file,json=configFile,proto3" json:"config_file,omitempty"`
+	// QR код в base64 (PNG или SVG, см. qr_options)
+	// Для сканирования мобильным приложением
+	QrCodeBase64 string `protobuf:"bytes,2,opt,name=qr_code_base64,json=qrCodeBase64,proto3" json:"qr_code_base64,omitempty"`
+	// Ссылка для импорта туннеля, формат задаётся deep_link_options.
+	// По умолчанию wireguard://tunnels/add/<base64_config>. Официальные приложения
+	// WireGuard такие ссылки не обрабатывают - нужен свой обработчик (бот, клиент)
+	DeepLink string `protobuf:"bytes,3,opt,name=deep_link,json=deepLink,proto3" json:"deep_link,omitempty"`
+	// Выделенный IP адрес клиента
+	ClientIp string `protobuf:"bytes,4,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	// Режим ключей: "server" - ключи сгенерированы агентом,
+	// "client" - клиент прислал свой публичный ключ (QR и deep link не формируются)
+	KeyMode string `protobuf:"bytes,5,opt,name=key_mode,json=keyMode,proto3" json:"key_mode,omitempty"`
+	// Используется ли preshared key
+	HasPresharedKey bool `protobuf:"varint,6,opt,name=has_preshared_key,json=hasPresharedKey,proto3" json:"has_preshared_key,omitempty"`
+	// Все адреса клиента: IPv4 и IPv6 (если включён WG_SUBNET6)
+	ClientIps []string `protobuf:"bytes,7,rep,name=client_ips,json=clientIps,proto3" json:"client_ips,omitempty"`
+	// Конфиг в форматах из output_formats, в порядке запроса
+	Artifacts []*ConfigArtifact `protobuf:"bytes,8,rep,name=artifacts,proto3" json:"artifacts,omitempty"`
+	// Предлагаемое имя файла для config_file (<tunnel_name>.conf):
+	// приложения WireGuard называют туннель по имени файла
+	ConfigFilename string `protobuf:"bytes,9,opt,name=config_filename,json=configFilename,proto3" json:"config_filename,omitempty"`
+	// Что сделал агент: "created", "existing", "rotated" (см. if_exists)
+	// или "replayed" (повтор запроса с тем же idempotency_key)
+	Result        string `protobuf:"bytes,10,opt,name=result,proto3" json:"result,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *EnableClientRequest) Reset() {
-	*x = EnableClientRequest{}
+func (x *CreateClientResponse) Reset() {
+	*x = CreateClientResponse{}
 	mi := &file_api_proto_agent_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *EnableClientRequest) String() string {
+func (x *CreateClientResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*EnableClientRequest) ProtoMessage() {}
+func (*CreateClientResponse) ProtoMessage() {}
+
+func (x *CreateClientResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateClientResponse.ProtoReflect.Descriptor instead.
+func (*CreateClientResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreateClientResponse) GetConfigFile() string {
+	if x != nil {
+		return x.ConfigFile
+	}
+	return ""
+}
+
+func (x *CreateClientResponse) GetQrCodeBase64() string {
+	if x != nil {
+		return x.QrCodeBase64
+	}
+	return ""
+}
+
+func (x *CreateClientResponse) GetDeepLink() string {
+	if x != nil {
+		return x.DeepLink
+	}
+	return ""
+}
+
+func (x *CreateClientResponse) GetClientIp() string {
+	if x != nil {
+		return x.ClientIp
+	}
+	return ""
+}
+
+func (x *CreateClientResponse) GetKeyMode() string {
+	if x != nil {
+		return x.KeyMode
+	}
+	return ""
+}
+
+func (x *CreateClientResponse) GetHasPresharedKey() bool {
+	if x != nil {
+		return x.HasPresharedKey
+	}
+	return false
+}
+
+func (x *CreateClientResponse) GetClientIps() []string {
+	if x != nil {
+		return x.ClientIps
+	}
+	return nil
+}
+
+func (x *CreateClientResponse) GetArtifacts() []*ConfigArtifact {
+	if x != nil {
+		return x.Artifacts
+	}
+	return nil
+}
+
+func (x *CreateClientResponse) GetConfigFilename() string {
+	if x != nil {
+		return x.ConfigFilename
+	}
+	return ""
+}
+
+func (x *CreateClientResponse) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+// ConfigArtifact - конфиг клиента в одном из форматов
+type ConfigArtifact struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Format        string                 `protobuf:"bytes,1,opt,name=format,proto3" json:"format,omitempty"`                              // формат из output_formats
+	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`                          // предлагаемое имя файла (wg0.mobileconfig)
+	ContentType   string                 `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"` // MIME тип
+	Content       []byte                 `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigArtifact) Reset() {
+	*x = ConfigArtifact{}
+	mi := &file_api_proto_agent_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigArtifact) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigArtifact) ProtoMessage() {}
+
+func (x *ConfigArtifact) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigArtifact.ProtoReflect.Descriptor instead.
+func (*ConfigArtifact) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ConfigArtifact) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *ConfigArtifact) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *ConfigArtifact) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *ConfigArtifact) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type DisableClientRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisableClientRequest) Reset() {
+	*x = DisableClientRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisableClientRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisableClientRequest) ProtoMessage() {}
+
+func (x *DisableClientRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisableClientRequest.ProtoReflect.Descriptor instead.
+func (*DisableClientRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DisableClientRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type DisableClientResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Устарело: ошибки возвращаются статусом gRPC, если в запросе передан
+	// заголовок "x-wg-agent-errors: status". Без него (прежнее поведение)
+	// ошибка приходит как success=false и причина в message.
+	//
+	// Deprecated: Marked as deprecated in api/proto/agent.proto.
+	Success       bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"` // "disabled", "already disabled" или причина ошибки
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisableClientResponse) Reset() {
+	*x = DisableClientResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisableClientResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisableClientResponse) ProtoMessage() {}
+
+func (x *DisableClientResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisableClientResponse.ProtoReflect.Descriptor instead.
+func (*DisableClientResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{7}
+}
+
+// Deprecated: Marked as deprecated in api/proto/agent.proto.
+func (x *DisableClientResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DisableClientResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type EnableClientRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnableClientRequest) Reset() {
+	*x = EnableClientRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnableClientRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnableClientRequest) ProtoMessage() {}
+
+func (x *EnableClientRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnableClientRequest.ProtoReflect.Descriptor instead.
+func (*EnableClientRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *EnableClientRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type EnableClientResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Устарело: см. DisableClientResponse.success
+	//
+	// Deprecated: Marked as deprecated in api/proto/agent.proto.
+	Success       bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"` // "enabled", "already enabled" или причина ошибки
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnableClientResponse) Reset() {
+	*x = EnableClientResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnableClientResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnableClientResponse) ProtoMessage() {}
+
+func (x *EnableClientResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnableClientResponse.ProtoReflect.Descriptor instead.
+func (*EnableClientResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{9}
+}
+
+// Deprecated: Marked as deprecated in api/proto/agent.proto.
+func (x *EnableClientResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *EnableClientResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ExtendClientRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Новый срок подписки (unix timestamp), должен быть в будущем.
+	// Если expires_at, duration_seconds и clear_expiry не заданы - срок не меняется
+	ExpiresAt int64 `protobuf:"varint,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// Продлить на столько секунд от текущего срока (от текущего момента,
+	// если срок уже прошёл или не задан). Взаимоисключающе с expires_at
+	DurationSeconds int64 `protobuf:"varint,3,opt,name=duration_seconds,json=durationSeconds,proto3" json:"duration_seconds,omitempty"`
+	// Включить клиента, если он отключен (в том числе по истечении срока)
+	Enable bool `protobuf:"varint,4,opt,name=enable,proto3" json:"enable,omitempty"`
+	// Сделать подписку бессрочной. Взаимоисключающе с expires_at и duration_seconds
+	ClearExpiry   bool `protobuf:"varint,5,opt,name=clear_expiry,json=clearExpiry,proto3" json:"clear_expiry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExtendClientRequest) Reset() {
+	*x = ExtendClientRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExtendClientRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExtendClientRequest) ProtoMessage() {}
+
+func (x *ExtendClientRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExtendClientRequest.ProtoReflect.Descriptor instead.
+func (*ExtendClientRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ExtendClientRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ExtendClientRequest) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *ExtendClientRequest) GetDurationSeconds() int64 {
+	if x != nil {
+		return x.DurationSeconds
+	}
+	return 0
+}
+
+func (x *ExtendClientRequest) GetEnable() bool {
+	if x != nil {
+		return x.Enable
+	}
+	return false
+}
+
+func (x *ExtendClientRequest) GetClearExpiry() bool {
+	if x != nil {
+		return x.ClearExpiry
+	}
+	return false
+}
+
+type ExtendClientResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ExpiresAt     int64                  `protobuf:"varint,1,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // новый срок, 0 - бессрочно
+	Enabled       bool                   `protobuf:"varint,2,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExtendClientResponse) Reset() {
+	*x = ExtendClientResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExtendClientResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExtendClientResponse) ProtoMessage() {}
+
+func (x *ExtendClientResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExtendClientResponse.ProtoReflect.Descriptor instead.
+func (*ExtendClientResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ExtendClientResponse) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *ExtendClientResponse) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+type DeleteClientRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteClientRequest) Reset() {
+	*x = DeleteClientRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteClientRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteClientRequest) ProtoMessage() {}
+
+func (x *DeleteClientRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteClientRequest.ProtoReflect.Descriptor instead.
+func (*DeleteClientRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DeleteClientRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type CreateDeviceRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// ID устройства (опционально): 1-32 символа a-z, A-Z, 0-9, _.-,
+	// "primary" зарезервирован. Если пусто - генерируется агентом.
+	DeviceId string `protobuf:"bytes,2,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	// Лимит устройств клиента, включая основное (опционально).
+	// 0 - лимит сервера WG_AGENT_MAX_DEVICES.
+	MaxDevices int32 `protobuf:"varint,3,opt,name=max_devices,json=maxDevices,proto3" json:"max_devices,omitempty"`
+	// Публичный ключ устройства (опционально), см. CreateClientRequest
+	PublicKey string `protobuf:"bytes,4,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	// Выдать устройству preshared key (опционально), см. CreateClientRequest
+	PresharedKey bool `protobuf:"varint,5,opt,name=preshared_key,json=presharedKey,proto3" json:"preshared_key,omitempty"`
+	// Имя туннеля (опционально), по умолчанию - как у основного устройства
+	TunnelName string `protobuf:"bytes,6,opt,name=tunnel_name,json=tunnelName,proto3" json:"tunnel_name,omitempty"`
+	// Параметры QR кода (опционально)
+	QrOptions *QrOptions `protobuf:"bytes,7,opt,name=qr_options,json=qrOptions,proto3" json:"qr_options,omitempty"`
+	// Дополнительные форматы конфига (опционально), см. CreateClientRequest
+	OutputFormats []string `protobuf:"bytes,8,rep,name=output_formats,json=outputFormats,proto3" json:"output_formats,omitempty"`
+	// Формат deep_link (опционально), см. CreateClientRequest
+	DeepLinkOptions *DeepLinkOptions `protobuf:"bytes,9,opt,name=deep_link_options,json=deepLinkOptions,proto3" json:"deep_link_options,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *CreateDeviceRequest) Reset() {
+	*x = CreateDeviceRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateDeviceRequest) ProtoMessage() {}
+
+func (x *CreateDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDeviceRequest.ProtoReflect.Descriptor instead.
+func (*CreateDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CreateDeviceRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *CreateDeviceRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *CreateDeviceRequest) GetMaxDevices() int32 {
+	if x != nil {
+		return x.MaxDevices
+	}
+	return 0
+}
+
+func (x *CreateDeviceRequest) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *CreateDeviceRequest) GetPresharedKey() bool {
+	if x != nil {
+		return x.PresharedKey
+	}
+	return false
+}
+
+func (x *CreateDeviceRequest) GetTunnelName() string {
+	if x != nil {
+		return x.TunnelName
+	}
+	return ""
+}
+
+func (x *CreateDeviceRequest) GetQrOptions() *QrOptions {
+	if x != nil {
+		return x.QrOptions
+	}
+	return nil
+}
+
+func (x *CreateDeviceRequest) GetOutputFormats() []string {
+	if x != nil {
+		return x.OutputFormats
+	}
+	return nil
+}
+
+func (x *CreateDeviceRequest) GetDeepLinkOptions() *DeepLinkOptions {
+	if x != nil {
+		return x.DeepLinkOptions
+	}
+	return nil
+}
+
+type CreateDeviceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId      string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Config        *ClientConfigResponse  `protobuf:"bytes,2,opt,name=config,proto3" json:"config,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateDeviceResponse) Reset() {
+	*x = CreateDeviceResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateDeviceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateDeviceResponse) ProtoMessage() {}
+
+func (x *CreateDeviceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDeviceResponse.ProtoReflect.Descriptor instead.
+func (*CreateDeviceResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *CreateDeviceResponse) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *CreateDeviceResponse) GetConfig() *ClientConfigResponse {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+type ListDevicesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDevicesRequest) Reset() {
+	*x = ListDevicesRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesRequest) ProtoMessage() {}
+
+func (x *ListDevicesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesRequest.ProtoReflect.Descriptor instead.
+func (*ListDevicesRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListDevicesRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type ListDevicesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Devices       []*DeviceInfo          `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"` // основное устройство первым
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDevicesResponse) Reset() {
+	*x = ListDevicesResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesResponse) ProtoMessage() {}
+
+func (x *ListDevicesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesResponse.ProtoReflect.Descriptor instead.
+func (*ListDevicesResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ListDevicesResponse) GetDevices() []*DeviceInfo {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+type DeviceInfo struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId        string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"` // "primary" у основного устройства
+	ClientIp        string                 `protobuf:"bytes,2,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	ClientIps       []string               `protobuf:"bytes,3,rep,name=client_ips,json=clientIps,proto3" json:"client_ips,omitempty"`
+	Enabled         bool                   `protobuf:"varint,4,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	KeyMode         string                 `protobuf:"bytes,5,opt,name=key_mode,json=keyMode,proto3" json:"key_mode,omitempty"`
+	HasPresharedKey bool                   `protobuf:"varint,6,opt,name=has_preshared_key,json=hasPresharedKey,proto3" json:"has_preshared_key,omitempty"`
+	TunnelName      string                 `protobuf:"bytes,7,opt,name=tunnel_name,json=tunnelName,proto3" json:"tunnel_name,omitempty"`
+	LastHandshake   int64                  `protobuf:"varint,8,opt,name=last_handshake,json=lastHandshake,proto3" json:"last_handshake,omitempty"`
+	RxBytes         int64                  `protobuf:"varint,9,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"`
+	TxBytes         int64                  `protobuf:"varint,10,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *DeviceInfo) Reset() {
+	*x = DeviceInfo{}
+	mi := &file_api_proto_agent_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceInfo) ProtoMessage() {}
+
+func (x *DeviceInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceInfo.ProtoReflect.Descriptor instead.
+func (*DeviceInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *DeviceInfo) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *DeviceInfo) GetClientIp() string {
+	if x != nil {
+		return x.ClientIp
+	}
+	return ""
+}
+
+func (x *DeviceInfo) GetClientIps() []string {
+	if x != nil {
+		return x.ClientIps
+	}
+	return nil
+}
+
+func (x *DeviceInfo) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *DeviceInfo) GetKeyMode() string {
+	if x != nil {
+		return x.KeyMode
+	}
+	return ""
+}
+
+func (x *DeviceInfo) GetHasPresharedKey() bool {
+	if x != nil {
+		return x.HasPresharedKey
+	}
+	return false
+}
+
+func (x *DeviceInfo) GetTunnelName() string {
+	if x != nil {
+		return x.TunnelName
+	}
+	return ""
+}
+
+func (x *DeviceInfo) GetLastHandshake() int64 {
+	if x != nil {
+		return x.LastHandshake
+	}
+	return 0
+}
+
+func (x *DeviceInfo) GetRxBytes() int64 {
+	if x != nil {
+		return x.RxBytes
+	}
+	return 0
+}
+
+func (x *DeviceInfo) GetTxBytes() int64 {
+	if x != nil {
+		return x.TxBytes
+	}
+	return 0
+}
+
+type DeleteDeviceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	DeviceId      string                 `protobuf:"bytes,2,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteDeviceRequest) Reset() {
+	*x = DeleteDeviceRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteDeviceRequest) ProtoMessage() {}
+
+func (x *DeleteDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteDeviceRequest.ProtoReflect.Descriptor instead.
+func (*DeleteDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *DeleteDeviceRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *DeleteDeviceRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+type GetClientRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetClientRequest) Reset() {
+	*x = GetClientRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetClientRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetClientRequest) ProtoMessage() {}
+
+func (x *GetClientRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetClientRequest.ProtoReflect.Descriptor instead.
+func (*GetClientRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *GetClientRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type GetClientResponse struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	UserId   string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	ClientIp string                 `protobuf:"bytes,2,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	Enabled  bool                   `protobuf:"varint,3,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	// Статистика (если клиент подключен)
+	RxBytes         int64             `protobuf:"varint,4,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"`                   // скачано байт
+	TxBytes         int64             `protobuf:"varint,5,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`                   // отправлено байт
+	LastHandshake   int64             `protobuf:"varint,6,opt,name=last_handshake,json=lastHandshake,proto3" json:"last_handshake,omitempty"` // unix timestamp последнего подключения (0 = никогда)
+	KeyMode         string            `protobuf:"bytes,7,opt,name=key_mode,json=keyMode,proto3" json:"key_mode,omitempty"`                    // "server" или "client", см. CreateClientResponse
+	HasPresharedKey bool              `protobuf:"varint,8,opt,name=has_preshared_key,json=hasPresharedKey,proto3" json:"has_preshared_key,omitempty"`
+	ClientIps       []string          `protobuf:"bytes,9,rep,name=client_ips,json=clientIps,proto3" json:"client_ips,omitempty"` // IPv4 и IPv6 адреса клиента
+	Pool            string            `protobuf:"bytes,10,opt,name=pool,proto3" json:"pool,omitempty"`                           // пул адресов клиента
+	TunnelName      string            `protobuf:"bytes,11,opt,name=tunnel_name,json=tunnelName,proto3" json:"tunnel_name,omitempty"`
+	Labels          map[string]string `protobuf:"bytes,12,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	DeviceCount     int32             `protobuf:"varint,13,opt,name=device_count,json=deviceCount,proto3" json:"device_count,omitempty"` // число устройств клиента, включая основное
+	ExpiresAt       int64             `protobuf:"varint,14,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`       // срок подписки (unix timestamp), 0 - бессрочно
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *GetClientResponse) Reset() {
+	*x = GetClientResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetClientResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetClientResponse) ProtoMessage() {}
+
+func (x *GetClientResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetClientResponse.ProtoReflect.Descriptor instead.
+func (*GetClientResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GetClientResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *GetClientResponse) GetClientIp() string {
+	if x != nil {
+		return x.ClientIp
+	}
+	return ""
+}
+
+func (x *GetClientResponse) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *GetClientResponse) GetRxBytes() int64 {
+	if x != nil {
+		return x.RxBytes
+	}
+	return 0
+}
+
+func (x *GetClientResponse) GetTxBytes() int64 {
+	if x != nil {
+		return x.TxBytes
+	}
+	return 0
+}
+
+func (x *GetClientResponse) GetLastHandshake() int64 {
+	if x != nil {
+		return x.LastHandshake
+	}
+	return 0
+}
+
+func (x *GetClientResponse) GetKeyMode() string {
+	if x != nil {
+		return x.KeyMode
+	}
+	return ""
+}
+
+func (x *GetClientResponse) GetHasPresharedKey() bool {
+	if x != nil {
+		return x.HasPresharedKey
+	}
+	return false
+}
+
+func (x *GetClientResponse) GetClientIps() []string {
+	if x != nil {
+		return x.ClientIps
+	}
+	return nil
+}
+
+func (x *GetClientResponse) GetPool() string {
+	if x != nil {
+		return x.Pool
+	}
+	return ""
+}
+
+func (x *GetClientResponse) GetTunnelName() string {
+	if x != nil {
+		return x.TunnelName
+	}
+	return ""
+}
+
+func (x *GetClientResponse) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *GetClientResponse) GetDeviceCount() int32 {
+	if x != nil {
+		return x.DeviceCount
+	}
+	return 0
+}
+
+func (x *GetClientResponse) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type GetClientConfigRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Параметры QR кода (опционально)
+	QrOptions *QrOptions `protobuf:"bytes,2,opt,name=qr_options,json=qrOptions,proto3" json:"qr_options,omitempty"`
+	// Дополнительные форматы конфига (опционально), см. CreateClientRequest
+	OutputFormats []string `protobuf:"bytes,3,rep,name=output_formats,json=outputFormats,proto3" json:"output_formats,omitempty"`
+	// Формат deep_link (опционально), см. CreateClientRequest
+	DeepLinkOptions *DeepLinkOptions `protobuf:"bytes,4,opt,name=deep_link_options,json=deepLinkOptions,proto3" json:"deep_link_options,omitempty"`
+	// Устройство клиента (опционально), пусто или "primary" - основное
+	DeviceId      string `protobuf:"bytes,5,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetClientConfigRequest) Reset() {
+	*x = GetClientConfigRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetClientConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetClientConfigRequest) ProtoMessage() {}
+
+func (x *GetClientConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetClientConfigRequest.ProtoReflect.Descriptor instead.
+func (*GetClientConfigRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GetClientConfigRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *GetClientConfigRequest) GetQrOptions() *QrOptions {
+	if x != nil {
+		return x.QrOptions
+	}
+	return nil
+}
+
+func (x *GetClientConfigRequest) GetOutputFormats() []string {
+	if x != nil {
+		return x.OutputFormats
+	}
+	return nil
+}
+
+func (x *GetClientConfigRequest) GetDeepLinkOptions() *DeepLinkOptions {
+	if x != nil {
+		return x.DeepLinkOptions
+	}
+	return nil
+}
+
+func (x *GetClientConfigRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+type ListClientsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Только клиенты со всеми перечисленными метками (опционально)
+	Labels        map[string]string `protobuf:"bytes,1,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListClientsRequest) Reset() {
+	*x = ListClientsRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListClientsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListClientsRequest) ProtoMessage() {}
+
+func (x *ListClientsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListClientsRequest.ProtoReflect.Descriptor instead.
+func (*ListClientsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ListClientsRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type ListClientsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Clients       []*ClientInfo          `protobuf:"bytes,1,rep,name=clients,proto3" json:"clients,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListClientsResponse) Reset() {
+	*x = ListClientsResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListClientsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListClientsResponse) ProtoMessage() {}
+
+func (x *ListClientsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListClientsResponse.ProtoReflect.Descriptor instead.
+func (*ListClientsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ListClientsResponse) GetClients() []*ClientInfo {
+	if x != nil {
+		return x.Clients
+	}
+	return nil
+}
+
+type ClientInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	ClientIp      string                 `protobuf:"bytes,2,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	Enabled       bool                   `protobuf:"varint,3,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	LastHandshake int64                  `protobuf:"varint,4,opt,name=last_handshake,json=lastHandshake,proto3" json:"last_handshake,omitempty"`
+	KeyMode       string                 `protobuf:"bytes,5,opt,name=key_mode,json=keyMode,proto3" json:"key_mode,omitempty"`
+	ClientIps     []string               `protobuf:"bytes,6,rep,name=client_ips,json=clientIps,proto3" json:"client_ips,omitempty"`
+	Pool          string                 `protobuf:"bytes,7,opt,name=pool,proto3" json:"pool,omitempty"`
+	TunnelName    string                 `protobuf:"bytes,8,opt,name=tunnel_name,json=tunnelName,proto3" json:"tunnel_name,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,9,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	DeviceCount   int32                  `protobuf:"varint,10,opt,name=device_count,json=deviceCount,proto3" json:"device_count,omitempty"` // число устройств клиента, включая основное
+	ExpiresAt     int64                  `protobuf:"varint,11,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`       // срок подписки (unix timestamp), 0 - бессрочно
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClientInfo) Reset() {
+	*x = ClientInfo{}
+	mi := &file_api_proto_agent_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClientInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientInfo) ProtoMessage() {}
+
+func (x *ClientInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientInfo.ProtoReflect.Descriptor instead.
+func (*ClientInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ClientInfo) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ClientInfo) GetClientIp() string {
+	if x != nil {
+		return x.ClientIp
+	}
+	return ""
+}
+
+func (x *ClientInfo) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *ClientInfo) GetLastHandshake() int64 {
+	if x != nil {
+		return x.LastHandshake
+	}
+	return 0
+}
+
+func (x *ClientInfo) GetKeyMode() string {
+	if x != nil {
+		return x.KeyMode
+	}
+	return ""
+}
+
+func (x *ClientInfo) GetClientIps() []string {
+	if x != nil {
+		return x.ClientIps
+	}
+	return nil
+}
+
+func (x *ClientInfo) GetPool() string {
+	if x != nil {
+		return x.Pool
+	}
+	return ""
+}
+
+func (x *ClientInfo) GetTunnelName() string {
+	if x != nil {
+		return x.TunnelName
+	}
+	return ""
+}
+
+func (x *ClientInfo) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *ClientInfo) GetDeviceCount() int32 {
+	if x != nil {
+		return x.DeviceCount
+	}
+	return 0
+}
+
+func (x *ClientInfo) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type GetReconcileStatusRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Запустить сверку прямо сейчас, а не вернуть последний результат
+	RunNow        bool `protobuf:"varint,1,opt,name=run_now,json=runNow,proto3" json:"run_now,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetReconcileStatusRequest) Reset() {
+	*x = GetReconcileStatusRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetReconcileStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReconcileStatusRequest) ProtoMessage() {}
+
+func (x *GetReconcileStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReconcileStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetReconcileStatusRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *GetReconcileStatusRequest) GetRunNow() bool {
+	if x != nil {
+		return x.RunNow
+	}
+	return false
+}
+
+type GetReconcileStatusResponse struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	LastRun             int64                  `protobuf:"varint,1,opt,name=last_run,json=lastRun,proto3" json:"last_run,omitempty"`                                      // unix timestamp последней сверки (0 = ещё не было)
+	InSync              bool                   `protobuf:"varint,2,opt,name=in_sync,json=inSync,proto3" json:"in_sync,omitempty"`                                         // true если расхождений не найдено
+	UnknownPeerPolicy   string                 `protobuf:"bytes,3,opt,name=unknown_peer_policy,json=unknownPeerPolicy,proto3" json:"unknown_peer_policy,omitempty"`       // политика для неизвестных пиров: adopt, remove, ignore
+	ReaddedUserIds      []string               `protobuf:"bytes,4,rep,name=readded_user_ids,json=readdedUserIds,proto3" json:"readded_user_ids,omitempty"`                // включенные клиенты, которых не было на устройстве
+	RemovedUserIds      []string               `protobuf:"bytes,5,rep,name=removed_user_ids,json=removedUserIds,proto3" json:"removed_user_ids,omitempty"`                // отключенные клиенты, найденные на устройстве
+	UnknownPeers        []string               `protobuf:"bytes,6,rep,name=unknown_peers,json=unknownPeers,proto3" json:"unknown_peers,omitempty"`                        // публичные ключи пиров, которых нет в хранилище
+	AdoptedUserIds      []string               `protobuf:"bytes,7,rep,name=adopted_user_ids,json=adoptedUserIds,proto3" json:"adopted_user_ids,omitempty"`                // неизвестные пиры, взятые под управление (adopt)
+	RemovedUnknownPeers []string               `protobuf:"bytes,8,rep,name=removed_unknown_peers,json=removedUnknownPeers,proto3" json:"removed_unknown_peers,omitempty"` // неизвестные пиры, удалённые с устройства (remove)
+	Errors              []string               `protobuf:"bytes,9,rep,name=errors,proto3" json:"errors,omitempty"`                                                        // ошибки при сверке
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *GetReconcileStatusResponse) Reset() {
+	*x = GetReconcileStatusResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetReconcileStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReconcileStatusResponse) ProtoMessage() {}
+
+func (x *GetReconcileStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReconcileStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetReconcileStatusResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *GetReconcileStatusResponse) GetLastRun() int64 {
+	if x != nil {
+		return x.LastRun
+	}
+	return 0
+}
+
+func (x *GetReconcileStatusResponse) GetInSync() bool {
+	if x != nil {
+		return x.InSync
+	}
+	return false
+}
+
+func (x *GetReconcileStatusResponse) GetUnknownPeerPolicy() string {
+	if x != nil {
+		return x.UnknownPeerPolicy
+	}
+	return ""
+}
+
+func (x *GetReconcileStatusResponse) GetReaddedUserIds() []string {
+	if x != nil {
+		return x.ReaddedUserIds
+	}
+	return nil
+}
+
+func (x *GetReconcileStatusResponse) GetRemovedUserIds() []string {
+	if x != nil {
+		return x.RemovedUserIds
+	}
+	return nil
+}
+
+func (x *GetReconcileStatusResponse) GetUnknownPeers() []string {
+	if x != nil {
+		return x.UnknownPeers
+	}
+	return nil
+}
+
+func (x *GetReconcileStatusResponse) GetAdoptedUserIds() []string {
+	if x != nil {
+		return x.AdoptedUserIds
+	}
+	return nil
+}
+
+func (x *GetReconcileStatusResponse) GetRemovedUnknownPeers() []string {
+	if x != nil {
+		return x.RemovedUnknownPeers
+	}
+	return nil
+}
+
+func (x *GetReconcileStatusResponse) GetErrors() []string {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+type ClientConfigResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ConfigFile      string                 `protobuf:"bytes,1,opt,name=config_file,json=configFile,proto3" json:"config_file,omitempty"` // см. CreateClientResponse
+	QrCodeBase64    string                 `protobuf:"bytes,2,opt,name=qr_code_base64,json=qrCodeBase64,proto3" json:"qr_code_base64,omitempty"`
+	DeepLink        string                 `protobuf:"bytes,3,opt,name=deep_link,json=deepLink,proto3" json:"deep_link,omitempty"`
+	ClientIp        string                 `protobuf:"bytes,4,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	KeyMode         string                 `protobuf:"bytes,5,opt,name=key_mode,json=keyMode,proto3" json:"key_mode,omitempty"`
+	HasPresharedKey bool                   `protobuf:"varint,6,opt,name=has_preshared_key,json=hasPresharedKey,proto3" json:"has_preshared_key,omitempty"`
+	ClientIps       []string               `protobuf:"bytes,7,rep,name=client_ips,json=clientIps,proto3" json:"client_ips,omitempty"`
+	Artifacts       []*ConfigArtifact      `protobuf:"bytes,8,rep,name=artifacts,proto3" json:"artifacts,omitempty"`
+	ConfigFilename  string                 `protobuf:"bytes,9,opt,name=config_filename,json=configFilename,proto3" json:"config_filename,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ClientConfigResponse) Reset() {
+	*x = ClientConfigResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClientConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientConfigResponse) ProtoMessage() {}
+
+func (x *ClientConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientConfigResponse.ProtoReflect.Descriptor instead.
+func (*ClientConfigResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ClientConfigResponse) GetConfigFile() string {
+	if x != nil {
+		return x.ConfigFile
+	}
+	return ""
+}
+
+func (x *ClientConfigResponse) GetQrCodeBase64() string {
+	if x != nil {
+		return x.QrCodeBase64
+	}
+	return ""
+}
+
+func (x *ClientConfigResponse) GetDeepLink() string {
+	if x != nil {
+		return x.DeepLink
+	}
+	return ""
+}
+
+func (x *ClientConfigResponse) GetClientIp() string {
+	if x != nil {
+		return x.ClientIp
+	}
+	return ""
+}
+
+func (x *ClientConfigResponse) GetKeyMode() string {
+	if x != nil {
+		return x.KeyMode
+	}
+	return ""
+}
+
+func (x *ClientConfigResponse) GetHasPresharedKey() bool {
+	if x != nil {
+		return x.HasPresharedKey
+	}
+	return false
+}
+
+func (x *ClientConfigResponse) GetClientIps() []string {
+	if x != nil {
+		return x.ClientIps
+	}
+	return nil
+}
+
+func (x *ClientConfigResponse) GetArtifacts() []*ConfigArtifact {
+	if x != nil {
+		return x.Artifacts
+	}
+	return nil
+}
+
+func (x *ClientConfigResponse) GetConfigFilename() string {
+	if x != nil {
+		return x.ConfigFilename
+	}
+	return ""
+}
+
+type AddPresharedKeyRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Параметры QR кода (опционально)
+	QrOptions *QrOptions `protobuf:"bytes,2,opt,name=qr_options,json=qrOptions,proto3" json:"qr_options,omitempty"`
+	// Дополнительные форматы конфига (опционально), см. CreateClientRequest
+	OutputFormats []string `protobuf:"bytes,3,rep,name=output_formats,json=outputFormats,proto3" json:"output_formats,omitempty"`
+	// Формат deep_link (опционально), см. CreateClientRequest
+	DeepLinkOptions *DeepLinkOptions `protobuf:"bytes,4,opt,name=deep_link_options,json=deepLinkOptions,proto3" json:"deep_link_options,omitempty"`
+	// Устройство клиента (опционально), пусто или "primary" - основное
+	DeviceId      string `protobuf:"bytes,5,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddPresharedKeyRequest) Reset() {
+	*x = AddPresharedKeyRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddPresharedKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddPresharedKeyRequest) ProtoMessage() {}
+
+func (x *AddPresharedKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddPresharedKeyRequest.ProtoReflect.Descriptor instead.
+func (*AddPresharedKeyRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *AddPresharedKeyRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *AddPresharedKeyRequest) GetQrOptions() *QrOptions {
+	if x != nil {
+		return x.QrOptions
+	}
+	return nil
+}
+
+func (x *AddPresharedKeyRequest) GetOutputFormats() []string {
+	if x != nil {
+		return x.OutputFormats
+	}
+	return nil
+}
+
+func (x *AddPresharedKeyRequest) GetDeepLinkOptions() *DeepLinkOptions {
+	if x != nil {
+		return x.DeepLinkOptions
+	}
+	return nil
+}
+
+func (x *AddPresharedKeyRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+type RotateClientKeysRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Новый публичный ключ клиента (опционально, base64).
+	// Если пусто - агент генерирует новую пару ключей сам.
+	PublicKey string `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	// Параметры QR кода (опционально)
+	QrOptions *QrOptions `protobuf:"bytes,3,opt,name=qr_options,json=qrOptions,proto3" json:"qr_options,omitempty"`
+	// Дополнительные форматы конфига (опционально), см. CreateClientRequest
+	OutputFormats []string `protobuf:"bytes,4,rep,name=output_formats,json=outputFormats,proto3" json:"output_formats,omitempty"`
+	// Формат deep_link (опционально), см. CreateClientRequest
+	DeepLinkOptions *DeepLinkOptions `protobuf:"bytes,5,opt,name=deep_link_options,json=deepLinkOptions,proto3" json:"deep_link_options,omitempty"`
+	// Устройство клиента (опционально), пусто или "primary" - основное.
+	// Ключи устройства меняются без смены его адреса
+	DeviceId      string `protobuf:"bytes,6,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateClientKeysRequest) Reset() {
+	*x = RotateClientKeysRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateClientKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateClientKeysRequest) ProtoMessage() {}
+
+func (x *RotateClientKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateClientKeysRequest.ProtoReflect.Descriptor instead.
+func (*RotateClientKeysRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *RotateClientKeysRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *RotateClientKeysRequest) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *RotateClientKeysRequest) GetQrOptions() *QrOptions {
+	if x != nil {
+		return x.QrOptions
+	}
+	return nil
+}
+
+func (x *RotateClientKeysRequest) GetOutputFormats() []string {
+	if x != nil {
+		return x.OutputFormats
+	}
+	return nil
+}
+
+func (x *RotateClientKeysRequest) GetDeepLinkOptions() *DeepLinkOptions {
+	if x != nil {
+		return x.DeepLinkOptions
+	}
+	return nil
+}
+
+func (x *RotateClientKeysRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+type ReserveIPRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Адрес (опционально). Если пусто - резервируется первый свободный IPv4
+	Ip string `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+	// Пул, из которого резервируется адрес, если ip пусто (опционально)
+	Pool          string `protobuf:"bytes,3,opt,name=pool,proto3" json:"pool,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReserveIPRequest) Reset() {
+	*x = ReserveIPRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReserveIPRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReserveIPRequest) ProtoMessage() {}
+
+func (x *ReserveIPRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReserveIPRequest.ProtoReflect.Descriptor instead.
+func (*ReserveIPRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ReserveIPRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ReserveIPRequest) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *ReserveIPRequest) GetPool() string {
+	if x != nil {
+		return x.Pool
+	}
+	return ""
+}
+
+type ReleaseReservationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ip            string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseReservationRequest) Reset() {
+	*x = ReleaseReservationRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseReservationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseReservationRequest) ProtoMessage() {}
+
+func (x *ReleaseReservationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseReservationRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseReservationRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ReleaseReservationRequest) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+type ListReservationsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Только резервирования этого user_id (опционально)
+	UserId        string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListReservationsRequest) Reset() {
+	*x = ListReservationsRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListReservationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListReservationsRequest) ProtoMessage() {}
+
+func (x *ListReservationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListReservationsRequest.ProtoReflect.Descriptor instead.
+func (*ListReservationsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ListReservationsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type ListReservationsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reservations  []*Reservation         `protobuf:"bytes,1,rep,name=reservations,proto3" json:"reservations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListReservationsResponse) Reset() {
+	*x = ListReservationsResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListReservationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListReservationsResponse) ProtoMessage() {}
+
+func (x *ListReservationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListReservationsResponse.ProtoReflect.Descriptor instead.
+func (*ListReservationsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ListReservationsResponse) GetReservations() []*Reservation {
+	if x != nil {
+		return x.Reservations
+	}
+	return nil
+}
+
+type Reservation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ip            string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"` // адрес с маской хоста ("10.8.0.50/32")
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // unix timestamp
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Reservation) Reset() {
+	*x = Reservation{}
+	mi := &file_api_proto_agent_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Reservation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reservation) ProtoMessage() {}
 
-func (x *EnableClientRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[4]
+func (x *Reservation) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -271,41 +2697,53 @@ func (x *EnableClientRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use EnableClientRequest.ProtoReflect.Descriptor instead.
-func (*EnableClientRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use Reservation.ProtoReflect.Descriptor instead.
+func (*Reservation) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{34}
 }
 
-func (x *EnableClientRequest) GetUserId() string {
+func (x *Reservation) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *Reservation) GetUserId() string {
 	if x != nil {
 		return x.UserId
 	}
 	return ""
 }
 
-type EnableClientResponse struct {
+func (x *Reservation) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+type ListQuarantineRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *EnableClientResponse) Reset() {
-	*x = EnableClientResponse{}
-	mi := &file_api_proto_agent_proto_msgTypes[5]
+func (x *ListQuarantineRequest) Reset() {
+	*x = ListQuarantineRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[35]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *EnableClientResponse) String() string {
+func (x *ListQuarantineRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*EnableClientResponse) ProtoMessage() {}
+func (*ListQuarantineRequest) ProtoMessage() {}
 
-func (x *EnableClientResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[5]
+func (x *ListQuarantineRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[35]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -316,47 +2754,33 @@ func (x *EnableClientResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use EnableClientResponse.ProtoReflect.Descriptor instead.
-func (*EnableClientResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{5}
-}
-
-func (x *EnableClientResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
-}
-
-func (x *EnableClientResponse) GetMessage() string {
-	if x != nil {
-		return x.Message
-	}
-	return ""
+// Deprecated: Use ListQuarantineRequest.ProtoReflect.Descriptor instead.
+func (*ListQuarantineRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{35}
 }
 
-type DeleteClientRequest struct {
+type ListQuarantineResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Entries       []*QuarantinedIP       `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DeleteClientRequest) Reset() {
-	*x = DeleteClientRequest{}
-	mi := &file_api_proto_agent_proto_msgTypes[6]
+func (x *ListQuarantineResponse) Reset() {
+	*x = ListQuarantineResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[36]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteClientRequest) String() string {
+func (x *ListQuarantineResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteClientRequest) ProtoMessage() {}
+func (*ListQuarantineResponse) ProtoMessage() {}
 
-func (x *DeleteClientRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[6]
+func (x *ListQuarantineResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[36]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -367,40 +2791,43 @@ func (x *DeleteClientRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteClientRequest.ProtoReflect.Descriptor instead.
-func (*DeleteClientRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use ListQuarantineResponse.ProtoReflect.Descriptor instead.
+func (*ListQuarantineResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{36}
 }
 
-func (x *DeleteClientRequest) GetUserId() string {
+func (x *ListQuarantineResponse) GetEntries() []*QuarantinedIP {
 	if x != nil {
-		return x.UserId
+		return x.Entries
 	}
-	return ""
+	return nil
 }
 
-type GetClientRequest struct {
+type QuarantinedIP struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Ip            string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`                                    // адрес с маской хоста ("10.8.0.5/32")
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`              // предыдущий владелец, ему адрес выдаётся и до истечения карантина
+	ReleasedAt    int64                  `protobuf:"varint,3,opt,name=released_at,json=releasedAt,proto3" json:"released_at,omitempty"` // unix timestamp
+	ExpiresAt     int64                  `protobuf:"varint,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`    // unix timestamp
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetClientRequest) Reset() {
-	*x = GetClientRequest{}
-	mi := &file_api_proto_agent_proto_msgTypes[7]
+func (x *QuarantinedIP) Reset() {
+	*x = QuarantinedIP{}
+	mi := &file_api_proto_agent_proto_msgTypes[37]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetClientRequest) String() string {
+func (x *QuarantinedIP) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetClientRequest) ProtoMessage() {}
+func (*QuarantinedIP) ProtoMessage() {}
 
-func (x *GetClientRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[7]
+func (x *QuarantinedIP) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[37]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -411,46 +2838,63 @@ func (x *GetClientRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetClientRequest.ProtoReflect.Descriptor instead.
-func (*GetClientRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use QuarantinedIP.ProtoReflect.Descriptor instead.
+func (*QuarantinedIP) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{37}
 }
 
-func (x *GetClientRequest) GetUserId() string {
+func (x *QuarantinedIP) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *QuarantinedIP) GetUserId() string {
 	if x != nil {
 		return x.UserId
 	}
 	return ""
 }
 
-type GetClientResponse struct {
-	state    protoimpl.MessageState `protogen:"open.v1"`
-	UserId   string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	ClientIp string                 `protobuf:"bytes,2,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
-	Enabled  bool                   `protobuf:"varint,3,opt,name=enabled,proto3" json:"enabled,omitempty"`
-	// Статистика (если клиент подключен)
-	RxBytes       int64 `protobuf:"varint,4,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"`                   // скачано байт
-	TxBytes       int64 `protobuf:"varint,5,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`                   // отправлено байт
-	LastHandshake int64 `protobuf:"varint,6,opt,name=last_handshake,json=lastHandshake,proto3" json:"last_handshake,omitempty"` // unix timestamp последнего подключения (0 = никогда)
+func (x *QuarantinedIP) GetReleasedAt() int64 {
+	if x != nil {
+		return x.ReleasedAt
+	}
+	return 0
+}
+
+func (x *QuarantinedIP) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type ReleaseQuarantineRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Ip    string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	// Освободить все адреса в карантине (ip игнорируется)
+	All           bool `protobuf:"varint,2,opt,name=all,proto3" json:"all,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetClientResponse) Reset() {
-	*x = GetClientResponse{}
-	mi := &file_api_proto_agent_proto_msgTypes[8]
+func (x *ReleaseQuarantineRequest) Reset() {
+	*x = ReleaseQuarantineRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[38]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetClientResponse) String() string {
+func (x *ReleaseQuarantineRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetClientResponse) ProtoMessage() {}
+func (*ReleaseQuarantineRequest) ProtoMessage() {}
 
-func (x *GetClientResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[8]
+func (x *ReleaseQuarantineRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[38]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -461,74 +2905,98 @@ func (x *GetClientResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetClientResponse.ProtoReflect.Descriptor instead.
-func (*GetClientResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use ReleaseQuarantineRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseQuarantineRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{38}
 }
 
-func (x *GetClientResponse) GetUserId() string {
+func (x *ReleaseQuarantineRequest) GetIp() string {
 	if x != nil {
-		return x.UserId
+		return x.Ip
 	}
 	return ""
 }
 
-func (x *GetClientResponse) GetClientIp() string {
+func (x *ReleaseQuarantineRequest) GetAll() bool {
 	if x != nil {
-		return x.ClientIp
+		return x.All
 	}
-	return ""
+	return false
 }
 
-func (x *GetClientResponse) GetEnabled() bool {
-	if x != nil {
-		return x.Enabled
-	}
-	return false
+type ReleaseQuarantineResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ReleasedIps   []string               `protobuf:"bytes,1,rep,name=released_ips,json=releasedIps,proto3" json:"released_ips,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetClientResponse) GetRxBytes() int64 {
-	if x != nil {
-		return x.RxBytes
-	}
-	return 0
+func (x *ReleaseQuarantineResponse) Reset() {
+	*x = ReleaseQuarantineResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetClientResponse) GetTxBytes() int64 {
+func (x *ReleaseQuarantineResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseQuarantineResponse) ProtoMessage() {}
+
+func (x *ReleaseQuarantineResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[39]
 	if x != nil {
-		return x.TxBytes
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *GetClientResponse) GetLastHandshake() int64 {
+// Deprecated: Use ReleaseQuarantineResponse.ProtoReflect.Descriptor instead.
+func (*ReleaseQuarantineResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *ReleaseQuarantineResponse) GetReleasedIps() []string {
 	if x != nil {
-		return x.LastHandshake
+		return x.ReleasedIps
 	}
-	return 0
+	return nil
 }
 
-type ListClientsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+type ImportClientsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Содержимое конфига wg-quick (wg0.conf или вывод `wg showconf`).
+	// Если пусто - читается конфиг интерфейса на сервере (/etc/wireguard/wg0.conf)
+	Config string `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+	// Соответствие public_key -> user_id.
+	// Имеет приоритет над тегом "# user_id = ..." в секции [Peer]
+	UserIds map[string]string `protobuf:"bytes,2,rep,name=user_ids,json=userIds,proto3" json:"user_ids,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Только показать результат, ничего не сохранять
+	DryRun        bool `protobuf:"varint,3,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListClientsRequest) Reset() {
-	*x = ListClientsRequest{}
-	mi := &file_api_proto_agent_proto_msgTypes[9]
+func (x *ImportClientsRequest) Reset() {
+	*x = ImportClientsRequest{}
+	mi := &file_api_proto_agent_proto_msgTypes[40]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListClientsRequest) String() string {
+func (x *ImportClientsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListClientsRequest) ProtoMessage() {}
+func (*ImportClientsRequest) ProtoMessage() {}
 
-func (x *ListClientsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[9]
+func (x *ImportClientsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[40]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -539,33 +3007,54 @@ func (x *ListClientsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListClientsRequest.ProtoReflect.Descriptor instead.
-func (*ListClientsRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use ImportClientsRequest.ProtoReflect.Descriptor instead.
+func (*ImportClientsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{40}
 }
 
-type ListClientsResponse struct {
+func (x *ImportClientsRequest) GetConfig() string {
+	if x != nil {
+		return x.Config
+	}
+	return ""
+}
+
+func (x *ImportClientsRequest) GetUserIds() map[string]string {
+	if x != nil {
+		return x.UserIds
+	}
+	return nil
+}
+
+func (x *ImportClientsRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type ImportClientsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Clients       []*ClientInfo          `protobuf:"bytes,1,rep,name=clients,proto3" json:"clients,omitempty"`
+	Results       []*ImportResult        `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListClientsResponse) Reset() {
-	*x = ListClientsResponse{}
-	mi := &file_api_proto_agent_proto_msgTypes[10]
+func (x *ImportClientsResponse) Reset() {
+	*x = ImportClientsResponse{}
+	mi := &file_api_proto_agent_proto_msgTypes[41]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListClientsResponse) String() string {
+func (x *ImportClientsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListClientsResponse) ProtoMessage() {}
+func (*ImportClientsResponse) ProtoMessage() {}
 
-func (x *ListClientsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[10]
+func (x *ImportClientsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[41]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -576,43 +3065,46 @@ func (x *ListClientsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListClientsResponse.ProtoReflect.Descriptor instead.
-func (*ListClientsResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use ImportClientsResponse.ProtoReflect.Descriptor instead.
+func (*ImportClientsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{41}
 }
 
-func (x *ListClientsResponse) GetClients() []*ClientInfo {
+func (x *ImportClientsResponse) GetResults() []*ImportResult {
 	if x != nil {
-		return x.Clients
+		return x.Results
 	}
 	return nil
 }
 
-type ClientInfo struct {
+type ImportResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	ClientIp      string                 `protobuf:"bytes,2,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
-	Enabled       bool                   `protobuf:"varint,3,opt,name=enabled,proto3" json:"enabled,omitempty"`
-	LastHandshake int64                  `protobuf:"varint,4,opt,name=last_handshake,json=lastHandshake,proto3" json:"last_handshake,omitempty"`
+	PublicKey     string                 `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	ClientIp      string                 `protobuf:"bytes,3,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`   // imported, exists, skipped, error
+	Message       string                 `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"` // причина пропуска или ошибки
+	ClientIps     []string               `protobuf:"bytes,6,rep,name=client_ips,json=clientIps,proto3" json:"client_ips,omitempty"`
+	DeviceId      string                 `protobuf:"bytes,7,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"` // из тега "# device_id = ...", пусто - основное устройство
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ClientInfo) Reset() {
-	*x = ClientInfo{}
-	mi := &file_api_proto_agent_proto_msgTypes[11]
+func (x *ImportResult) Reset() {
+	*x = ImportResult{}
+	mi := &file_api_proto_agent_proto_msgTypes[42]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ClientInfo) String() string {
+func (x *ImportResult) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ClientInfo) ProtoMessage() {}
+func (*ImportResult) ProtoMessage() {}
 
-func (x *ClientInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_proto_msgTypes[11]
+func (x *ImportResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_proto_msgTypes[42]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -623,89 +3115,384 @@ func (x *ClientInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ClientInfo.ProtoReflect.Descriptor instead.
-func (*ClientInfo) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use ImportResult.ProtoReflect.Descriptor instead.
+func (*ImportResult) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_proto_rawDescGZIP(), []int{42}
 }
 
-func (x *ClientInfo) GetUserId() string {
+func (x *ImportResult) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *ImportResult) GetUserId() string {
 	if x != nil {
 		return x.UserId
 	}
 	return ""
 }
 
-func (x *ClientInfo) GetClientIp() string {
+func (x *ImportResult) GetClientIp() string {
 	if x != nil {
 		return x.ClientIp
 	}
 	return ""
 }
 
-func (x *ClientInfo) GetEnabled() bool {
+func (x *ImportResult) GetStatus() string {
 	if x != nil {
-		return x.Enabled
+		return x.Status
 	}
-	return false
+	return ""
 }
 
-func (x *ClientInfo) GetLastHandshake() int64 {
+func (x *ImportResult) GetMessage() string {
 	if x != nil {
-		return x.LastHandshake
+		return x.Message
 	}
-	return 0
+	return ""
+}
+
+func (x *ImportResult) GetClientIps() []string {
+	if x != nil {
+		return x.ClientIps
+	}
+	return nil
+}
+
+func (x *ImportResult) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
 }
 
 var File_api_proto_agent_proto protoreflect.FileDescriptor
 
 const file_api_proto_agent_proto_rawDesc = "" +
 	"\n" +
-	"\x15api/proto/agent.proto\x12\awgagent\x1a\x1bgoogle/protobuf/empty.proto\".\n" +
+	"\x15api/proto/agent.proto\x12\awgagent\x1a\x1bgoogle/protobuf/empty.proto\"\x91\x05\n" +
 	"\x13CreateClientRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\"\x97\x01\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\tR\tpublicKey\x12#\n" +
+	"\rpreshared_key\x18\x03 \x01(\bR\fpresharedKey\x121\n" +
+	"\n" +
+	"qr_options\x18\x04 \x01(\v2\x12.wgagent.QrOptionsR\tqrOptions\x12!\n" +
+	"\frequested_ip\x18\x05 \x01(\tR\vrequestedIp\x12\x12\n" +
+	"\x04pool\x18\x06 \x01(\tR\x04pool\x12C\n" +
+	"\x0econfig_options\x18\a \x01(\v2\x1c.wgagent.ClientConfigOptionsR\rconfigOptions\x12%\n" +
+	"\x0eoutput_formats\x18\b \x03(\tR\routputFormats\x12D\n" +
+	"\x11deep_link_options\x18\t \x01(\v2\x18.wgagent.DeepLinkOptionsR\x0fdeepLinkOptions\x12\x1f\n" +
+	"\vtunnel_name\x18\n" +
+	" \x01(\tR\n" +
+	"tunnelName\x12@\n" +
+	"\x06labels\x18\v \x03(\v2(.wgagent.CreateClientRequest.LabelsEntryR\x06labels\x12'\n" +
+	"\x0fidempotency_key\x18\f \x01(\tR\x0eidempotencyKey\x12\x1b\n" +
+	"\tif_exists\x18\r \x01(\tR\bifExists\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x0e \x01(\x03R\texpiresAt\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"E\n" +
+	"\x0fDeepLinkOptions\x12\x16\n" +
+	"\x06scheme\x18\x01 \x01(\tR\x06scheme\x12\x1a\n" +
+	"\bcompress\x18\x02 \x01(\bR\bcompress\"\xe7\x01\n" +
+	"\x13ClientConfigOptions\x12\x10\n" +
+	"\x03dns\x18\x01 \x03(\tR\x03dns\x12\x1f\n" +
+	"\vallowed_ips\x18\x02 \x03(\tR\n" +
+	"allowedIps\x12\x10\n" +
+	"\x03mtu\x18\x03 \x01(\rR\x03mtu\x126\n" +
+	"\x14persistent_keepalive\x18\x04 \x01(\rH\x00R\x13persistentKeepalive\x88\x01\x01\x12\x17\n" +
+	"\apost_up\x18\x05 \x03(\tR\x06postUp\x12!\n" +
+	"\fsplit_tunnel\x18\x06 \x01(\tR\vsplitTunnelB\x17\n" +
+	"\x15_persistent_keepalive\"\xa2\x01\n" +
+	"\tQrOptions\x12\x16\n" +
+	"\x06format\x18\x01 \x01(\tR\x06format\x12\x1f\n" +
+	"\vmodule_size\x18\x02 \x01(\x05R\n" +
+	"moduleSize\x12)\n" +
+	"\x10error_correction\x18\x03 \x01(\tR\x0ferrorCorrection\x12\"\n" +
+	"\n" +
+	"quiet_zone\x18\x04 \x01(\x05H\x00R\tquietZone\x88\x01\x01B\r\n" +
+	"\v_quiet_zone\"\xf5\x02\n" +
 	"\x14CreateClientResponse\x12\x1f\n" +
 	"\vconfig_file\x18\x01 \x01(\tR\n" +
 	"configFile\x12$\n" +
 	"\x0eqr_code_base64\x18\x02 \x01(\tR\fqrCodeBase64\x12\x1b\n" +
 	"\tdeep_link\x18\x03 \x01(\tR\bdeepLink\x12\x1b\n" +
-	"\tclient_ip\x18\x04 \x01(\tR\bclientIp\"/\n" +
+	"\tclient_ip\x18\x04 \x01(\tR\bclientIp\x12\x19\n" +
+	"\bkey_mode\x18\x05 \x01(\tR\akeyMode\x12*\n" +
+	"\x11has_preshared_key\x18\x06 \x01(\bR\x0fhasPresharedKey\x12\x1d\n" +
+	"\n" +
+	"client_ips\x18\a \x03(\tR\tclientIps\x125\n" +
+	"\tartifacts\x18\b \x03(\v2\x17.wgagent.ConfigArtifactR\tartifacts\x12'\n" +
+	"\x0fconfig_filename\x18\t \x01(\tR\x0econfigFilename\x12\x16\n" +
+	"\x06result\x18\n" +
+	" \x01(\tR\x06result\"\x81\x01\n" +
+	"\x0eConfigArtifact\x12\x16\n" +
+	"\x06format\x18\x01 \x01(\tR\x06format\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12!\n" +
+	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\x12\x18\n" +
+	"\acontent\x18\x04 \x01(\fR\acontent\"/\n" +
 	"\x14DisableClientRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\"K\n" +
-	"\x15DisableClientResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"O\n" +
+	"\x15DisableClientResponse\x12\x1c\n" +
+	"\asuccess\x18\x01 \x01(\bB\x02\x18\x01R\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\".\n" +
 	"\x13EnableClientRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\"J\n" +
-	"\x14EnableClientResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\".\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"N\n" +
+	"\x14EnableClientResponse\x12\x1c\n" +
+	"\asuccess\x18\x01 \x01(\bB\x02\x18\x01R\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\xb3\x01\n" +
+	"\x13ExtendClientRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x02 \x01(\x03R\texpiresAt\x12)\n" +
+	"\x10duration_seconds\x18\x03 \x01(\x03R\x0fdurationSeconds\x12\x16\n" +
+	"\x06enable\x18\x04 \x01(\bR\x06enable\x12!\n" +
+	"\fclear_expiry\x18\x05 \x01(\bR\vclearExpiry\"O\n" +
+	"\x14ExtendClientResponse\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x01 \x01(\x03R\texpiresAt\x12\x18\n" +
+	"\aenabled\x18\x02 \x01(\bR\aenabled\".\n" +
 	"\x13DeleteClientRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\"+\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"\xf1\x02\n" +
+	"\x13CreateDeviceRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1b\n" +
+	"\tdevice_id\x18\x02 \x01(\tR\bdeviceId\x12\x1f\n" +
+	"\vmax_devices\x18\x03 \x01(\x05R\n" +
+	"maxDevices\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x04 \x01(\tR\tpublicKey\x12#\n" +
+	"\rpreshared_key\x18\x05 \x01(\bR\fpresharedKey\x12\x1f\n" +
+	"\vtunnel_name\x18\x06 \x01(\tR\n" +
+	"tunnelName\x121\n" +
+	"\n" +
+	"qr_options\x18\a \x01(\v2\x12.wgagent.QrOptionsR\tqrOptions\x12%\n" +
+	"\x0eoutput_formats\x18\b \x03(\tR\routputFormats\x12D\n" +
+	"\x11deep_link_options\x18\t \x01(\v2\x18.wgagent.DeepLinkOptionsR\x0fdeepLinkOptions\"j\n" +
+	"\x14CreateDeviceResponse\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x125\n" +
+	"\x06config\x18\x02 \x01(\v2\x1d.wgagent.ClientConfigResponseR\x06config\"-\n" +
+	"\x12ListDevicesRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"D\n" +
+	"\x13ListDevicesResponse\x12-\n" +
+	"\adevices\x18\x01 \x03(\v2\x13.wgagent.DeviceInfoR\adevices\"\xc4\x02\n" +
+	"\n" +
+	"DeviceInfo\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12\x1b\n" +
+	"\tclient_ip\x18\x02 \x01(\tR\bclientIp\x12\x1d\n" +
+	"\n" +
+	"client_ips\x18\x03 \x03(\tR\tclientIps\x12\x18\n" +
+	"\aenabled\x18\x04 \x01(\bR\aenabled\x12\x19\n" +
+	"\bkey_mode\x18\x05 \x01(\tR\akeyMode\x12*\n" +
+	"\x11has_preshared_key\x18\x06 \x01(\bR\x0fhasPresharedKey\x12\x1f\n" +
+	"\vtunnel_name\x18\a \x01(\tR\n" +
+	"tunnelName\x12%\n" +
+	"\x0elast_handshake\x18\b \x01(\x03R\rlastHandshake\x12\x19\n" +
+	"\brx_bytes\x18\t \x01(\x03R\arxBytes\x12\x19\n" +
+	"\btx_bytes\x18\n" +
+	" \x01(\x03R\atxBytes\"K\n" +
+	"\x13DeleteDeviceRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1b\n" +
+	"\tdevice_id\x18\x02 \x01(\tR\bdeviceId\"+\n" +
 	"\x10GetClientRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\"\xc0\x01\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"\x98\x04\n" +
 	"\x11GetClientResponse\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1b\n" +
 	"\tclient_ip\x18\x02 \x01(\tR\bclientIp\x12\x18\n" +
 	"\aenabled\x18\x03 \x01(\bR\aenabled\x12\x19\n" +
 	"\brx_bytes\x18\x04 \x01(\x03R\arxBytes\x12\x19\n" +
 	"\btx_bytes\x18\x05 \x01(\x03R\atxBytes\x12%\n" +
-	"\x0elast_handshake\x18\x06 \x01(\x03R\rlastHandshake\"\x14\n" +
-	"\x12ListClientsRequest\"D\n" +
+	"\x0elast_handshake\x18\x06 \x01(\x03R\rlastHandshake\x12\x19\n" +
+	"\bkey_mode\x18\a \x01(\tR\akeyMode\x12*\n" +
+	"\x11has_preshared_key\x18\b \x01(\bR\x0fhasPresharedKey\x12\x1d\n" +
+	"\n" +
+	"client_ips\x18\t \x03(\tR\tclientIps\x12\x12\n" +
+	"\x04pool\x18\n" +
+	" \x01(\tR\x04pool\x12\x1f\n" +
+	"\vtunnel_name\x18\v \x01(\tR\n" +
+	"tunnelName\x12>\n" +
+	"\x06labels\x18\f \x03(\v2&.wgagent.GetClientResponse.LabelsEntryR\x06labels\x12!\n" +
+	"\fdevice_count\x18\r \x01(\x05R\vdeviceCount\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x0e \x01(\x03R\texpiresAt\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xee\x01\n" +
+	"\x16GetClientConfigRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x121\n" +
+	"\n" +
+	"qr_options\x18\x02 \x01(\v2\x12.wgagent.QrOptionsR\tqrOptions\x12%\n" +
+	"\x0eoutput_formats\x18\x03 \x03(\tR\routputFormats\x12D\n" +
+	"\x11deep_link_options\x18\x04 \x01(\v2\x18.wgagent.DeepLinkOptionsR\x0fdeepLinkOptions\x12\x1b\n" +
+	"\tdevice_id\x18\x05 \x01(\tR\bdeviceId\"\x90\x01\n" +
+	"\x12ListClientsRequest\x12?\n" +
+	"\x06labels\x18\x01 \x03(\v2'.wgagent.ListClientsRequest.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"D\n" +
 	"\x13ListClientsResponse\x12-\n" +
-	"\aclients\x18\x01 \x03(\v2\x13.wgagent.ClientInfoR\aclients\"\x83\x01\n" +
+	"\aclients\x18\x01 \x03(\v2\x13.wgagent.ClientInfoR\aclients\"\xa8\x03\n" +
 	"\n" +
 	"ClientInfo\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1b\n" +
 	"\tclient_ip\x18\x02 \x01(\tR\bclientIp\x12\x18\n" +
 	"\aenabled\x18\x03 \x01(\bR\aenabled\x12%\n" +
-	"\x0elast_handshake\x18\x04 \x01(\x03R\rlastHandshake2\xce\x03\n" +
+	"\x0elast_handshake\x18\x04 \x01(\x03R\rlastHandshake\x12\x19\n" +
+	"\bkey_mode\x18\x05 \x01(\tR\akeyMode\x12\x1d\n" +
+	"\n" +
+	"client_ips\x18\x06 \x03(\tR\tclientIps\x12\x12\n" +
+	"\x04pool\x18\a \x01(\tR\x04pool\x12\x1f\n" +
+	"\vtunnel_name\x18\b \x01(\tR\n" +
+	"tunnelName\x127\n" +
+	"\x06labels\x18\t \x03(\v2\x1f.wgagent.ClientInfo.LabelsEntryR\x06labels\x12!\n" +
+	"\fdevice_count\x18\n" +
+	" \x01(\x05R\vdeviceCount\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\v \x01(\x03R\texpiresAt\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"4\n" +
+	"\x19GetReconcileStatusRequest\x12\x17\n" +
+	"\arun_now\x18\x01 \x01(\bR\x06runNow\"\xef\x02\n" +
+	"\x1aGetReconcileStatusResponse\x12\x19\n" +
+	"\blast_run\x18\x01 \x01(\x03R\alastRun\x12\x17\n" +
+	"\ain_sync\x18\x02 \x01(\bR\x06inSync\x12.\n" +
+	"\x13unknown_peer_policy\x18\x03 \x01(\tR\x11unknownPeerPolicy\x12(\n" +
+	"\x10readded_user_ids\x18\x04 \x03(\tR\x0ereaddedUserIds\x12(\n" +
+	"\x10removed_user_ids\x18\x05 \x03(\tR\x0eremovedUserIds\x12#\n" +
+	"\runknown_peers\x18\x06 \x03(\tR\funknownPeers\x12(\n" +
+	"\x10adopted_user_ids\x18\a \x03(\tR\x0eadoptedUserIds\x122\n" +
+	"\x15removed_unknown_peers\x18\b \x03(\tR\x13removedUnknownPeers\x12\x16\n" +
+	"\x06errors\x18\t \x03(\tR\x06errors\"\xdd\x02\n" +
+	"\x14ClientConfigResponse\x12\x1f\n" +
+	"\vconfig_file\x18\x01 \x01(\tR\n" +
+	"configFile\x12$\n" +
+	"\x0eqr_code_base64\x18\x02 \x01(\tR\fqrCodeBase64\x12\x1b\n" +
+	"\tdeep_link\x18\x03 \x01(\tR\bdeepLink\x12\x1b\n" +
+	"\tclient_ip\x18\x04 \x01(\tR\bclientIp\x12\x19\n" +
+	"\bkey_mode\x18\x05 \x01(\tR\akeyMode\x12*\n" +
+	"\x11has_preshared_key\x18\x06 \x01(\bR\x0fhasPresharedKey\x12\x1d\n" +
+	"\n" +
+	"client_ips\x18\a \x03(\tR\tclientIps\x125\n" +
+	"\tartifacts\x18\b \x03(\v2\x17.wgagent.ConfigArtifactR\tartifacts\x12'\n" +
+	"\x0fconfig_filename\x18\t \x01(\tR\x0econfigFilename\"\xee\x01\n" +
+	"\x16AddPresharedKeyRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x121\n" +
+	"\n" +
+	"qr_options\x18\x02 \x01(\v2\x12.wgagent.QrOptionsR\tqrOptions\x12%\n" +
+	"\x0eoutput_formats\x18\x03 \x03(\tR\routputFormats\x12D\n" +
+	"\x11deep_link_options\x18\x04 \x01(\v2\x18.wgagent.DeepLinkOptionsR\x0fdeepLinkOptions\x12\x1b\n" +
+	"\tdevice_id\x18\x05 \x01(\tR\bdeviceId\"\x8e\x02\n" +
+	"\x17RotateClientKeysRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\tR\tpublicKey\x121\n" +
+	"\n" +
+	"qr_options\x18\x03 \x01(\v2\x12.wgagent.QrOptionsR\tqrOptions\x12%\n" +
+	"\x0eoutput_formats\x18\x04 \x03(\tR\routputFormats\x12D\n" +
+	"\x11deep_link_options\x18\x05 \x01(\v2\x18.wgagent.DeepLinkOptionsR\x0fdeepLinkOptions\x12\x1b\n" +
+	"\tdevice_id\x18\x06 \x01(\tR\bdeviceId\"O\n" +
+	"\x10ReserveIPRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x0e\n" +
+	"\x02ip\x18\x02 \x01(\tR\x02ip\x12\x12\n" +
+	"\x04pool\x18\x03 \x01(\tR\x04pool\"+\n" +
+	"\x19ReleaseReservationRequest\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\"2\n" +
+	"\x17ListReservationsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"T\n" +
+	"\x18ListReservationsResponse\x128\n" +
+	"\freservations\x18\x01 \x03(\v2\x14.wgagent.ReservationR\freservations\"U\n" +
+	"\vReservation\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x03 \x01(\x03R\tcreatedAt\"\x17\n" +
+	"\x15ListQuarantineRequest\"J\n" +
+	"\x16ListQuarantineResponse\x120\n" +
+	"\aentries\x18\x01 \x03(\v2\x16.wgagent.QuarantinedIPR\aentries\"x\n" +
+	"\rQuarantinedIP\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x1f\n" +
+	"\vreleased_at\x18\x03 \x01(\x03R\n" +
+	"releasedAt\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\x03R\texpiresAt\"<\n" +
+	"\x18ReleaseQuarantineRequest\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x10\n" +
+	"\x03all\x18\x02 \x01(\bR\x03all\">\n" +
+	"\x19ReleaseQuarantineResponse\x12!\n" +
+	"\freleased_ips\x18\x01 \x03(\tR\vreleasedIps\"\xca\x01\n" +
+	"\x14ImportClientsRequest\x12\x16\n" +
+	"\x06config\x18\x01 \x01(\tR\x06config\x12E\n" +
+	"\buser_ids\x18\x02 \x03(\v2*.wgagent.ImportClientsRequest.UserIdsEntryR\auserIds\x12\x17\n" +
+	"\adry_run\x18\x03 \x01(\bR\x06dryRun\x1a:\n" +
+	"\fUserIdsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"H\n" +
+	"\x15ImportClientsResponse\x12/\n" +
+	"\aresults\x18\x01 \x03(\v2\x15.wgagent.ImportResultR\aresults\"\xd1\x01\n" +
+	"\fImportResult\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\tR\tpublicKey\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x1b\n" +
+	"\tclient_ip\x18\x03 \x01(\tR\bclientIp\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\x12\x1d\n" +
+	"\n" +
+	"client_ips\x18\x06 \x03(\tR\tclientIps\x12\x1b\n" +
+	"\tdevice_id\x18\a \x01(\tR\bdeviceId*\xd0\x04\n" +
+	"\vErrorReason\x12\x1c\n" +
+	"\x18ERROR_REASON_UNSPECIFIED\x10\x00\x12\x14\n" +
+	"\x10INVALID_ARGUMENT\x10\x01\x12\x14\n" +
+	"\x10CLIENT_NOT_FOUND\x10\x02\x12\x19\n" +
+	"\x15CLIENT_ALREADY_EXISTS\x10\x03\x12\x15\n" +
+	"\x11PUBLIC_KEY_IN_USE\x10\x04\x12\x10\n" +
+	"\fUNKNOWN_POOL\x10\x05\x12\x13\n" +
+	"\x0fIP_OUTSIDE_POOL\x10\x06\x12\r\n" +
+	"\tIP_IN_USE\x10\a\x12\x0f\n" +
+	"\vIP_RESERVED\x10\b\x12\x12\n" +
+	"\x0eIP_QUARANTINED\x10\t\x12\x12\n" +
+	"\x0ePOOL_EXHAUSTED\x10\n" +
+	"\x12\x19\n" +
+	"\x15RESERVATION_NOT_FOUND\x10\v\x12\x16\n" +
+	"\x12RESERVATION_EXISTS\x10\f\x12\x13\n" +
+	"\x0fNOT_QUARANTINED\x10\r\x12\x1a\n" +
+	"\x16PRESHARED_KEY_DISABLED\x10\x0e\x12\x1b\n" +
+	"\x17PRIVATE_KEY_UNAVAILABLE\x10\x0f\x12\x19\n" +
+	"\x15SERVER_NOT_CONFIGURED\x10\x10\x12\x16\n" +
+	"\x12DEVICE_UNAVAILABLE\x10\x11\x12\x12\n" +
+	"\x0eSTORAGE_FAILED\x10\x12\x12\f\n" +
+	"\bINTERNAL\x10\x13\x12\x1a\n" +
+	"\x16IDEMPOTENCY_KEY_REUSED\x10\x14\x12\x14\n" +
+	"\x10DEVICE_NOT_FOUND\x10\x15\x12\x19\n" +
+	"\x15DEVICE_ALREADY_EXISTS\x10\x16\x12\x19\n" +
+	"\x15DEVICE_LIMIT_EXCEEDED\x10\x17\x12\x18\n" +
+	"\x14SUBSCRIPTION_EXPIRED\x10\x182\xba\f\n" +
 	"\x0eWireGuardAgent\x12K\n" +
 	"\fCreateClient\x12\x1c.wgagent.CreateClientRequest\x1a\x1d.wgagent.CreateClientResponse\x12N\n" +
 	"\rDisableClient\x12\x1d.wgagent.DisableClientRequest\x1a\x1e.wgagent.DisableClientResponse\x12K\n" +
-	"\fEnableClient\x12\x1c.wgagent.EnableClientRequest\x1a\x1d.wgagent.EnableClientResponse\x12D\n" +
-	"\fDeleteClient\x12\x1c.wgagent.DeleteClientRequest\x1a\x16.google.protobuf.Empty\x12B\n" +
-	"\tGetClient\x12\x19.wgagent.GetClientRequest\x1a\x1a.wgagent.GetClientResponse\x12H\n" +
-	"\vListClients\x12\x1b.wgagent.ListClientsRequest\x1a\x1c.wgagent.ListClientsResponseB&Z$github.com/quibex/wg-agent/api/protob\x06proto3"
+	"\fEnableClient\x12\x1c.wgagent.EnableClientRequest\x1a\x1d.wgagent.EnableClientResponse\x12K\n" +
+	"\fExtendClient\x12\x1c.wgagent.ExtendClientRequest\x1a\x1d.wgagent.ExtendClientResponse\x12D\n" +
+	"\fDeleteClient\x12\x1c.wgagent.DeleteClientRequest\x1a\x16.google.protobuf.Empty\x12K\n" +
+	"\fCreateDevice\x12\x1c.wgagent.CreateDeviceRequest\x1a\x1d.wgagent.CreateDeviceResponse\x12H\n" +
+	"\vListDevices\x12\x1b.wgagent.ListDevicesRequest\x1a\x1c.wgagent.ListDevicesResponse\x12D\n" +
+	"\fDeleteDevice\x12\x1c.wgagent.DeleteDeviceRequest\x1a\x16.google.protobuf.Empty\x12B\n" +
+	"\tGetClient\x12\x19.wgagent.GetClientRequest\x1a\x1a.wgagent.GetClientResponse\x12Q\n" +
+	"\x0fGetClientConfig\x12\x1f.wgagent.GetClientConfigRequest\x1a\x1d.wgagent.ClientConfigResponse\x12H\n" +
+	"\vListClients\x12\x1b.wgagent.ListClientsRequest\x1a\x1c.wgagent.ListClientsResponse\x12Q\n" +
+	"\x0fAddPresharedKey\x12\x1f.wgagent.AddPresharedKeyRequest\x1a\x1d.wgagent.ClientConfigResponse\x12S\n" +
+	"\x10RotateClientKeys\x12 .wgagent.RotateClientKeysRequest\x1a\x1d.wgagent.ClientConfigResponse\x12<\n" +
+	"\tReserveIP\x12\x19.wgagent.ReserveIPRequest\x1a\x14.wgagent.Reservation\x12P\n" +
+	"\x12ReleaseReservation\x12\".wgagent.ReleaseReservationRequest\x1a\x16.google.protobuf.Empty\x12W\n" +
+	"\x10ListReservations\x12 .wgagent.ListReservationsRequest\x1a!.wgagent.ListReservationsResponse\x12Q\n" +
+	"\x0eListQuarantine\x12\x1e.wgagent.ListQuarantineRequest\x1a\x1f.wgagent.ListQuarantineResponse\x12Z\n" +
+	"\x11ReleaseQuarantine\x12!.wgagent.ReleaseQuarantineRequest\x1a\".wgagent.ReleaseQuarantineResponse\x12N\n" +
+	"\rImportClients\x12\x1d.wgagent.ImportClientsRequest\x1a\x1e.wgagent.ImportClientsResponse\x12]\n" +
+	"\x12GetReconcileStatus\x12\".wgagent.GetReconcileStatusRequest\x1a#.wgagent.GetReconcileStatusResponseB&Z$github.com/quibex/wg-agent/api/protob\x06proto3"
 
 var (
 	file_api_proto_agent_proto_rawDescOnce sync.Once
@@ -719,41 +3506,130 @@ func file_api_proto_agent_proto_rawDescGZIP() []byte {
 	return file_api_proto_agent_proto_rawDescData
 }
 
-var file_api_proto_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_api_proto_agent_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_proto_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 48)
 var file_api_proto_agent_proto_goTypes = []any{
-	(*CreateClientRequest)(nil),   // 0: wgagent.CreateClientRequest
-	(*CreateClientResponse)(nil),  // 1: wgagent.CreateClientResponse
-	(*DisableClientRequest)(nil),  // 2: wgagent.DisableClientRequest
-	(*DisableClientResponse)(nil), // 3: wgagent.DisableClientResponse
-	(*EnableClientRequest)(nil),   // 4: wgagent.EnableClientRequest
-	(*EnableClientResponse)(nil),  // 5: wgagent.EnableClientResponse
-	(*DeleteClientRequest)(nil),   // 6: wgagent.DeleteClientRequest
-	(*GetClientRequest)(nil),      // 7: wgagent.GetClientRequest
-	(*GetClientResponse)(nil),     // 8: wgagent.GetClientResponse
-	(*ListClientsRequest)(nil),    // 9: wgagent.ListClientsRequest
-	(*ListClientsResponse)(nil),   // 10: wgagent.ListClientsResponse
-	(*ClientInfo)(nil),            // 11: wgagent.ClientInfo
-	(*emptypb.Empty)(nil),         // 12: google.protobuf.Empty
+	(ErrorReason)(0),                   // 0: wgagent.ErrorReason
+	(*CreateClientRequest)(nil),        // 1: wgagent.CreateClientRequest
+	(*DeepLinkOptions)(nil),            // 2: wgagent.DeepLinkOptions
+	(*ClientConfigOptions)(nil),        // 3: wgagent.ClientConfigOptions
+	(*QrOptions)(nil),                  // 4: wgagent.QrOptions
+	(*CreateClientResponse)(nil),       // 5: wgagent.CreateClientResponse
+	(*ConfigArtifact)(nil),             // 6: wgagent.ConfigArtifact
+	(*DisableClientRequest)(nil),       // 7: wgagent.DisableClientRequest
+	(*DisableClientResponse)(nil),      // 8: wgagent.DisableClientResponse
+	(*EnableClientRequest)(nil),        // 9: wgagent.EnableClientRequest
+	(*EnableClientResponse)(nil),       // 10: wgagent.EnableClientResponse
+	(*ExtendClientRequest)(nil),        // 11: wgagent.ExtendClientRequest
+	(*ExtendClientResponse)(nil),       // 12: wgagent.ExtendClientResponse
+	(*DeleteClientRequest)(nil),        // 13: wgagent.DeleteClientRequest
+	(*CreateDeviceRequest)(nil),        // 14: wgagent.CreateDeviceRequest
+	(*CreateDeviceResponse)(nil),       // 15: wgagent.CreateDeviceResponse
+	(*ListDevicesRequest)(nil),         // 16: wgagent.ListDevicesRequest
+	(*ListDevicesResponse)(nil),        // 17: wgagent.ListDevicesResponse
+	(*DeviceInfo)(nil),                 // 18: wgagent.DeviceInfo
+	(*DeleteDeviceRequest)(nil),        // 19: wgagent.DeleteDeviceRequest
+	(*GetClientRequest)(nil),           // 20: wgagent.GetClientRequest
+	(*GetClientResponse)(nil),          // 21: wgagent.GetClientResponse
+	(*GetClientConfigRequest)(nil),     // 22: wgagent.GetClientConfigRequest
+	(*ListClientsRequest)(nil),         // 23: wgagent.ListClientsRequest
+	(*ListClientsResponse)(nil),        // 24: wgagent.ListClientsResponse
+	(*ClientInfo)(nil),                 // 25: wgagent.ClientInfo
+	(*GetReconcileStatusRequest)(nil),  // 26: wgagent.GetReconcileStatusRequest
+	(*GetReconcileStatusResponse)(nil), // 27: wgagent.GetReconcileStatusResponse
+	(*ClientConfigResponse)(nil),       // 28: wgagent.ClientConfigResponse
+	(*AddPresharedKeyRequest)(nil),     // 29: wgagent.AddPresharedKeyRequest
+	(*RotateClientKeysRequest)(nil),    // 30: wgagent.RotateClientKeysRequest
+	(*ReserveIPRequest)(nil),           // 31: wgagent.ReserveIPRequest
+	(*ReleaseReservationRequest)(nil),  // 32: wgagent.ReleaseReservationRequest
+	(*ListReservationsRequest)(nil),    // 33: wgagent.ListReservationsRequest
+	(*ListReservationsResponse)(nil),   // 34: wgagent.ListReservationsResponse
+	(*Reservation)(nil),                // 35: wgagent.Reservation
+	(*ListQuarantineRequest)(nil),      // 36: wgagent.ListQuarantineRequest
+	(*ListQuarantineResponse)(nil),     // 37: wgagent.ListQuarantineResponse
+	(*QuarantinedIP)(nil),              // 38: wgagent.QuarantinedIP
+	(*ReleaseQuarantineRequest)(nil),   // 39: wgagent.ReleaseQuarantineRequest
+	(*ReleaseQuarantineResponse)(nil),  // 40: wgagent.ReleaseQuarantineResponse
+	(*ImportClientsRequest)(nil),       // 41: wgagent.ImportClientsRequest
+	(*ImportClientsResponse)(nil),      // 42: wgagent.ImportClientsResponse
+	(*ImportResult)(nil),               // 43: wgagent.ImportResult
+	nil,                                // 44: wgagent.CreateClientRequest.LabelsEntry
+	nil,                                // 45: wgagent.GetClientResponse.LabelsEntry
+	nil,                                // 46: wgagent.ListClientsRequest.LabelsEntry
+	nil,                                // 47: wgagent.ClientInfo.LabelsEntry
+	nil,                                // 48: wgagent.ImportClientsRequest.UserIdsEntry
+	(*emptypb.Empty)(nil),              // 49: google.protobuf.Empty
 }
 var file_api_proto_agent_proto_depIdxs = []int32{
-	11, // 0: wgagent.ListClientsResponse.clients:type_name -> wgagent.ClientInfo
-	0,  // 1: wgagent.WireGuardAgent.CreateClient:input_type -> wgagent.CreateClientRequest
-	2,  // 2: wgagent.WireGuardAgent.DisableClient:input_type -> wgagent.DisableClientRequest
-	4,  // 3: wgagent.WireGuardAgent.EnableClient:input_type -> wgagent.EnableClientRequest
-	6,  // 4: wgagent.WireGuardAgent.DeleteClient:input_type -> wgagent.DeleteClientRequest
-	7,  // 5: wgagent.WireGuardAgent.GetClient:input_type -> wgagent.GetClientRequest
-	9,  // 6: wgagent.WireGuardAgent.ListClients:input_type -> wgagent.ListClientsRequest
-	1,  // 7: wgagent.WireGuardAgent.CreateClient:output_type -> wgagent.CreateClientResponse
-	3,  // 8: wgagent.WireGuardAgent.DisableClient:output_type -> wgagent.DisableClientResponse
-	5,  // 9: wgagent.WireGuardAgent.EnableClient:output_type -> wgagent.EnableClientResponse
-	12, // 10: wgagent.WireGuardAgent.DeleteClient:output_type -> google.protobuf.Empty
-	8,  // 11: wgagent.WireGuardAgent.GetClient:output_type -> wgagent.GetClientResponse
-	10, // 12: wgagent.WireGuardAgent.ListClients:output_type -> wgagent.ListClientsResponse
-	7,  // [7:13] is the sub-list for method output_type
-	1,  // [1:7] is the sub-list for method input_type
-	1,  // [1:1] is the sub-list for extension type_name
-	1,  // [1:1] is the sub-list for extension extendee
-	0,  // [0:1] is the sub-list for field type_name
+	4,  // 0: wgagent.CreateClientRequest.qr_options:type_name -> wgagent.QrOptions
+	3,  // 1: wgagent.CreateClientRequest.config_options:type_name -> wgagent.ClientConfigOptions
+	2,  // 2: wgagent.CreateClientRequest.deep_link_options:type_name -> wgagent.DeepLinkOptions
+	44, // 3: wgagent.CreateClientRequest.labels:type_name -> wgagent.CreateClientRequest.LabelsEntry
+	6,  // 4: wgagent.CreateClientResponse.artifacts:type_name -> wgagent.ConfigArtifact
+	4,  // 5: wgagent.CreateDeviceRequest.qr_options:type_name -> wgagent.QrOptions
+	2,  // 6: wgagent.CreateDeviceRequest.deep_link_options:type_name -> wgagent.DeepLinkOptions
+	28, // 7: wgagent.CreateDeviceResponse.config:type_name -> wgagent.ClientConfigResponse
+	18, // 8: wgagent.ListDevicesResponse.devices:type_name -> wgagent.DeviceInfo
+	45, // 9: wgagent.GetClientResponse.labels:type_name -> wgagent.GetClientResponse.LabelsEntry
+	4,  // 10: wgagent.GetClientConfigRequest.qr_options:type_name -> wgagent.QrOptions
+	2,  // 11: wgagent.GetClientConfigRequest.deep_link_options:type_name -> wgagent.DeepLinkOptions
+	46, // 12: wgagent.ListClientsRequest.labels:type_name -> wgagent.ListClientsRequest.LabelsEntry
+	25, // 13: wgagent.ListClientsResponse.clients:type_name -> wgagent.ClientInfo
+	47, // 14: wgagent.ClientInfo.labels:type_name -> wgagent.ClientInfo.LabelsEntry
+	6,  // 15: wgagent.ClientConfigResponse.artifacts:type_name -> wgagent.ConfigArtifact
+	4,  // 16: wgagent.AddPresharedKeyRequest.qr_options:type_name -> wgagent.QrOptions
+	2,  // 17: wgagent.AddPresharedKeyRequest.deep_link_options:type_name -> wgagent.DeepLinkOptions
+	4,  // 18: wgagent.RotateClientKeysRequest.qr_options:type_name -> wgagent.QrOptions
+	2,  // 19: wgagent.RotateClientKeysRequest.deep_link_options:type_name -> wgagent.DeepLinkOptions
+	35, // 20: wgagent.ListReservationsResponse.reservations:type_name -> wgagent.Reservation
+	38, // 21: wgagent.ListQuarantineResponse.entries:type_name -> wgagent.QuarantinedIP
+	48, // 22: wgagent.ImportClientsRequest.user_ids:type_name -> wgagent.ImportClientsRequest.UserIdsEntry
+	43, // 23: wgagent.ImportClientsResponse.results:type_name -> wgagent.ImportResult
+	1,  // 24: wgagent.WireGuardAgent.CreateClient:input_type -> wgagent.CreateClientRequest
+	7,  // 25: wgagent.WireGuardAgent.DisableClient:input_type -> wgagent.DisableClientRequest
+	9,  // 26: wgagent.WireGuardAgent.EnableClient:input_type -> wgagent.EnableClientRequest
+	11, // 27: wgagent.WireGuardAgent.ExtendClient:input_type -> wgagent.ExtendClientRequest
+	13, // 28: wgagent.WireGuardAgent.DeleteClient:input_type -> wgagent.DeleteClientRequest
+	14, // 29: wgagent.WireGuardAgent.CreateDevice:input_type -> wgagent.CreateDeviceRequest
+	16, // 30: wgagent.WireGuardAgent.ListDevices:input_type -> wgagent.ListDevicesRequest
+	19, // 31: wgagent.WireGuardAgent.DeleteDevice:input_type -> wgagent.DeleteDeviceRequest
+	20, // 32: wgagent.WireGuardAgent.GetClient:input_type -> wgagent.GetClientRequest
+	22, // 33: wgagent.WireGuardAgent.GetClientConfig:input_type -> wgagent.GetClientConfigRequest
+	23, // 34: wgagent.WireGuardAgent.ListClients:input_type -> wgagent.ListClientsRequest
+	29, // 35: wgagent.WireGuardAgent.AddPresharedKey:input_type -> wgagent.AddPresharedKeyRequest
+	30, // 36: wgagent.WireGuardAgent.RotateClientKeys:input_type -> wgagent.RotateClientKeysRequest
+	31, // 37: wgagent.WireGuardAgent.ReserveIP:input_type -> wgagent.ReserveIPRequest
+	32, // 38: wgagent.WireGuardAgent.ReleaseReservation:input_type -> wgagent.ReleaseReservationRequest
+	33, // 39: wgagent.WireGuardAgent.ListReservations:input_type -> wgagent.ListReservationsRequest
+	36, // 40: wgagent.WireGuardAgent.ListQuarantine:input_type -> wgagent.ListQuarantineRequest
+	39, // 41: wgagent.WireGuardAgent.ReleaseQuarantine:input_type -> wgagent.ReleaseQuarantineRequest
+	41, // 42: wgagent.WireGuardAgent.ImportClients:input_type -> wgagent.ImportClientsRequest
+	26, // 43: wgagent.WireGuardAgent.GetReconcileStatus:input_type -> wgagent.GetReconcileStatusRequest
+	5,  // 44: wgagent.WireGuardAgent.CreateClient:output_type -> wgagent.CreateClientResponse
+	8,  // 45: wgagent.WireGuardAgent.DisableClient:output_type -> wgagent.DisableClientResponse
+	10, // 46: wgagent.WireGuardAgent.EnableClient:output_type -> wgagent.EnableClientResponse
+	12, // 47: wgagent.WireGuardAgent.ExtendClient:output_type -> wgagent.ExtendClientResponse
+	49, // 48: wgagent.WireGuardAgent.DeleteClient:output_type -> google.protobuf.Empty
+	15, // 49: wgagent.WireGuardAgent.CreateDevice:output_type -> wgagent.CreateDeviceResponse
+	17, // 50: wgagent.WireGuardAgent.ListDevices:output_type -> wgagent.ListDevicesResponse
+	49, // 51: wgagent.WireGuardAgent.DeleteDevice:output_type -> google.protobuf.Empty
+	21, // 52: wgagent.WireGuardAgent.GetClient:output_type -> wgagent.GetClientResponse
+	28, // 53: wgagent.WireGuardAgent.GetClientConfig:output_type -> wgagent.ClientConfigResponse
+	24, // 54: wgagent.WireGuardAgent.ListClients:output_type -> wgagent.ListClientsResponse
+	28, // 55: wgagent.WireGuardAgent.AddPresharedKey:output_type -> wgagent.ClientConfigResponse
+	28, // 56: wgagent.WireGuardAgent.RotateClientKeys:output_type -> wgagent.ClientConfigResponse
+	35, // 57: wgagent.WireGuardAgent.ReserveIP:output_type -> wgagent.Reservation
+	49, // 58: wgagent.WireGuardAgent.ReleaseReservation:output_type -> google.protobuf.Empty
+	34, // 59: wgagent.WireGuardAgent.ListReservations:output_type -> wgagent.ListReservationsResponse
+	37, // 60: wgagent.WireGuardAgent.ListQuarantine:output_type -> wgagent.ListQuarantineResponse
+	40, // 61: wgagent.WireGuardAgent.ReleaseQuarantine:output_type -> wgagent.ReleaseQuarantineResponse
+	42, // 62: wgagent.WireGuardAgent.ImportClients:output_type -> wgagent.ImportClientsResponse
+	27, // 63: wgagent.WireGuardAgent.GetReconcileStatus:output_type -> wgagent.GetReconcileStatusResponse
+	44, // [44:64] is the sub-list for method output_type
+	24, // [24:44] is the sub-list for method input_type
+	24, // [24:24] is the sub-list for extension type_name
+	24, // [24:24] is the sub-list for extension extendee
+	0,  // [0:24] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_agent_proto_init() }
@@ -761,18 +3637,21 @@ func file_api_proto_agent_proto_init() {
 	if File_api_proto_agent_proto != nil {
 		return
 	}
+	file_api_proto_agent_proto_msgTypes[2].OneofWrappers = []any{}
+	file_api_proto_agent_proto_msgTypes[3].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_agent_proto_rawDesc), len(file_api_proto_agent_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   12,
+			NumEnums:      1,
+			NumMessages:   48,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_api_proto_agent_proto_goTypes,
 		DependencyIndexes: file_api_proto_agent_proto_depIdxs,
+		EnumInfos:         file_api_proto_agent_proto_enumTypes,
 		MessageInfos:      file_api_proto_agent_proto_msgTypes,
 	}.Build()
 	File_api_proto_agent_proto = out.File
@@ -20,12 +20,26 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	WireGuardAgent_CreateClient_FullMethodName  = "/wgagent.WireGuardAgent/CreateClient"
-	WireGuardAgent_DisableClient_FullMethodName = "/wgagent.WireGuardAgent/DisableClient"
-	WireGuardAgent_EnableClient_FullMethodName  = "/wgagent.WireGuardAgent/EnableClient"
-	WireGuardAgent_DeleteClient_FullMethodName  = "/wgagent.WireGuardAgent/DeleteClient"
-	WireGuardAgent_GetClient_FullMethodName     = "/wgagent.WireGuardAgent/GetClient"
-	WireGuardAgent_ListClients_FullMethodName   = "/wgagent.WireGuardAgent/ListClients"
+	WireGuardAgent_CreateClient_FullMethodName       = "/wgagent.WireGuardAgent/CreateClient"
+	WireGuardAgent_DisableClient_FullMethodName      = "/wgagent.WireGuardAgent/DisableClient"
+	WireGuardAgent_EnableClient_FullMethodName       = "/wgagent.WireGuardAgent/EnableClient"
+	WireGuardAgent_ExtendClient_FullMethodName       = "/wgagent.WireGuardAgent/ExtendClient"
+	WireGuardAgent_DeleteClient_FullMethodName       = "/wgagent.WireGuardAgent/DeleteClient"
+	WireGuardAgent_CreateDevice_FullMethodName       = "/wgagent.WireGuardAgent/CreateDevice"
+	WireGuardAgent_ListDevices_FullMethodName        = "/wgagent.WireGuardAgent/ListDevices"
+	WireGuardAgent_DeleteDevice_FullMethodName       = "/wgagent.WireGuardAgent/DeleteDevice"
+	WireGuardAgent_GetClient_FullMethodName          = "/wgagent.WireGuardAgent/GetClient"
+	WireGuardAgent_GetClientConfig_FullMethodName    = "/wgagent.WireGuardAgent/GetClientConfig"
+	WireGuardAgent_ListClients_FullMethodName        = "/wgagent.WireGuardAgent/ListClients"
+	WireGuardAgent_AddPresharedKey_FullMethodName    = "/wgagent.WireGuardAgent/AddPresharedKey"
+	WireGuardAgent_RotateClientKeys_FullMethodName   = "/wgagent.WireGuardAgent/RotateClientKeys"
+	WireGuardAgent_ReserveIP_FullMethodName          = "/wgagent.WireGuardAgent/ReserveIP"
+	WireGuardAgent_ReleaseReservation_FullMethodName = "/wgagent.WireGuardAgent/ReleaseReservation"
+	WireGuardAgent_ListReservations_FullMethodName   = "/wgagent.WireGuardAgent/ListReservations"
+	WireGuardAgent_ListQuarantine_FullMethodName     = "/wgagent.WireGuardAgent/ListQuarantine"
+	WireGuardAgent_ReleaseQuarantine_FullMethodName  = "/wgagent.WireGuardAgent/ReleaseQuarantine"
+	WireGuardAgent_ImportClients_FullMethodName      = "/wgagent.WireGuardAgent/ImportClients"
+	WireGuardAgent_GetReconcileStatus_FullMethodName = "/wgagent.WireGuardAgent/GetReconcileStatus"
 )
 
 // WireGuardAgentClient is the client API for WireGuardAgent service.
@@ -38,24 +52,72 @@ const (
 // - CreateClient: создать нового клиента → получить конфиг, QR, ссылку
 // - DisableClient: отключить клиента (подписка закончилась)
 // - EnableClient: включить обратно
+// - ExtendClient: продлить подписку (срок expires_at)
 // - DeleteClient: удалить полностью
+// - CreateDevice / ListDevices / DeleteDevice: несколько устройств одного клиента
 // - GetClient: получить информацию о клиенте
+// - GetClientConfig: получить конфиг, QR и ссылку повторно
+// - AddPresharedKey: добавить/обновить preshared key клиента
+// - RotateClientKeys: перевыпустить ключи клиента (потерянное устройство)
+// - ReserveIP / ReleaseReservation / ListReservations: статические адреса
+// - ImportClients: импортировать существующие пиры из wg0.conf
+// - GetReconcileStatus: результат сверки хранилища с устройством WireGuard
 type WireGuardAgentClient interface {
 	// CreateClient - создаёт нового VPN клиента.
-	// Генерирует ключи, выделяет IP, добавляет в WireGuard.
+	// Генерирует ключи (или принимает публичный ключ клиента), выделяет IP, добавляет в WireGuard.
 	// Возвращает всё что нужно для подключения клиента.
 	CreateClient(ctx context.Context, in *CreateClientRequest, opts ...grpc.CallOption) (*CreateClientResponse, error)
 	// DisableClient - отключает клиента (подписка закончилась).
-	// Клиент остаётся в базе, но не может подключиться.
+	// Клиент остаётся в базе, но не может подключиться ни с одного устройства.
 	DisableClient(ctx context.Context, in *DisableClientRequest, opts ...grpc.CallOption) (*DisableClientResponse, error)
-	// EnableClient - включает ранее отключенного клиента.
+	// EnableClient - включает ранее отключенного клиента со всеми устройствами.
+	// Клиента с истёкшим сроком подписки включает только ExtendClient(enable=true).
 	EnableClient(ctx context.Context, in *EnableClientRequest, opts ...grpc.CallOption) (*EnableClientResponse, error)
-	// DeleteClient - полностью удаляет клиента.
+	// ExtendClient - меняет срок подписки клиента (expires_at) и, если попросили,
+	// включает его. После срока (плюс WG_AGENT_EXPIRY_GRACE) агент сам отключает
+	// клиента, как DisableClient, даже если бот недоступен.
+	ExtendClient(ctx context.Context, in *ExtendClientRequest, opts ...grpc.CallOption) (*ExtendClientResponse, error)
+	// DeleteClient - полностью удаляет клиента вместе со всеми устройствами.
 	DeleteClient(ctx context.Context, in *DeleteClientRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// CreateDevice - добавляет клиенту ещё одно устройство со своими ключами и IP.
+	// Клиент должен уже существовать (CreateClient создаёт основное устройство).
+	// Число устройств ограничено max_devices из запроса или WG_AGENT_MAX_DEVICES.
+	CreateDevice(ctx context.Context, in *CreateDeviceRequest, opts ...grpc.CallOption) (*CreateDeviceResponse, error)
+	// ListDevices - устройства клиента, включая основное (device_id "primary").
+	ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error)
+	// DeleteDevice - удаляет дополнительное устройство клиента.
+	// Основное устройство удаляется только вместе с клиентом (DeleteClient).
+	DeleteDevice(ctx context.Context, in *DeleteDeviceRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	// GetClient - получить информацию о клиенте.
 	GetClient(ctx context.Context, in *GetClientRequest, opts ...grpc.CallOption) (*GetClientResponse, error)
+	// GetClientConfig - повторно отдаёт конфиг, QR код и deep link клиента.
+	// Доступно только для клиентов, чей приватный ключ хранится на сервере.
+	GetClientConfig(ctx context.Context, in *GetClientConfigRequest, opts ...grpc.CallOption) (*ClientConfigResponse, error)
 	// ListClients - список всех клиентов.
 	ListClients(ctx context.Context, in *ListClientsRequest, opts ...grpc.CallOption) (*ListClientsResponse, error)
+	// AddPresharedKey - генерирует клиенту новый preshared key (постквантовая защита),
+	// применяет его на устройстве и возвращает обновлённый конфиг.
+	AddPresharedKey(ctx context.Context, in *AddPresharedKeyRequest, opts ...grpc.CallOption) (*ClientConfigResponse, error)
+	// RotateClientKeys - перевыпускает ключи клиента (и preshared key, если он есть).
+	// IP клиента сохраняется, старый ключ перестаёт работать сразу.
+	RotateClientKeys(ctx context.Context, in *RotateClientKeysRequest, opts ...grpc.CallOption) (*ClientConfigResponse, error)
+	// ReserveIP - закрепляет адрес за user_id (в том числе до создания клиента).
+	// CreateClient для этого user_id выдаст зарезервированный адрес, остальным он не выдаётся.
+	ReserveIP(ctx context.Context, in *ReserveIPRequest, opts ...grpc.CallOption) (*Reservation, error)
+	// ReleaseReservation - снимает резервирование адреса.
+	ReleaseReservation(ctx context.Context, in *ReleaseReservationRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ListReservations - список резервирований.
+	ListReservations(ctx context.Context, in *ListReservationsRequest, opts ...grpc.CallOption) (*ListReservationsResponse, error)
+	// ListQuarantine - адреса удалённых клиентов, которые ещё не выдаются другим.
+	ListQuarantine(ctx context.Context, in *ListQuarantineRequest, opts ...grpc.CallOption) (*ListQuarantineResponse, error)
+	// ReleaseQuarantine - досрочно возвращает адреса из карантина в пул.
+	ReleaseQuarantine(ctx context.Context, in *ReleaseQuarantineRequest, opts ...grpc.CallOption) (*ReleaseQuarantineResponse, error)
+	// ImportClients - регистрирует пиры из конфига wg-quick как клиентов.
+	// Устройство WireGuard не изменяется. Возвращает результат по каждому пиру.
+	ImportClients(ctx context.Context, in *ImportClientsRequest, opts ...grpc.CallOption) (*ImportClientsResponse, error)
+	// GetReconcileStatus - результат последней сверки хранилища агента
+	// с пирами на устройстве WireGuard. Для алертов о расхождении.
+	GetReconcileStatus(ctx context.Context, in *GetReconcileStatusRequest, opts ...grpc.CallOption) (*GetReconcileStatusResponse, error)
 }
 
 type wireGuardAgentClient struct {
@@ -96,6 +158,16 @@ func (c *wireGuardAgentClient) EnableClient(ctx context.Context, in *EnableClien
 	return out, nil
 }
 
+func (c *wireGuardAgentClient) ExtendClient(ctx context.Context, in *ExtendClientRequest, opts ...grpc.CallOption) (*ExtendClientResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExtendClientResponse)
+	err := c.cc.Invoke(ctx, WireGuardAgent_ExtendClient_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *wireGuardAgentClient) DeleteClient(ctx context.Context, in *DeleteClientRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(emptypb.Empty)
@@ -106,6 +178,36 @@ func (c *wireGuardAgentClient) DeleteClient(ctx context.Context, in *DeleteClien
 	return out, nil
 }
 
+func (c *wireGuardAgentClient) CreateDevice(ctx context.Context, in *CreateDeviceRequest, opts ...grpc.CallOption) (*CreateDeviceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateDeviceResponse)
+	err := c.cc.Invoke(ctx, WireGuardAgent_CreateDevice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardAgentClient) ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDevicesResponse)
+	err := c.cc.Invoke(ctx, WireGuardAgent_ListDevices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardAgentClient) DeleteDevice(ctx context.Context, in *DeleteDeviceRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, WireGuardAgent_DeleteDevice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *wireGuardAgentClient) GetClient(ctx context.Context, in *GetClientRequest, opts ...grpc.CallOption) (*GetClientResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetClientResponse)
@@ -116,6 +218,16 @@ func (c *wireGuardAgentClient) GetClient(ctx context.Context, in *GetClientReque
 	return out, nil
 }
 
+func (c *wireGuardAgentClient) GetClientConfig(ctx context.Context, in *GetClientConfigRequest, opts ...grpc.CallOption) (*ClientConfigResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClientConfigResponse)
+	err := c.cc.Invoke(ctx, WireGuardAgent_GetClientConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *wireGuardAgentClient) ListClients(ctx context.Context, in *ListClientsRequest, opts ...grpc.CallOption) (*ListClientsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ListClientsResponse)
@@ -126,6 +238,96 @@ func (c *wireGuardAgentClient) ListClients(ctx context.Context, in *ListClientsR
 	return out, nil
 }
 
+func (c *wireGuardAgentClient) AddPresharedKey(ctx context.Context, in *AddPresharedKeyRequest, opts ...grpc.CallOption) (*ClientConfigResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClientConfigResponse)
+	err := c.cc.Invoke(ctx, WireGuardAgent_AddPresharedKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardAgentClient) RotateClientKeys(ctx context.Context, in *RotateClientKeysRequest, opts ...grpc.CallOption) (*ClientConfigResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClientConfigResponse)
+	err := c.cc.Invoke(ctx, WireGuardAgent_RotateClientKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardAgentClient) ReserveIP(ctx context.Context, in *ReserveIPRequest, opts ...grpc.CallOption) (*Reservation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Reservation)
+	err := c.cc.Invoke(ctx, WireGuardAgent_ReserveIP_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardAgentClient) ReleaseReservation(ctx context.Context, in *ReleaseReservationRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, WireGuardAgent_ReleaseReservation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardAgentClient) ListReservations(ctx context.Context, in *ListReservationsRequest, opts ...grpc.CallOption) (*ListReservationsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListReservationsResponse)
+	err := c.cc.Invoke(ctx, WireGuardAgent_ListReservations_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardAgentClient) ListQuarantine(ctx context.Context, in *ListQuarantineRequest, opts ...grpc.CallOption) (*ListQuarantineResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListQuarantineResponse)
+	err := c.cc.Invoke(ctx, WireGuardAgent_ListQuarantine_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardAgentClient) ReleaseQuarantine(ctx context.Context, in *ReleaseQuarantineRequest, opts ...grpc.CallOption) (*ReleaseQuarantineResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReleaseQuarantineResponse)
+	err := c.cc.Invoke(ctx, WireGuardAgent_ReleaseQuarantine_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardAgentClient) ImportClients(ctx context.Context, in *ImportClientsRequest, opts ...grpc.CallOption) (*ImportClientsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportClientsResponse)
+	err := c.cc.Invoke(ctx, WireGuardAgent_ImportClients_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wireGuardAgentClient) GetReconcileStatus(ctx context.Context, in *GetReconcileStatusRequest, opts ...grpc.CallOption) (*GetReconcileStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetReconcileStatusResponse)
+	err := c.cc.Invoke(ctx, WireGuardAgent_GetReconcileStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // WireGuardAgentServer is the server API for WireGuardAgent service.
 // All implementations must embed UnimplementedWireGuardAgentServer
 // for forward compatibility.
@@ -136,24 +338,72 @@ func (c *wireGuardAgentClient) ListClients(ctx context.Context, in *ListClientsR
 // - CreateClient: создать нового клиента → получить конфиг, QR, ссылку
 // - DisableClient: отключить клиента (подписка закончилась)
 // - EnableClient: включить обратно
+// - ExtendClient: продлить подписку (срок expires_at)
 // - DeleteClient: удалить полностью
+// - CreateDevice / ListDevices / DeleteDevice: несколько устройств одного клиента
 // - GetClient: получить информацию о клиенте
+// - GetClientConfig: получить конфиг, QR и ссылку повторно
+// - AddPresharedKey: добавить/обновить preshared key клиента
+// - RotateClientKeys: перевыпустить ключи клиента (потерянное устройство)
+// - ReserveIP / ReleaseReservation / ListReservations: статические адреса
+// - ImportClients: импортировать существующие пиры из wg0.conf
+// - GetReconcileStatus: результат сверки хранилища с устройством WireGuard
 type WireGuardAgentServer interface {
 	// CreateClient - создаёт нового VPN клиента.
-	// Генерирует ключи, выделяет IP, добавляет в WireGuard.
+	// Генерирует ключи (или принимает публичный ключ клиента), выделяет IP, добавляет в WireGuard.
 	// Возвращает всё что нужно для подключения клиента.
 	CreateClient(context.Context, *CreateClientRequest) (*CreateClientResponse, error)
 	// DisableClient - отключает клиента (подписка закончилась).
-	// Клиент остаётся в базе, но не может подключиться.
+	// Клиент остаётся в базе, но не может подключиться ни с одного устройства.
 	DisableClient(context.Context, *DisableClientRequest) (*DisableClientResponse, error)
-	// EnableClient - включает ранее отключенного клиента.
+	// EnableClient - включает ранее отключенного клиента со всеми устройствами.
+	// Клиента с истёкшим сроком подписки включает только ExtendClient(enable=true).
 	EnableClient(context.Context, *EnableClientRequest) (*EnableClientResponse, error)
-	// DeleteClient - полностью удаляет клиента.
+	// ExtendClient - меняет срок подписки клиента (expires_at) и, если попросили,
+	// включает его. После срока (плюс WG_AGENT_EXPIRY_GRACE) агент сам отключает
+	// клиента, как DisableClient, даже если бот недоступен.
+	ExtendClient(context.Context, *ExtendClientRequest) (*ExtendClientResponse, error)
+	// DeleteClient - полностью удаляет клиента вместе со всеми устройствами.
 	DeleteClient(context.Context, *DeleteClientRequest) (*emptypb.Empty, error)
+	// CreateDevice - добавляет клиенту ещё одно устройство со своими ключами и IP.
+	// Клиент должен уже существовать (CreateClient создаёт основное устройство).
+	// Число устройств ограничено max_devices из запроса или WG_AGENT_MAX_DEVICES.
+	CreateDevice(context.Context, *CreateDeviceRequest) (*CreateDeviceResponse, error)
+	// ListDevices - устройства клиента, включая основное (device_id "primary").
+	ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error)
+	// DeleteDevice - удаляет дополнительное устройство клиента.
+	// Основное устройство удаляется только вместе с клиентом (DeleteClient).
+	DeleteDevice(context.Context, *DeleteDeviceRequest) (*emptypb.Empty, error)
 	// GetClient - получить информацию о клиенте.
 	GetClient(context.Context, *GetClientRequest) (*GetClientResponse, error)
+	// GetClientConfig - повторно отдаёт конфиг, QR код и deep link клиента.
+	// Доступно только для клиентов, чей приватный ключ хранится на сервере.
+	GetClientConfig(context.Context, *GetClientConfigRequest) (*ClientConfigResponse, error)
 	// ListClients - список всех клиентов.
 	ListClients(context.Context, *ListClientsRequest) (*ListClientsResponse, error)
+	// AddPresharedKey - генерирует клиенту новый preshared key (постквантовая защита),
+	// применяет его на устройстве и возвращает обновлённый конфиг.
+	AddPresharedKey(context.Context, *AddPresharedKeyRequest) (*ClientConfigResponse, error)
+	// RotateClientKeys - перевыпускает ключи клиента (и preshared key, если он есть).
+	// IP клиента сохраняется, старый ключ перестаёт работать сразу.
+	RotateClientKeys(context.Context, *RotateClientKeysRequest) (*ClientConfigResponse, error)
+	// ReserveIP - закрепляет адрес за user_id (в том числе до создания клиента).
+	// CreateClient для этого user_id выдаст зарезервированный адрес, остальным он не выдаётся.
+	ReserveIP(context.Context, *ReserveIPRequest) (*Reservation, error)
+	// ReleaseReservation - снимает резервирование адреса.
+	ReleaseReservation(context.Context, *ReleaseReservationRequest) (*emptypb.Empty, error)
+	// ListReservations - список резервирований.
+	ListReservations(context.Context, *ListReservationsRequest) (*ListReservationsResponse, error)
+	// ListQuarantine - адреса удалённых клиентов, которые ещё не выдаются другим.
+	ListQuarantine(context.Context, *ListQuarantineRequest) (*ListQuarantineResponse, error)
+	// ReleaseQuarantine - досрочно возвращает адреса из карантина в пул.
+	ReleaseQuarantine(context.Context, *ReleaseQuarantineRequest) (*ReleaseQuarantineResponse, error)
+	// ImportClients - регистрирует пиры из конфига wg-quick как клиентов.
+	// Устройство WireGuard не изменяется. Возвращает результат по каждому пиру.
+	ImportClients(context.Context, *ImportClientsRequest) (*ImportClientsResponse, error)
+	// GetReconcileStatus - результат последней сверки хранилища агента
+	// с пирами на устройстве WireGuard. Для алертов о расхождении.
+	GetReconcileStatus(context.Context, *GetReconcileStatusRequest) (*GetReconcileStatusResponse, error)
 	mustEmbedUnimplementedWireGuardAgentServer()
 }
 
@@ -173,15 +423,57 @@ func (UnimplementedWireGuardAgentServer) DisableClient(context.Context, *Disable
 func (UnimplementedWireGuardAgentServer) EnableClient(context.Context, *EnableClientRequest) (*EnableClientResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method EnableClient not implemented")
 }
+func (UnimplementedWireGuardAgentServer) ExtendClient(context.Context, *ExtendClientRequest) (*ExtendClientResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExtendClient not implemented")
+}
 func (UnimplementedWireGuardAgentServer) DeleteClient(context.Context, *DeleteClientRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteClient not implemented")
 }
+func (UnimplementedWireGuardAgentServer) CreateDevice(context.Context, *CreateDeviceRequest) (*CreateDeviceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateDevice not implemented")
+}
+func (UnimplementedWireGuardAgentServer) ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDevices not implemented")
+}
+func (UnimplementedWireGuardAgentServer) DeleteDevice(context.Context, *DeleteDeviceRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteDevice not implemented")
+}
 func (UnimplementedWireGuardAgentServer) GetClient(context.Context, *GetClientRequest) (*GetClientResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetClient not implemented")
 }
+func (UnimplementedWireGuardAgentServer) GetClientConfig(context.Context, *GetClientConfigRequest) (*ClientConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetClientConfig not implemented")
+}
 func (UnimplementedWireGuardAgentServer) ListClients(context.Context, *ListClientsRequest) (*ListClientsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListClients not implemented")
 }
+func (UnimplementedWireGuardAgentServer) AddPresharedKey(context.Context, *AddPresharedKeyRequest) (*ClientConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddPresharedKey not implemented")
+}
+func (UnimplementedWireGuardAgentServer) RotateClientKeys(context.Context, *RotateClientKeysRequest) (*ClientConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateClientKeys not implemented")
+}
+func (UnimplementedWireGuardAgentServer) ReserveIP(context.Context, *ReserveIPRequest) (*Reservation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReserveIP not implemented")
+}
+func (UnimplementedWireGuardAgentServer) ReleaseReservation(context.Context, *ReleaseReservationRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseReservation not implemented")
+}
+func (UnimplementedWireGuardAgentServer) ListReservations(context.Context, *ListReservationsRequest) (*ListReservationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListReservations not implemented")
+}
+func (UnimplementedWireGuardAgentServer) ListQuarantine(context.Context, *ListQuarantineRequest) (*ListQuarantineResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListQuarantine not implemented")
+}
+func (UnimplementedWireGuardAgentServer) ReleaseQuarantine(context.Context, *ReleaseQuarantineRequest) (*ReleaseQuarantineResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseQuarantine not implemented")
+}
+func (UnimplementedWireGuardAgentServer) ImportClients(context.Context, *ImportClientsRequest) (*ImportClientsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportClients not implemented")
+}
+func (UnimplementedWireGuardAgentServer) GetReconcileStatus(context.Context, *GetReconcileStatusRequest) (*GetReconcileStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReconcileStatus not implemented")
+}
 func (UnimplementedWireGuardAgentServer) mustEmbedUnimplementedWireGuardAgentServer() {}
 func (UnimplementedWireGuardAgentServer) testEmbeddedByValue()                        {}
 
@@ -257,6 +549,24 @@ func _WireGuardAgent_EnableClient_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WireGuardAgent_ExtendClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExtendClientRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).ExtendClient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_ExtendClient_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).ExtendClient(ctx, req.(*ExtendClientRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _WireGuardAgent_DeleteClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeleteClientRequest)
 	if err := dec(in); err != nil {
@@ -275,6 +585,60 @@ func _WireGuardAgent_DeleteClient_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WireGuardAgent_CreateDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).CreateDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_CreateDevice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).CreateDevice(ctx, req.(*CreateDeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuardAgent_ListDevices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDevicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).ListDevices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_ListDevices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).ListDevices(ctx, req.(*ListDevicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuardAgent_DeleteDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).DeleteDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_DeleteDevice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).DeleteDevice(ctx, req.(*DeleteDeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _WireGuardAgent_GetClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetClientRequest)
 	if err := dec(in); err != nil {
@@ -293,6 +657,24 @@ func _WireGuardAgent_GetClient_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WireGuardAgent_GetClientConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetClientConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).GetClientConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_GetClientConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).GetClientConfig(ctx, req.(*GetClientConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _WireGuardAgent_ListClients_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ListClientsRequest)
 	if err := dec(in); err != nil {
@@ -311,6 +693,168 @@ func _WireGuardAgent_ListClients_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WireGuardAgent_AddPresharedKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddPresharedKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).AddPresharedKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_AddPresharedKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).AddPresharedKey(ctx, req.(*AddPresharedKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuardAgent_RotateClientKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateClientKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).RotateClientKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_RotateClientKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).RotateClientKeys(ctx, req.(*RotateClientKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuardAgent_ReserveIP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReserveIPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).ReserveIP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_ReserveIP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).ReserveIP(ctx, req.(*ReserveIPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuardAgent_ReleaseReservation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseReservationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).ReleaseReservation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_ReleaseReservation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).ReleaseReservation(ctx, req.(*ReleaseReservationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuardAgent_ListReservations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListReservationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).ListReservations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_ListReservations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).ListReservations(ctx, req.(*ListReservationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuardAgent_ListQuarantine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListQuarantineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).ListQuarantine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_ListQuarantine_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).ListQuarantine(ctx, req.(*ListQuarantineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuardAgent_ReleaseQuarantine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseQuarantineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).ReleaseQuarantine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_ReleaseQuarantine_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).ReleaseQuarantine(ctx, req.(*ReleaseQuarantineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuardAgent_ImportClients_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportClientsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).ImportClients(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_ImportClients_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).ImportClients(ctx, req.(*ImportClientsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WireGuardAgent_GetReconcileStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReconcileStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WireGuardAgentServer).GetReconcileStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WireGuardAgent_GetReconcileStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WireGuardAgentServer).GetReconcileStatus(ctx, req.(*GetReconcileStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // WireGuardAgent_ServiceDesc is the grpc.ServiceDesc for WireGuardAgent service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -330,18 +874,74 @@ var WireGuardAgent_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "EnableClient",
 			Handler:    _WireGuardAgent_EnableClient_Handler,
 		},
+		{
+			MethodName: "ExtendClient",
+			Handler:    _WireGuardAgent_ExtendClient_Handler,
+		},
 		{
 			MethodName: "DeleteClient",
 			Handler:    _WireGuardAgent_DeleteClient_Handler,
 		},
+		{
+			MethodName: "CreateDevice",
+			Handler:    _WireGuardAgent_CreateDevice_Handler,
+		},
+		{
+			MethodName: "ListDevices",
+			Handler:    _WireGuardAgent_ListDevices_Handler,
+		},
+		{
+			MethodName: "DeleteDevice",
+			Handler:    _WireGuardAgent_DeleteDevice_Handler,
+		},
 		{
 			MethodName: "GetClient",
 			Handler:    _WireGuardAgent_GetClient_Handler,
 		},
+		{
+			MethodName: "GetClientConfig",
+			Handler:    _WireGuardAgent_GetClientConfig_Handler,
+		},
 		{
 			MethodName: "ListClients",
 			Handler:    _WireGuardAgent_ListClients_Handler,
 		},
+		{
+			MethodName: "AddPresharedKey",
+			Handler:    _WireGuardAgent_AddPresharedKey_Handler,
+		},
+		{
+			MethodName: "RotateClientKeys",
+			Handler:    _WireGuardAgent_RotateClientKeys_Handler,
+		},
+		{
+			MethodName: "ReserveIP",
+			Handler:    _WireGuardAgent_ReserveIP_Handler,
+		},
+		{
+			MethodName: "ReleaseReservation",
+			Handler:    _WireGuardAgent_ReleaseReservation_Handler,
+		},
+		{
+			MethodName: "ListReservations",
+			Handler:    _WireGuardAgent_ListReservations_Handler,
+		},
+		{
+			MethodName: "ListQuarantine",
+			Handler:    _WireGuardAgent_ListQuarantine_Handler,
+		},
+		{
+			MethodName: "ReleaseQuarantine",
+			Handler:    _WireGuardAgent_ReleaseQuarantine_Handler,
+		},
+		{
+			MethodName: "ImportClients",
+			Handler:    _WireGuardAgent_ImportClients_Handler,
+		},
+		{
+			MethodName: "GetReconcileStatus",
+			Handler:    _WireGuardAgent_GetReconcileStatus_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/proto/agent.proto",
@@ -251,6 +251,21 @@ clone_and_build() {
     echo "✅ wg-agent binary installed to $BINARY_PATH"
 }
 
+generate_kek() {
+    echo ""
+    echo "🔑 Generating KEK for client keys..."
+
+    mkdir -p "$INSTALL_DIR"
+    if [ -f "$INSTALL_DIR/kek" ]; then
+        echo "   KEK already exists, keeping it"
+    else
+        (umask 077 && head -c 32 /dev/urandom | base64 > "$INSTALL_DIR/kek")
+    fi
+    chmod 600 "$INSTALL_DIR/kek"
+
+    echo "✅ KEK stored in $INSTALL_DIR/kek"
+}
+
 create_systemd_service() {
     echo ""
     echo "⚙️  Creating systemd service..."
@@ -275,6 +290,7 @@ Environment="WG_AGENT_RATE_LIMIT=${WG_AGENT_RATE_LIMIT}"
 Environment="WG_AGENT_TLS_CERT=${INSTALL_DIR}/cert.pem"
 Environment="WG_AGENT_TLS_PRIVATE=${INSTALL_DIR}/key.pem"
 Environment="WG_AGENT_CA_BUNDLE=${INSTALL_DIR}/ca.pem"
+Environment="WG_AGENT_KEK_FILE=${INSTALL_DIR}/kek"
 
 [Install]
 WantedBy=multi-user.target
@@ -343,6 +359,7 @@ main() {
     setup_wireguard
     generate_tls_certificates
     clone_and_build
+    generate_kek
     create_systemd_service
     output_connection_info
 }